@@ -0,0 +1,120 @@
+package analysis
+
+import (
+	"errors"
+	"math"
+)
+
+// epsilon is the tolerance under which a tableau value is treated as zero.
+const epsilon = 1e-9
+
+// ErrUnbounded is returned by Maximize when the objective can grow without limit.
+var ErrUnbounded = errors.New("linear program is unbounded")
+
+// Maximize solves the linear program
+//
+//	maximize c·x subject to A·x <= b and x >= 0
+//
+// with the simplex method on a dense tableau. Every b[i] must be non-negative so
+// that x = 0 is a feasible starting point; the slack variables form the initial
+// basis. Bland's rule picks the entering and leaving variables, which prevents
+// cycling on the degenerate programs steady-state balances produce.
+//
+// Parameters:
+//   - c: the objective coefficients, one per variable.
+//   - a: the constraint matrix, one row per constraint and one column per variable.
+//   - b: the right-hand sides, one per constraint.
+//
+// Returns:
+//   - The optimal values of the variables and the optimal objective value.
+//   - ErrUnbounded if the objective is unbounded, or an error if the input is malformed.
+func Maximize(c []float64, a [][]float64, b []float64) ([]float64, float64, error) {
+	n, m := len(c), len(a)
+	if len(b) != m {
+		return nil, 0, errors.New("constraint matrix and right-hand side sizes differ")
+	}
+
+	// Tableau rows are the constraints followed by the objective row;
+	// columns are the variables, the slacks and the right-hand side.
+	width := n + m + 1
+	tableau := make([][]float64, m+1)
+	for i := 0; i < m; i++ {
+		if len(a[i]) != n {
+			return nil, 0, errors.New("constraint row size differs from the number of variables")
+		}
+		if b[i] < 0 {
+			return nil, 0, errors.New("right-hand sides must be non-negative")
+		}
+		tableau[i] = make([]float64, width)
+		copy(tableau[i], a[i])
+		tableau[i][n+i] = 1
+		tableau[i][width-1] = b[i]
+	}
+	tableau[m] = make([]float64, width)
+	for j, v := range c {
+		tableau[m][j] = -v
+	}
+
+	basis := make([]int, m)
+	for i := range basis {
+		basis[i] = n + i
+	}
+
+	for {
+		// Entering variable: lowest index with a negative reduced cost
+		enter := -1
+		for j := 0; j < width-1; j++ {
+			if tableau[m][j] < -epsilon {
+				enter = j
+				break
+			}
+		}
+		if enter == -1 {
+			break
+		}
+
+		// Leaving variable: minimum ratio, ties broken by the lowest basis index
+		leave := -1
+		best := math.Inf(1)
+		for i := 0; i < m; i++ {
+			if tableau[i][enter] <= epsilon {
+				continue
+			}
+			ratio := tableau[i][width-1] / tableau[i][enter]
+			if ratio < best-epsilon || (math.Abs(ratio-best) <= epsilon && basis[i] < basis[leave]) {
+				best, leave = ratio, i
+			}
+		}
+		if leave == -1 {
+			return nil, 0, ErrUnbounded
+		}
+
+		pivot(tableau, leave, enter)
+		basis[leave] = enter
+	}
+
+	x := make([]float64, n)
+	for i, v := range basis {
+		if v < n {
+			x[v] = tableau[i][width-1]
+		}
+	}
+	return x, tableau[m][width-1], nil
+}
+
+// pivot makes column col a unit vector with its 1 on row row.
+func pivot(tableau [][]float64, row, col int) {
+	p := tableau[row][col]
+	for j := range tableau[row] {
+		tableau[row][j] /= p
+	}
+	for i := range tableau {
+		if i == row || tableau[i][col] == 0 {
+			continue
+		}
+		f := tableau[i][col]
+		for j := range tableau[i] {
+			tableau[i][j] -= f * tableau[row][j]
+		}
+	}
+}
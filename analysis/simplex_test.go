@@ -0,0 +1,46 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+)
+
+// TestMaximize tests the simplex solver on a textbook program, a degenerate one and an unbounded one.
+func TestMaximize(t *testing.T) {
+	t.Run("textbook", func(t *testing.T) {
+		// maximize 3x + 5y s.t. x <= 4, 2y <= 12, 3x + 2y <= 18 => x=2, y=6, value 36
+		x, value, err := Maximize(
+			[]float64{3, 5},
+			[][]float64{{1, 0}, {0, 2}, {3, 2}},
+			[]float64{4, 12, 18},
+		)
+		if err != nil {
+			t.Fatalf("Maximize returned an error: %v", err)
+		}
+		if math.Abs(value-36) > 1e-6 || math.Abs(x[0]-2) > 1e-6 || math.Abs(x[1]-6) > 1e-6 {
+			t.Errorf("got x=%v value=%v, want x=[2 6] value=36", x, value)
+		}
+	})
+
+	t.Run("degenerate", func(t *testing.T) {
+		// maximize x + y s.t. x - y <= 0, y - x <= 0, x + y <= 4 => value 4
+		_, value, err := Maximize(
+			[]float64{1, 1},
+			[][]float64{{1, -1}, {-1, 1}, {1, 1}},
+			[]float64{0, 0, 4},
+		)
+		if err != nil {
+			t.Fatalf("Maximize returned an error: %v", err)
+		}
+		if math.Abs(value-4) > 1e-6 {
+			t.Errorf("value = %v, want 4", value)
+		}
+	})
+
+	t.Run("unbounded", func(t *testing.T) {
+		_, _, err := Maximize([]float64{1, 0}, [][]float64{{-1, 1}}, []float64{1})
+		if err != ErrUnbounded {
+			t.Errorf("err = %v, want ErrUnbounded", err)
+		}
+	})
+}
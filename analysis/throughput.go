@@ -0,0 +1,159 @@
+package analysis
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/jesee-kuya/stock_exchange/engine"
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// TargetRate is the steady-state analysis of one optimize target.
+//
+// Fields:
+//   - Target: the item name.
+//   - Rate: the maximal long-run net production of the target, in units per cycle.
+//   - Unbounded: true when the rate can grow without limit, e.g. a process without needs produces the target.
+//   - Mix: the start rate of each process in the optimal mix, in runs per cycle; idle processes are omitted.
+//   - Running: the average number of concurrent instances of each process in the mix.
+type TargetRate struct {
+	Target    string
+	Rate      float64
+	Unbounded bool
+	Mix       map[string]float64
+	Running   map[string]float64
+}
+
+// Sustainable reports whether the target can be produced forever at a positive rate.
+func (t TargetRate) Sustainable() bool {
+	return t.Unbounded || t.Rate > epsilon
+}
+
+// Throughput computes the maximal long-run production rate of every optimize target.
+//
+// The stoichiometric matrix has one column per process, holding Output minus Needs,
+// and the variables are the start rates of the processes in runs per cycle. For each
+// target, a linear program maximizes its net rate subject to:
+//   - balance: every other item is produced at least as fast as it is consumed,
+//     since any finite initial stock runs out under a negative net rate;
+//   - occupancy: for items no process can increase (like a worker that a process takes
+//     and gives back, or a renewable resource it holds), the units carried by running
+//     processes, rate × duration × output, cannot exceed the initial stock;
+//   - storage: for items with a storage capacity, the units on their way from running
+//     processes cannot exceed it, since the engine reserves room for them at the start.
+//
+// A rate of zero means the configuration cannot sustain the target and only its initial
+// stock matters, as for any recipe ending when a raw material runs out.
+//
+// Parameters:
+//   - config: the parsed configuration.
+//
+// Returns:
+//   - One TargetRate per optimize target, in alphabetical order; the "time" keyword is
+//     replaced by the terminal products as in the engine.
+func Throughput(config *util.ConfigData) ([]TargetRate, error) {
+	processes := config.Processes
+	obj := engine.NewObjective(config.OptimizeTargets, processes, config.Stocks)
+
+	items := map[string]bool{}
+	increasable := map[string]bool{}
+	for _, p := range processes {
+		for item, qty := range p.Output() {
+			items[item] = true
+			if qty > p.Needs[item] {
+				increasable[item] = true
+			}
+		}
+		for item := range p.Needs {
+			items[item] = true
+		}
+	}
+	names := sortedKeys(items)
+
+	targets := sortedKeys(obj.Items)
+	rates := make([]TargetRate, 0, len(targets))
+	for _, target := range targets {
+		c := make([]float64, len(processes))
+		for j, p := range processes {
+			c[j] = float64(p.Output()[target] - p.Needs[target])
+		}
+
+		a := [][]float64{}
+		b := []float64{}
+		for _, item := range names {
+			if item == target {
+				continue
+			}
+			row := make([]float64, len(processes))
+			for j, p := range processes {
+				row[j] = float64(p.Needs[item] - p.Output()[item])
+			}
+			a, b = append(a, row), append(b, 0)
+
+			if !increasable[item] {
+				row := make([]float64, len(processes))
+				for j, p := range processes {
+					row[j] = float64(p.Cycle * p.Output()[item])
+				}
+				a, b = append(a, row), append(b, float64(config.Stocks[item]))
+			}
+		}
+		for _, item := range sortedKeys(config.Capacities) {
+			row := make([]float64, len(processes))
+			for j, p := range processes {
+				row[j] = float64(p.Cycle * p.Result[item])
+			}
+			a, b = append(a, row), append(b, float64(config.Capacities[item]))
+		}
+
+		rate := TargetRate{Target: target, Mix: map[string]float64{}, Running: map[string]float64{}}
+		x, value, err := Maximize(c, a, b)
+		switch {
+		case err == ErrUnbounded:
+			rate.Unbounded = true
+		case err != nil:
+			return nil, fmt.Errorf("analysis of %s failed: %w", target, err)
+		default:
+			rate.Rate = value
+			for j, p := range processes {
+				if x[j] > epsilon {
+					rate.Mix[p.Name] += x[j]
+					rate.Running[p.Name] += x[j] * float64(p.Cycle)
+				}
+			}
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
+
+// PrintThroughput writes the analysis in a human-readable form, one target per block
+// followed by its optimal process mix.
+func PrintThroughput(w io.Writer, rates []TargetRate) {
+	fmt.Fprintln(w, "Steady-state throughput:")
+	for _, r := range rates {
+		switch {
+		case r.Unbounded:
+			fmt.Fprintf(w, " %s => unbounded (sustainable)\n", r.Target)
+			continue
+		case r.Sustainable():
+			fmt.Fprintf(w, " %s => %.4f per cycle (sustainable)\n", r.Target, r.Rate)
+		default:
+			fmt.Fprintf(w, " %s => 0 per cycle (not sustainable, bounded by the initial stock)\n", r.Target)
+		}
+		for _, name := range sortedKeys(r.Mix) {
+			fmt.Fprintf(w, "   %s: %.4f runs per cycle, %.2f running on average\n", name, r.Mix[name], r.Running[name])
+		}
+	}
+}
+
+// sortedKeys returns the keys of a map in alphabetical order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
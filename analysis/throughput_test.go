@@ -0,0 +1,57 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jesee-kuya/stock_exchange/process"
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// TestThroughput tests the steady-state rates of the self-sustaining example and of a
+// recipe that stops once its raw material is used up.
+func TestThroughput(t *testing.T) {
+	t.Run("self-sustaining", func(t *testing.T) {
+		config := &util.ConfigData{
+			Stocks: map[string]int{"water": 10, "you": 1},
+			Processes: []*process.Process{
+				{Name: "run", Needs: map[string]int{"water": 5}, Result: map[string]int{"joy": 5}, Cycle: 1},
+				{Name: "rest", Needs: map[string]int{"you": 1}, Result: map[string]int{"water": 2, "you": 1}, Cycle: 3},
+			},
+			OptimizeTargets: []string{"time", "joy"},
+		}
+
+		rates, err := Throughput(config)
+		if err != nil {
+			t.Fatalf("Throughput returned an error: %v", err)
+		}
+		if len(rates) != 1 || rates[0].Target != "joy" {
+			t.Fatalf("expected a single joy rate, got %+v", rates)
+		}
+		// rest can run once every 3 cycles, giving 2/3 water per cycle, hence 2/3 joy per cycle
+		if math.Abs(rates[0].Rate-2.0/3.0) > 1e-6 || !rates[0].Sustainable() {
+			t.Errorf("joy rate = %v, want 2/3", rates[0].Rate)
+		}
+		if math.Abs(rates[0].Running["rest"]-1) > 1e-6 {
+			t.Errorf("rest should keep one instance running, got %v", rates[0].Running["rest"])
+		}
+	})
+
+	t.Run("finite", func(t *testing.T) {
+		config := &util.ConfigData{
+			Stocks: map[string]int{"board": 7},
+			Processes: []*process.Process{
+				{Name: "do_shelf", Needs: map[string]int{"board": 1}, Result: map[string]int{"shelf": 1}, Cycle: 10},
+			},
+			OptimizeTargets: []string{"shelf"},
+		}
+
+		rates, err := Throughput(config)
+		if err != nil {
+			t.Fatalf("Throughput returned an error: %v", err)
+		}
+		if rates[0].Sustainable() {
+			t.Errorf("shelf production should not be sustainable, got %+v", rates[0])
+		}
+	})
+}
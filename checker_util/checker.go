@@ -1,8 +1,11 @@
 package checker
 
 import (
+	"fmt"
+
 	"github.com/jesee-kuya/stock_exchange/engine"
 	"github.com/jesee-kuya/stock_exchange/process"
+	"github.com/jesee-kuya/stock_exchange/util"
 )
 
 // Checker represents a structure used to manage and track stock-related data,
@@ -11,9 +14,67 @@ import (
 // Fields:
 // - Stocks: A map where the keys are stock names (string) and the values are their respective quantities (int).
 // - Processes: A slice of pointers to Process objects, representing the processes associated with the stock exchange.
+// - Capacities: The storage capacity of the items that have one.
+// - ShelfLife: The shelf life in cycles of the perishable items.
+// - OptimizeTargets: The optimize targets of the configuration, used to score the log.
+// - Deliveries: The inbound deliveries of the configuration, added to the stocks at their cycle.
+// - Orders: The customer orders of the configuration, shipped from the stocks as in the engine.
 // - Log: A slice of ScheduleEntry objects from the engine package, used to record scheduling events or logs.
+// - Outcomes: What each entry of Log produced, from an outcome trace; nil when every run had its planned outcome (see process.Process.NominalOutcome).
+// - Footer: The closing line of the log, nil when the log has none.
+// - ExpectedStock: The final stock block of the log, nil when the log has none.
+// - Policy: How Verify handles violations; the zero value stops at the first one.
 type Checker struct {
-	Stocks    map[string]int
-	Processes []*process.Process
-	Log       []engine.ScheduleEntry
+	Stocks          map[string]int
+	Processes       []*process.Process
+	Capacities      map[string]int
+	ShelfLife       map[string]int
+	OptimizeTargets []string
+	Deliveries      []util.Delivery
+	Orders          []util.Order
+	Log             []engine.ScheduleEntry
+	Outcomes        []process.Outcome
+	Footer          *Footer
+	ExpectedStock   map[string]int
+	Policy          Policy
+}
+
+// Policy tells Verify what to do with a log entry that violates the configuration.
+type Policy int
+
+const (
+	// StopAtFirst stops the simulation at the first violation.
+	StopAtFirst Policy = iota
+	// SkipEntry ignores the offending entry and keeps simulating: its needs are not
+	// consumed and its results are never produced.
+	SkipEntry
+	// AllowNegative runs the offending entry anyway, letting the missing stocks go negative.
+	// Unknown processes are skipped.
+	AllowNegative
+)
+
+// ParsePolicy returns the policy named "first", "skip" or "negative".
+func ParsePolicy(name string) (Policy, error) {
+	switch name {
+	case "first":
+		return StopAtFirst, nil
+	case "skip":
+		return SkipEntry, nil
+	case "negative":
+		return AllowNegative, nil
+	}
+	return StopAtFirst, fmt.Errorf("unknown policy %q, expected first, skip or negative", name)
+}
+
+// Footer is the closing line of a schedule log, either
+// "No more process doable at cycle N" or "Time limit exceeded after N cycles".
+//
+// Fields:
+// - Cycle: The cycle N claimed by the line.
+// - TimedOut: Whether the run stopped on the time limit rather than running out of work.
+// - Line: The 1-based line of the footer in the log file.
+type Footer struct {
+	Cycle    int
+	TimedOut bool
+	Line     int
 }
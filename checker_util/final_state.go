@@ -0,0 +1,146 @@
+package checker
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/jesee-kuya/stock_exchange/process"
+)
+
+// verifyFinalState checks the claims a log makes about the end of the run against the
+// final stocks of the report, and records every false claim as a violation.
+//
+// Behavior:
+//   - A "No more process doable at cycle N" footer must name the cycle right after the
+//     last completion or delivery, and no process may be able to run on the final stocks, with room
+//     for its results.
+//   - A "Time limit exceeded after N cycles" footer must not claim fewer cycles than the
+//     last start cycle; nothing is assumed about the remaining work.
+//   - An expected stock block must match the final stocks for every item it or the
+//     simulation mentions, missing items counting as zero.
+//   - A log without footer or stock block makes no claim and passes.
+func (c *Checker) verifyFinalState(report *Report) {
+	if c.Footer != nil {
+		c.verifyFooter(report)
+	}
+	if c.ExpectedStock != nil {
+		diffStocks(report, c.ExpectedStock)
+	}
+}
+
+// Ending returns the footer the log in c.Log is entitled to, for tools writing a log of
+// their own such as the schedule improver: "No more process doable" at the cycle after
+// the last completion or delivery when no process can run on the final stocks, and a
+// time limit after the last completion or delivery otherwise, since more work was left.
+//
+// Returns:
+//   - The footer, its Line left at 0.
+//   - The first violation of the log, which then has no footer to claim.
+func (c *Checker) Ending() (Footer, error) {
+	stocks, err := c.Replay()
+	if err != nil {
+		return Footer{}, err
+	}
+	_, lastEnd := c.lastCycles()
+	if len(c.stillRunnable(stocks)) > 0 {
+		return Footer{Cycle: lastEnd, TimedOut: true}, nil
+	}
+	return Footer{Cycle: lastEnd + 1}, nil
+}
+
+// lastCycles returns the last cycle a process of the log starts at, and the last cycle
+// a process completes at or a delivery arrives at.
+func (c *Checker) lastCycles() (lastStart, lastEnd int) {
+	for _, entry := range c.Log {
+		lastStart = max(lastStart, entry.Cycle)
+		lastEnd = max(lastEnd, entry.Cycle)
+		if p := c.process(entry.ProcessName); p != nil {
+			lastEnd = max(lastEnd, entry.Cycle+p.Cycle)
+		}
+	}
+	for _, d := range c.Deliveries {
+		lastEnd = max(lastEnd, d.Cycle)
+	}
+	return lastStart, lastEnd
+}
+
+// stillRunnable returns the processes that can run on the given final stocks, with room
+// for their results.
+func (c *Checker) stillRunnable(stocks map[string]int) []*process.Process {
+	runnable := []*process.Process{}
+	for _, p := range c.Processes {
+		if p.CanRun(stocks) && p.Fits(stocks, nil, c.Capacities) {
+			runnable = append(runnable, p)
+		}
+	}
+	return runnable
+}
+
+// verifyFooter checks the cycle and, for a completed run, the absence of runnable processes.
+func (c *Checker) verifyFooter(report *Report) {
+	lastStart, lastEnd := c.lastCycles()
+	claimed := c.Footer.Cycle
+
+	if c.Footer.TimedOut {
+		if claimed < lastStart {
+			report.Violations = append(report.Violations, Violation{
+				Kind:     WrongFinalCycle,
+				Entry:    -1,
+				CausedBy: -1,
+				Cycle:    claimed,
+				Message:  fmt.Sprintf("log claims a time limit after %d cycles, but a process starts at cycle %d", claimed, lastStart),
+			})
+		}
+		return
+	}
+
+	if claimed != lastEnd+1 {
+		report.Violations = append(report.Violations, Violation{
+			Kind:     WrongFinalCycle,
+			Entry:    -1,
+			CausedBy: -1,
+			Cycle:    claimed,
+			Message: fmt.Sprintf("log claims no more process doable at cycle %d, but the last process completes or delivery arrives at cycle %d (expected %d)",
+				claimed, lastEnd, lastEnd+1),
+		})
+	}
+	for _, p := range c.stillRunnable(report.FinalStock) {
+		report.Violations = append(report.Violations, Violation{
+			Kind:     StillRunnable,
+			Entry:    -1,
+			CausedBy: -1,
+			Cycle:    claimed,
+			Process:  p.Name,
+			Message:  fmt.Sprintf("log claims no more process doable at cycle %d, but '%s' can still run", claimed, p.Name),
+		})
+	}
+}
+
+// diffStocks compares the expected final stocks of a log with the simulated ones and
+// records one violation per mismatching item.
+func diffStocks(report *Report, expected map[string]int) {
+	actual := report.FinalStock
+	items := map[string]bool{}
+	for item := range expected {
+		items[item] = true
+	}
+	for item := range actual {
+		items[item] = true
+	}
+	names := make([]string, 0, len(items))
+	for item := range items {
+		names = append(names, item)
+	}
+	sort.Strings(names)
+
+	for _, item := range names {
+		if expected[item] != actual[item] {
+			report.Violations = append(report.Violations, Violation{
+				Kind:     StockMismatch,
+				Entry:    -1,
+				CausedBy: -1,
+				Message:  fmt.Sprintf("final stock mismatch: %s expected %d, got %d", item, expected[item], actual[item]),
+			})
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package checker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jesee-kuya/stock_exchange/process"
+)
+
+// newShelfChecker returns a checker with 3 boards and a single 10-cycle shelf process.
+func newShelfChecker() *Checker {
+	c := NewChecker()
+	c.Stocks = map[string]int{"board": 3}
+	c.Processes = []*process.Process{
+		{Name: "do_shelf", Needs: map[string]int{"board": 1}, Result: map[string]int{"shelf": 1}, Cycle: 10},
+	}
+	return c
+}
+
+// TestVerifyFinalState tests that the footer and the expected stock block of a log
+// are checked against the replayed final state.
+func TestVerifyFinalState(t *testing.T) {
+	testCases := []struct {
+		name    string
+		log     string
+		wantErr string
+	}{
+		{"no claims", "0:do_shelf\n", ""},
+		{"engine output", "Main Processes :\n 0:do_shelf\n 0:do_shelf\n 0:do_shelf\nNo more process doable at cycle 11\nStock:\n board => 0\n shelf => 3\n", ""},
+		{"wrong final cycle", "0:do_shelf\n0:do_shelf\n0:do_shelf\nNo more process doable at cycle 12\n", "expected 11"},
+		{"still runnable", "0:do_shelf\n0:do_shelf\nNo more process doable at cycle 11\n", "'do_shelf' can still run"},
+		{"timed out", "0:do_shelf\n0:do_shelf\nTime limit exceeded after 5 cycles\n", ""},
+		{"stock mismatch", "0:do_shelf\nStock:\n board => 2\n shelf => 2\n", "shelf expected 2, got 1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.log")
+			if err := os.WriteFile(path, []byte(tc.log), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			c := newShelfChecker()
+			if err := c.LoadLog(path); err != nil {
+				t.Fatalf("LoadLog returned an error: %v", err)
+			}
+
+			_, err := c.Verify()
+			if tc.wantErr == "" && err != nil {
+				t.Errorf("Verify returned an error: %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Errorf("Verify error = %v, want it to contain %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// TestLoadLogMalformed tests that malformed lines are reported with their line number.
+func TestLoadLogMalformed(t *testing.T) {
+	testCases := []struct {
+		name string
+		log  string
+		want string
+	}{
+		{"not an entry", "0:do_shelf\nbogus\n", ":2: malformed log entry"},
+		{"bad cycle", "x:do_shelf\n", ":1: invalid cycle"},
+		{"entry after footer", "No more process doable at cycle 1\n0:do_shelf\n", ":2: unexpected line after the log footer"},
+		{"bad stock line", "Stock:\n board 3\n", ":2: malformed stock line"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.log")
+			if err := os.WriteFile(path, []byte(tc.log), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			err := newShelfChecker().LoadLog(path)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("LoadLog error = %v, want it to contain %q", err, tc.want)
+			}
+		})
+	}
+}
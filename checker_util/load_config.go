@@ -6,7 +6,7 @@ import (
 
 // LoadConfig loads the configuration data from the specified file path.
 // It uses the util.ParseConfig function to parse the configuration file.
-// The parsed configuration data is then used to populate the Stocks and Processes fields of the Checker instance.
+// The parsed configuration data is then used to populate the Stocks, Processes, Capacities, ShelfLife, OptimizeTargets, Deliveries and Orders fields of the Checker instance.
 //
 // Parameters:
 //   - path: A string representing the file path to the configuration file.
@@ -14,13 +14,24 @@ import (
 // Returns:
 //   - error: An error if the configuration file cannot be parsed or loaded successfully, otherwise nil.
 func (c *Checker) LoadConfig(path string) error {
-	configData, err := util.ParseConfig(path)
+	return c.LoadConfigAs(path, util.FormatAuto)
+}
+
+// LoadConfigAs is LoadConfig for a configuration in the given format, or in the format
+// of its file extension for util.FormatAuto.
+func (c *Checker) LoadConfigAs(path string, format util.Format) error {
+	configData, err := util.ParseConfigAs(path, format)
 	if err != nil {
 		return err
 	}
 
 	c.Stocks = configData.Stocks
 	c.Processes = configData.Processes
+	c.Capacities = configData.Capacities
+	c.ShelfLife = configData.ShelfLife
+	c.OptimizeTargets = configData.OptimizeTargets
+	c.Deliveries = configData.Deliveries
+	c.Orders = configData.Orders
 
 	return nil
 }
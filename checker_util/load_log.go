@@ -2,6 +2,7 @@ package checker
 
 import (
 	"bufio"
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
@@ -9,25 +10,39 @@ import (
 	"github.com/jesee-kuya/stock_exchange/engine"
 )
 
+// Log footers written by the engine, with the cycle as their only argument.
+const (
+	doneFooter    = "No more process doable at cycle %d"
+	timeoutFooter = "Time limit exceeded after %d cycles"
+)
+
 // LoadLog reads a log file from the specified path and populates the Checker's Log field
 // with schedule entries. Each line in the log file is expected to be in the format:
 // "<cycle>:<process_name>", where <cycle> is an integer representing the cycle number
 // and <process_name> is a string representing the name of the process.
 //
-// Lines that do not match the expected format or contain invalid cycle numbers are skipped.
+// The log may also hold the rest of the engine output:
+//   - A "Main Processes :" header before the entries, which is ignored.
+//   - A footer after the entries, "No more process doable at cycle N" or
+//     "Time limit exceeded after N cycles", stored in c.Footer.
+//   - A "Stock:" block after the footer, with one " name => quantity" line per item,
+//     stored in c.ExpectedStock.
+//
+// Empty lines are skipped. Any other line is malformed and reported with its line number.
 //
 // Parameters:
 //   - path: The file path to the log file.
 //
 // Returns:
-//   - error: An error if the file cannot be opened or if there is an issue during scanning.
-//     Returns nil if the log is successfully loaded.
+//   - error: An error if the file cannot be opened, a line is malformed, or there is an
+//     issue during scanning. Returns nil if the log is successfully loaded.
 //
 // Example log file format:
 //
 //	1:ProcessA
 //	2:ProcessB
 //	3:ProcessC
+//	No more process doable at cycle 4
 func (c *Checker) LoadLog(path string) error {
 	file, err := os.Open(path)
 	if err != nil {
@@ -36,35 +51,86 @@ func (c *Checker) LoadLog(path string) error {
 	defer file.Close()
 
 	c.Log = []engine.ScheduleEntry{}
+	c.Footer = nil
+	c.ExpectedStock = nil
 
 	scanner := bufio.NewScanner(file)
+	lineNumber := 0
 	for scanner.Scan() {
-		line := scanner.Text()
-
-		// Skip lines that don't match the expected format
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
-			continue
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if err := c.parseLogLine(line, lineNumber); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, lineNumber, err)
 		}
+	}
 
-		cycleStr := strings.TrimSpace(parts[0])
-		name := strings.TrimSpace(parts[1])
+	if err := scanner.Err(); err != nil {
+		return err
+	}
 
-		cycle, err := strconv.Atoi(cycleStr)
-		if err != nil {
-			continue // skip lines with invalid cycle numbers
-		}
+	return nil
+}
 
-		entry := engine.ScheduleEntry{
-			Cycle:       cycle,
-			ProcessName: name,
-		}
-		c.Log = append(c.Log, entry)
+// parseLogLine interprets one trimmed log line according to what has been read so far:
+// entries until the footer, then at most one stock block.
+func (c *Checker) parseLogLine(line string, lineNumber int) error {
+	if line == "" {
+		return nil
+	}
+	if c.ExpectedStock != nil {
+		return c.parseStockLine(line)
 	}
 
-	if err := scanner.Err(); err != nil {
-		return err
+	var cycle int
+	switch {
+	case line == "Main Processes :" && len(c.Log) == 0 && c.Footer == nil:
+		return nil
+	case line == "Stock:":
+		c.ExpectedStock = map[string]int{}
+		return nil
+	case c.Footer != nil:
+		return fmt.Errorf("unexpected line after the log footer: %q", line)
+	case scanFooter(line, doneFooter, &cycle):
+		c.Footer = &Footer{Cycle: cycle, Line: lineNumber}
+		return nil
+	case scanFooter(line, timeoutFooter, &cycle):
+		c.Footer = &Footer{Cycle: cycle, TimedOut: true, Line: lineNumber}
+		return nil
 	}
 
+	cycleStr, name, ok := strings.Cut(line, ":")
+	if !ok {
+		return fmt.Errorf("malformed log entry %q, expected <cycle>:<process_name>", line)
+	}
+	cycle, err := strconv.Atoi(strings.TrimSpace(cycleStr))
+	if err != nil {
+		return fmt.Errorf("invalid cycle %q in log entry %q", cycleStr, line)
+	}
+	c.Log = append(c.Log, engine.ScheduleEntry{
+		Cycle:       cycle,
+		ProcessName: strings.TrimSpace(name),
+	})
 	return nil
 }
+
+// parseStockLine reads one " name => quantity" line of the expected stock block.
+func (c *Checker) parseStockLine(line string) error {
+	name, qtyStr, ok := strings.Cut(line, "=>")
+	if !ok {
+		return fmt.Errorf("malformed stock line %q, expected <name> => <quantity>", line)
+	}
+	qty, err := strconv.Atoi(strings.TrimSpace(qtyStr))
+	if err != nil {
+		return fmt.Errorf("invalid quantity %q in stock line %q", qtyStr, line)
+	}
+	c.ExpectedStock[strings.TrimSpace(name)] = qty
+	return nil
+}
+
+// scanFooter reports whether line is exactly the given footer, storing its cycle.
+func scanFooter(line, format string, cycle *int) bool {
+	if _, err := fmt.Sscanf(line, format, cycle); err != nil {
+		return false
+	}
+	return fmt.Sprintf(format, *cycle) == line
+}
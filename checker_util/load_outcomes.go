@@ -0,0 +1,90 @@
+package checker
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jesee-kuya/stock_exchange/process"
+)
+
+// LoadOutcomes reads the outcome trace of a log, as written by engine.SaveOutcomes, and
+// stores it in c.Outcomes, so that Verify replays each run with what it actually produced
+// rather than its expected results. Each line is expected to be in the format
+// "<cycle>:<process_name> => <outcome>", where <outcome> is "failed" or the results of the
+// run, such as "(bread:3;crumbs:1)".
+//
+// The log must be loaded first: the trace must hold exactly one line per log entry, in the
+// same order and with the same cycle and process. Empty lines are skipped.
+//
+// Parameters:
+//   - path: The file path to the outcome trace.
+//
+// Returns:
+//   - error: An error if the file cannot be opened, a line is malformed or does not match
+//     its log entry, or the trace and the log differ in length, with the line number where
+//     it applies. Returns nil if the trace is successfully loaded.
+//
+// Example outcome trace:
+//
+//	0:bake => (bread:3)
+//	0:bake => failed
+//	10:slice => (slices:8)
+func (c *Checker) LoadOutcomes(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	outcomes := []process.Outcome{}
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		outcome, err := c.parseOutcomeLine(line, len(outcomes))
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, lineNumber, err)
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(outcomes) != len(c.Log) {
+		return fmt.Errorf("%s: %d outcomes for %d log entries", path, len(outcomes), len(c.Log))
+	}
+
+	c.Outcomes = outcomes
+	return nil
+}
+
+// parseOutcomeLine reads the line of the outcome trace for log entry i.
+func (c *Checker) parseOutcomeLine(line string, i int) (process.Outcome, error) {
+	at := strings.LastIndex(line, "=>")
+	if at < 0 {
+		return process.Outcome{}, fmt.Errorf("malformed outcome line %q, expected <cycle>:<process_name> => <outcome>", line)
+	}
+	cycleStr, name, ok := strings.Cut(line[:at], ":")
+	if !ok {
+		return process.Outcome{}, fmt.Errorf("malformed outcome line %q, expected <cycle>:<process_name> => <outcome>", line)
+	}
+	cycle, err := strconv.Atoi(strings.TrimSpace(cycleStr))
+	if err != nil {
+		return process.Outcome{}, fmt.Errorf("invalid cycle %q in outcome line %q", cycleStr, line)
+	}
+	name = strings.TrimSpace(name)
+	if i >= len(c.Log) {
+		return process.Outcome{}, fmt.Errorf("outcome of %d:%s beyond the end of the log", cycle, name)
+	}
+	if entry := c.Log[i]; entry.Cycle != cycle || entry.ProcessName != name {
+		return process.Outcome{}, fmt.Errorf("outcome of %d:%s does not match log entry %d, %s", cycle, name, i+1, entry)
+	}
+	return process.ParseOutcome(line[at+2:])
+}
@@ -0,0 +1,284 @@
+package checker
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+
+	"github.com/jesee-kuya/stock_exchange/engine"
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// ViolationKind is a machine-readable identifier for the kind of a Violation.
+type ViolationKind string
+
+// Kinds of violations reported by Verify.
+const (
+	UnknownProcess    ViolationKind = "unknown-process"
+	InsufficientStock ViolationKind = "insufficient-stock"
+	WrongFinalCycle   ViolationKind = "wrong-final-cycle"
+	StillRunnable     ViolationKind = "still-runnable"
+	StockMismatch     ViolationKind = "stock-mismatch"
+	CapacityExceeded  ViolationKind = "capacity-exceeded"
+	StorageOverflow   ViolationKind = "storage-overflow"
+	ImpossibleOutcome ViolationKind = "impossible-outcome"
+)
+
+// Report is the structured result of Verify.
+//
+// Fields:
+//   - Entries: the result of each evaluated log entry, in log order.
+//   - Snapshots: the stocks after the last start of each cycle with entries.
+//   - Violations: every violation found, the first one first.
+//   - FinalStock: the stocks once every evaluated process has completed.
+//   - Expired: the quantity of each perishable item lost to expiry, empty when none was.
+//   - Orders: what became of each customer order, in configuration order.
+//   - Metrics: the score of the evaluated schedule.
+type Report struct {
+	Entries    []EntryResult   `json:"entries"`
+	Snapshots  []StockSnapshot `json:"snapshots"`
+	Violations []Violation     `json:"violations"`
+	FinalStock map[string]int  `json:"final_stock"`
+	Expired    map[string]int  `json:"expired,omitempty"`
+	Orders     []OrderStatus   `json:"orders,omitempty"`
+	Metrics    Metrics         `json:"metrics"`
+}
+
+// OrderStatus is what became of a customer order when the log was replayed.
+//
+// Fields:
+//   - Items, Due, Penalty: the order as configured.
+//   - Shipped: the cycle the order shipped at, -1 when it never did.
+//   - Late: the number of cycles it shipped after its due cycle.
+//   - Cost: its penalty for every cycle late.
+type OrderStatus struct {
+	Items   map[string]int `json:"items"`
+	Due     int            `json:"due"`
+	Penalty int            `json:"penalty"`
+	Shipped int            `json:"shipped"`
+	Late    int            `json:"late"`
+	Cost    int            `json:"cost"`
+}
+
+// newOrderStatus returns the status of an order replayed by the engine's timeline.
+func newOrderStatus(r engine.OrderResult) OrderStatus {
+	return OrderStatus{Items: r.Items, Due: r.Due, Penalty: r.Penalty, Shipped: r.Shipped, Late: r.Late(), Cost: r.Cost()}
+}
+
+// String describes the status like the engine does, such as "bread:10 due 150: unfilled".
+func (s OrderStatus) String() string {
+	return engine.OrderResult{Order: util.Order{Due: s.Due, Items: s.Items, Penalty: s.Penalty}, Shipped: s.Shipped}.String()
+}
+
+// EntryResult is the outcome of one log entry.
+type EntryResult struct {
+	Index   int    `json:"index"`
+	Cycle   int    `json:"cycle"`
+	Process string `json:"process"`
+	OK      bool   `json:"ok"`
+}
+
+// StockSnapshot records the stocks at a cycle.
+type StockSnapshot struct {
+	Cycle int            `json:"cycle"`
+	Stock map[string]int `json:"stock"`
+}
+
+// Violation describes one inconsistency between a log and its configuration.
+//
+// Fields:
+//   - Kind: the machine-readable kind of violation.
+//   - Entry: the index of the offending log entry, -1 for the final state checks.
+//   - Cycle: the cycle at which the violation occurs.
+//   - Process: the offending process name, empty when no process is involved.
+//   - Message: a human-readable description.
+//   - Missing: for insufficient stock, the quantity missing of each item.
+//   - CausedBy: the index in Report.Violations of the earlier violation this one is a
+//     cascade of, -1 for a root violation.
+type Violation struct {
+	Kind     ViolationKind  `json:"kind"`
+	Entry    int            `json:"entry"`
+	Cycle    int            `json:"cycle"`
+	Process  string         `json:"process,omitempty"`
+	Message  string         `json:"message"`
+	Missing  map[string]int `json:"missing,omitempty"`
+	CausedBy int            `json:"caused_by"`
+}
+
+// Error returns the message of the violation, so it can be returned as an error.
+func (v Violation) Error() string {
+	return v.Message
+}
+
+// Metrics scores a verified schedule.
+//
+// Fields:
+//   - Entries: the number of log entries evaluated.
+//   - EndCycle: the cycle at which the last successfully started process completes.
+//   - Makespan: the cycle at which the last optimize target unit is produced.
+//   - TargetQuantity: the total final stock of the optimize targets.
+//   - LateOrders, UnfilledOrders: the number of orders shipped late and never shipped.
+//   - Penalty: the total cost of the late orders.
+type Metrics struct {
+	Entries        int `json:"entries"`
+	EndCycle       int `json:"end_cycle"`
+	Makespan       int `json:"makespan"`
+	TargetQuantity int `json:"target_quantity"`
+
+	LateOrders     int `json:"late_orders,omitempty"`
+	UnfilledOrders int `json:"unfilled_orders,omitempty"`
+	Penalty        int `json:"penalty,omitempty"`
+}
+
+// Valid reports whether the log has no violation.
+func (r *Report) Valid() bool {
+	return len(r.Violations) == 0
+}
+
+// First returns the first violation, or nil if the log is valid.
+func (r *Report) First() *Violation {
+	if len(r.Violations) == 0 {
+		return nil
+	}
+	return &r.Violations[0]
+}
+
+// Cascade returns the indexes of the violations attributed to the i-th violation.
+func (r *Report) Cascade(i int) []int {
+	cascade := []int{}
+	for j, v := range r.Violations {
+		if v.CausedBy == i {
+			cascade = append(cascade, j)
+		}
+	}
+	return cascade
+}
+
+// fail records a failed entry and its violation.
+func (r *Report) fail(result EntryResult, v Violation) {
+	result.OK = false
+	v.Entry, v.Cycle, v.Process = result.Index, result.Cycle, result.Process
+	r.Entries = append(r.Entries, result)
+	r.Violations = append(r.Violations, v)
+}
+
+// snapshot records a copy of the stocks at a cycle.
+func (r *Report) snapshot(cycle int, stocks map[string]int) {
+	r.Snapshots = append(r.Snapshots, StockSnapshot{Cycle: cycle, Stock: maps.Clone(stocks)})
+}
+
+// WriteText writes the report in the checker's human-readable form: one "Evaluating"
+// line per evaluated entry, the lateness of each order if there are any, then either the
+// success message or each root violation followed by the violations it caused.
+func (r *Report) WriteText(w io.Writer) error {
+	for _, e := range r.Entries {
+		if _, err := fmt.Fprintf(w, "Evaluating: %d:%s\n", e.Cycle, e.Process); err != nil {
+			return err
+		}
+	}
+	for _, o := range r.Orders {
+		if _, err := fmt.Fprintf(w, "Order %s\n", o); err != nil {
+			return err
+		}
+	}
+	if r.Valid() {
+		_, err := fmt.Fprintln(w, "Trace completed. No error detected.")
+		return err
+	}
+	for i, v := range r.Violations {
+		if v.CausedBy != -1 {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "Verification failed: %s\n", v.Message); err != nil {
+			return err
+		}
+		for _, j := range r.Cascade(i) {
+			if _, err := fmt.Fprintf(w, "  caused: %s\n", r.Violations[j].Message); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the report as an indented JSON document.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// junitSuites is the root element of a JUnit XML report.
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+// junitSuite groups the test cases of one verified log.
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+// junitCase is one log entry, or the final state check.
+type junitCase struct {
+	Name      string         `xml:"name,attr"`
+	Classname string         `xml:"classname,attr"`
+	Failures  []junitFailure `xml:"failure"`
+}
+
+// junitFailure describes a violation of a test case.
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as JUnit XML, with one test case per evaluated entry
+// and a last "final state" test case for the footer and stock block checks.
+//
+// Parameters:
+//   - w: the destination of the XML document.
+//   - name: the test suite name, typically the log file path.
+func (r *Report) WriteJUnit(w io.Writer, name string) error {
+	suite := junitSuite{Name: name}
+	byEntry := map[int][]junitFailure{}
+	for _, v := range r.Violations {
+		f := junitFailure{Message: v.Message, Type: string(v.Kind), Text: v.Message}
+		if v.CausedBy != -1 {
+			f.Text += "\ncaused by: " + r.Violations[v.CausedBy].Message
+		}
+		byEntry[v.Entry] = append(byEntry[v.Entry], f)
+	}
+
+	for _, e := range r.Entries {
+		suite.Cases = append(suite.Cases, junitCase{
+			Name:      fmt.Sprintf("%d:%s", e.Cycle, e.Process),
+			Classname: name,
+			Failures:  byEntry[e.Index],
+		})
+	}
+	suite.Cases = append(suite.Cases, junitCase{Name: "final state", Classname: name, Failures: byEntry[-1]})
+
+	suite.Tests = len(suite.Cases)
+	for _, c := range suite.Cases {
+		if len(c.Failures) > 0 {
+			suite.Failures++
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitSuites{Suites: []junitSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,67 @@
+package checker
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+
+	"github.com/jesee-kuya/stock_exchange/engine"
+)
+
+// TestVerifyReport tests that Verify records entry results, snapshots per cycle,
+// the first violation and the score metrics.
+func TestVerifyReport(t *testing.T) {
+	c := newShelfChecker()
+	c.OptimizeTargets = []string{"shelf"}
+	c.Log = []engine.ScheduleEntry{{Cycle: 0, ProcessName: "do_shelf"}, {Cycle: 0, ProcessName: "do_shelf"}, {Cycle: 10, ProcessName: "do_table"}}
+
+	report, err := c.Verify()
+	if err == nil || report.First() == nil || report.First().Kind != UnknownProcess {
+		t.Fatalf("expected an unknown process violation, got %v", err)
+	}
+	if len(report.Entries) != 3 || !report.Entries[1].OK || report.Entries[2].OK {
+		t.Errorf("unexpected entry results %+v", report.Entries)
+	}
+	if len(report.Snapshots) != 2 || report.Snapshots[0].Stock["board"] != 1 || report.Snapshots[1].Cycle != 10 {
+		t.Errorf("unexpected snapshots %+v", report.Snapshots)
+	}
+	want := Metrics{Entries: 3, EndCycle: 10, Makespan: 10, TargetQuantity: 2}
+	if report.Metrics != want {
+		t.Errorf("Metrics = %+v, want %+v", report.Metrics, want)
+	}
+}
+
+// TestReportFormats tests that the JSON and JUnit renderings are well-formed and
+// carry the violations.
+func TestReportFormats(t *testing.T) {
+	c := newShelfChecker()
+	c.Log = []engine.ScheduleEntry{{Cycle: 0, ProcessName: "do_shelf"}}
+	c.ExpectedStock = map[string]int{"board": 2, "shelf": 2}
+	report, _ := c.Verify()
+
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(decoded.Violations) != 1 || decoded.Violations[0].Kind != StockMismatch || decoded.FinalStock["shelf"] != 1 {
+		t.Errorf("unexpected decoded report %+v", decoded)
+	}
+
+	buf.Reset()
+	if err := report.WriteJUnit(&buf, "test.log"); err != nil {
+		t.Fatal(err)
+	}
+	var suites junitSuites
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatalf("invalid XML: %v", err)
+	}
+	suite := suites.Suites[0]
+	if suite.Tests != 2 || suite.Failures != 1 || len(suite.Cases[1].Failures) != 1 {
+		t.Errorf("unexpected suite %+v", suite)
+	}
+}
@@ -2,7 +2,10 @@ package checker
 
 import (
 	"fmt"
+	"maps"
+	"sort"
 
+	"github.com/jesee-kuya/stock_exchange/engine"
 	"github.com/jesee-kuya/stock_exchange/process"
 )
 
@@ -11,75 +14,423 @@ import (
 //   - Each process in the log exists in the list of known processes.
 //   - Sufficient stock is available for each process's needs at the time it is executed.
 //   - Outputs from processes are applied after the required number of cycles.
+//   - Once the processes of a cycle have started, the stock of every item with a storage
+//     capacity, counting the production of the processes still running, fits in it, as the
+//     engine requires.
+//   - Perishable units are consumed oldest first and removed once their shelf life has
+//     elapsed, with the same lots as the engine (see engine.Lots).
+//   - With an outcome trace (see LoadOutcomes), each run produces what the trace says
+//     instead of its expected results, provided the process can have that outcome.
+//   - Deliveries arrive and orders ship as in the engine (see engine.Timeline): at every
+//     cycle a process starts or completes or a delivery arrives, after the completions and
+//     the expiry. What became of each order is recorded in the report.
 //
-// If any inconsistency is found (such as an unknown process or insufficient stock), an error is returned
-// describing the issue and the cycle at which it occurred. If the log is valid, it returns nil.
-func (c *Checker) Verify() error {
+// With the default StopAtFirst policy the simulation stops at the first inconsistency (such as
+// an unknown process or insufficient stock). The SkipEntry and AllowNegative policies keep
+// simulating past violations, so that every problem of the log is reported at once, each with
+// the earlier violation it is attributed to (see Violation.CausedBy). The footer and expected
+// stock block of the log, when present, are then checked against the final state.
+//
+// Returns:
+//   - A report with the result of every evaluated entry, a stock snapshot per cycle, the
+//     violations found, the final stocks and the score of the schedule. Nothing is printed;
+//     see Report.WriteText, Report.WriteJSON and Report.WriteJUnit.
+//   - The first violation as an error, nil if the log is valid.
+func (c *Checker) Verify() (*Report, error) {
+	report := c.replay(c.Policy, true)
+	if len(report.Violations) == 0 || c.Policy != StopAtFirst {
+		c.verifyFinalState(report)
+	}
+	report.Metrics = c.metrics(report)
+	if first := report.First(); first != nil {
+		return report, first
+	}
+	return report, nil
+}
+
+// Replay applies the same rules as Verify with the StopAtFirst policy, without the final
+// state checks and without recording snapshots, and returns the stocks once every process
+// in the log has completed. It is meant for tools that need to check many candidate logs,
+// such as the schedule improver.
+func (c *Checker) Replay() (map[string]int, error) {
+	stocks, _, err := c.ReplayOrders()
+	return stocks, err
+}
+
+// ReplayOrders is Replay, also returning what became of each customer order.
+func (c *Checker) ReplayOrders() (map[string]int, []OrderStatus, error) {
+	report := c.replay(StopAtFirst, false)
+	if first := report.First(); first != nil {
+		return nil, nil, first
+	}
+	return report.FinalStock, report.Orders, nil
+}
+
+// replay simulates the log against the initial stocks, handling violations according to
+// the policy. When record is set, the stocks are snapshotted after the last start of each cycle.
+//
+// To attribute cascades, every item made short by a violation is tainted with the index of
+// its root violation: the results of a skipped entry under SkipEntry, the overdrawn needs
+// under AllowNegative. A later shortage of a tainted item is attributed to that root.
+func (c *Checker) replay(policy Policy, record bool) *Report {
+	report := &Report{Entries: []EntryResult{}, Snapshots: []StockSnapshot{}, Violations: []Violation{}, Expired: map[string]int{}}
 	stocks := make(map[string]int)
 	for k, v := range c.Stocks {
 		stocks[k] = v
 	}
+	lots := engine.NewLots(c.ShelfLife, c.Stocks)
+	timeline := engine.NewTimeline(c.Deliveries, c.Orders)
+	taint := map[string]int{}
+	cycleEntries := []int{}
+	stopped := false
 
 	// Pending outputs map: cycle -> items
 	pending := make(map[int]map[string]int)
 	currentCycle := 0
+	advance(report, stocks, lots, pending, timeline, 0, 0)
 
-	for _, entry := range c.Log {
-		fmt.Printf("Evaluating: %d:%s\n", entry.Cycle, entry.ProcessName)
-
-		// Apply any pending outputs from prior cycles
-		for cycle := currentCycle; cycle <= entry.Cycle; cycle++ {
-			if outputs, ok := pending[cycle]; ok {
-				for item, qty := range outputs {
-					stocks[item] += qty
-				}
-				delete(pending, cycle)
+	for i, entry := range c.Log {
+		if i > 0 && entry.Cycle != currentCycle {
+			if c.checkStorage(report, stocks, pending, cycleEntries) && policy == StopAtFirst {
+				stopped = true
+				break
+			}
+			cycleEntries = cycleEntries[:0]
+			if record {
+				report.snapshot(currentCycle, stocks)
 			}
 		}
+
+		// Apply any pending outputs and events from prior cycles
+		advance(report, stocks, lots, pending, timeline, currentCycle, entry.Cycle)
 		currentCycle = entry.Cycle
+		if policy == AllowNegative {
+			clearRepaid(taint, stocks)
+		}
+
+		result := EntryResult{Index: i, Cycle: entry.Cycle, Process: entry.ProcessName, OK: true}
 
 		// Find the process
-		var proc *process.Process
-		for _, p := range c.Processes {
-			if p.Name == entry.ProcessName {
-				proc = p
+		proc := c.process(entry.ProcessName)
+		if proc == nil {
+			report.fail(result, Violation{
+				Kind:     UnknownProcess,
+				CausedBy: -1,
+				Message:  fmt.Sprintf("unknown process '%s' at cycle %d", entry.ProcessName, entry.Cycle),
+			})
+			if policy == StopAtFirst {
+				stopped = true
 				break
 			}
-		}
-		if proc == nil {
-			return fmt.Errorf("unknown process '%s' at cycle %d", entry.ProcessName, entry.Cycle)
+			continue
 		}
 
 		// Check if enough stock exists
-		for item, qty := range proc.Needs {
-			if stocks[item] < qty {
-				return fmt.Errorf("insufficient stock for '%s' at cycle %d: need %d %s, have %d",
-					proc.Name, entry.Cycle, qty, item, stocks[item])
+		if v, ok := checkNeeds(proc, entry, stocks); !ok {
+			v.CausedBy = cause(v.Missing, taint)
+			root := v.CausedBy
+			if root == -1 {
+				root = len(report.Violations)
+			}
+			report.fail(result, v)
+
+			if policy == StopAtFirst {
+				stopped = true
+				break
 			}
+			if policy == SkipEntry {
+				for item := range proc.Result {
+					taint[item] = root
+				}
+				continue
+			}
+			// AllowNegative: run the process anyway, overdrawing the missing items
+			for item := range v.Missing {
+				taint[item] = root
+			}
+		} else {
+			report.Entries = append(report.Entries, result)
 		}
 
-		// Deduct input from stocks
+		// Deduct input from stocks, oldest lots first
 		for item, qty := range proc.Needs {
 			stocks[item] -= qty
+			lots.Take(item, qty)
 		}
 
-		// Schedule outputs
+		cycleEntries = append(cycleEntries, i)
+
+		// Schedule outputs, as the outcome trace tells when there is one
+		output := proc.Output()
+		if i < len(c.Outcomes) {
+			if err := proc.CheckOutcome(c.Outcomes[i]); err != nil {
+				report.Entries[len(report.Entries)-1].OK = false
+				report.Violations = append(report.Violations, Violation{
+					Kind:     ImpossibleOutcome,
+					Entry:    i,
+					Cycle:    entry.Cycle,
+					Process:  entry.ProcessName,
+					CausedBy: -1,
+					Message:  fmt.Sprintf("impossible outcome '%s' at cycle %d: %v", c.Outcomes[i], entry.Cycle, err),
+				})
+				if policy == StopAtFirst {
+					stopped = true
+					break
+				}
+			} else {
+				output = proc.OutputOf(c.Outcomes[i])
+			}
+		}
 		dueCycle := entry.Cycle + proc.Cycle
 		if pending[dueCycle] == nil {
 			pending[dueCycle] = make(map[string]int)
 		}
-		for item, qty := range proc.Result {
+		for item, qty := range output {
 			pending[dueCycle][item] += qty
 		}
 	}
+	if !stopped {
+		c.checkStorage(report, stocks, pending, cycleEntries)
+	}
+	if record && len(report.Entries) > 0 {
+		report.snapshot(currentCycle, stocks)
+	}
 
-	// Flush remaining pending outputs
-	for _, outputs := range pending {
+	// Flush remaining pending outputs, up to the last completion or delivery
+	lastCycle := currentCycle
+	for cycle := range pending {
+		lastCycle = max(lastCycle, cycle)
+	}
+	for _, d := range timeline.Upcoming() {
+		lastCycle = max(lastCycle, d.Cycle)
+	}
+	advance(report, stocks, lots, pending, timeline, currentCycle, lastCycle)
+
+	report.FinalStock = stocks
+	for _, r := range timeline.Results() {
+		report.Orders = append(report.Orders, newOrderStatus(r))
+	}
+	return report
+}
+
+// cause returns the root violation a shortage is attributed to: the taint of the first
+// missing item in lexical order that is tainted, or -1 when none is.
+func cause(missing map[string]int, taint map[string]int) int {
+	items := make([]string, 0, len(missing))
+	for item := range missing {
+		items = append(items, item)
+	}
+	sort.Strings(items)
+	for _, item := range items {
+		if root, ok := taint[item]; ok {
+			return root
+		}
+	}
+	return -1
+}
+
+// clearRepaid removes the taint of the items whose stock is no longer negative.
+func clearRepaid(taint map[string]int, stocks map[string]int) {
+	for item := range taint {
+		if stocks[item] >= 0 {
+			delete(taint, item)
+		}
+	}
+}
+
+// advance brings the stocks from cycle from to cycle to, one event cycle at a time as
+// the engine does: the outputs due at that cycle are added, the expired units removed,
+// then the deliveries and orders of the cycle applied. Outputs due at from itself are
+// included, as in applyPending.
+func advance(report *Report, stocks map[string]int, lots *engine.Lots, pending map[int]map[string]int, timeline *engine.Timeline, from, to int) {
+	cycles := []int{to}
+	for cycle := range pending {
+		if cycle >= from && cycle < to {
+			cycles = append(cycles, cycle)
+		}
+	}
+	for _, d := range timeline.Upcoming() {
+		if d.Cycle < to {
+			cycles = append(cycles, d.Cycle)
+		}
+	}
+	sort.Ints(cycles)
+
+	for i, cycle := range cycles {
+		if i > 0 && cycle == cycles[i-1] {
+			continue
+		}
+		applyPending(stocks, lots, pending, cycle, cycle)
+		expire(report, stocks, lots, cycle)
+		timeline.Advance(cycle, stocks, func(item string, qty int) {
+			stocks[item] += qty
+			if qty > 0 {
+				lots.Add(item, qty, cycle)
+			} else {
+				lots.Take(item, -qty)
+			}
+		})
+	}
+}
+
+// applyPending adds to stocks the outputs due in the cycles from..to, both included,
+// records the perishable ones as lots produced at their due cycle, and removes them
+// from pending.
+func applyPending(stocks map[string]int, lots *engine.Lots, pending map[int]map[string]int, from, to int) {
+	for cycle, outputs := range pending {
+		if cycle < from || cycle > to {
+			continue
+		}
 		for item, qty := range outputs {
 			stocks[item] += qty
+			lots.Add(item, qty, cycle)
+		}
+		delete(pending, cycle)
+	}
+}
+
+// expire removes from stocks the perishable units whose shelf life has elapsed at the
+// given cycle and adds them to the expired quantities of the report.
+func expire(report *Report, stocks map[string]int, lots *engine.Lots, cycle int) {
+	for item, qty := range lots.Expire(cycle) {
+		stocks[item] -= qty
+		report.Expired[item] += qty
+	}
+}
+
+// checkNeeds reports whether the stocks cover the needs of a process started by an entry,
+// and otherwise returns the violation, listing every missing item. When only renewable
+// resources are missing, every unit is held by running processes and the violation is a
+// CapacityExceeded one.
+func checkNeeds(proc *process.Process, entry engine.ScheduleEntry, stocks map[string]int) (Violation, bool) {
+	missing := map[string]int{}
+	items := []string{}
+	for item, qty := range proc.Needs {
+		if stocks[item] < qty {
+			missing[item] = qty - stocks[item]
+			items = append(items, item)
+		}
+	}
+	if len(missing) == 0 {
+		return Violation{}, true
+	}
+
+	sort.Strings(items)
+	item := items[0]
+	held := 0
+	for _, item := range items {
+		if proc.Holds[item] > 0 {
+			held++
+		}
+	}
+	if held == len(items) {
+		return Violation{
+			Kind: CapacityExceeded,
+			Message: fmt.Sprintf("capacity of '%s' exceeded at cycle %d: '%s' holds %d, %d free",
+				item, entry.Cycle, proc.Name, proc.Needs[item], stocks[item]),
+			Missing: missing,
+		}, false
+	}
+	return Violation{
+		Kind: InsufficientStock,
+		Message: fmt.Sprintf("insufficient stock for '%s' at cycle %d: need %d %s, have %d",
+			proc.Name, entry.Cycle, proc.Needs[item], item, stocks[item]),
+		Missing: missing,
+	}, false
+}
+
+// checkStorage records a StorageOverflow violation for every item whose stock, plus the
+// quantity still pending from running processes, exceeds its storage capacity once the
+// entries of a cycle have started. The violation is attributed to the last entry of the
+// cycle producing the item; items no entry of the cycle produces are left alone, as their
+// overflow has already been reported. Since starts only take from the stocks, the order of
+// the entries within a cycle does not matter, as for their needs.
+//
+// Returns:
+//   - true if a violation was recorded.
+func (c *Checker) checkStorage(report *Report, stocks map[string]int, pending map[int]map[string]int, cycleEntries []int) bool {
+	if len(c.Capacities) == 0 || len(cycleEntries) == 0 {
+		return false
+	}
+	total := maps.Clone(stocks)
+	for _, outputs := range pending {
+		for item, qty := range outputs {
+			total[item] += qty
 		}
 	}
 
-	fmt.Println("Trace completed. No error detected.")
+	found := false
+	items := make([]string, 0, len(c.Capacities))
+	for item := range c.Capacities {
+		items = append(items, item)
+	}
+	sort.Strings(items)
+	for _, item := range items {
+		capacity := c.Capacities[item]
+		if total[item] <= capacity {
+			continue
+		}
+		for k := len(cycleEntries) - 1; k >= 0; k-- {
+			i := cycleEntries[k]
+			entry := c.Log[i]
+			if proc := c.process(entry.ProcessName); proc == nil || proc.Result[item] == 0 {
+				continue
+			}
+			report.Entries[i].OK = false
+			report.Violations = append(report.Violations, Violation{
+				Kind:     StorageOverflow,
+				Entry:    i,
+				Cycle:    entry.Cycle,
+				Process:  entry.ProcessName,
+				CausedBy: -1,
+				Message: fmt.Sprintf("storage of '%s' overflows at cycle %d: '%s' brings it to %d counting production in flight, capacity %d",
+					item, entry.Cycle, entry.ProcessName, total[item], capacity),
+			})
+			found = true
+			break
+		}
+	}
+	return found
+}
+
+// metrics scores the successfully replayed entries of the log against the optimize targets.
+func (c *Checker) metrics(report *Report) Metrics {
+	m := Metrics{Entries: len(report.Entries)}
+	replayed := []engine.ScheduleEntry{}
+	for _, result := range report.Entries {
+		if result.OK {
+			replayed = append(replayed, c.Log[result.Index])
+		}
+	}
+	for _, entry := range replayed {
+		end := entry.Cycle
+		if p := c.process(entry.ProcessName); p != nil {
+			end += p.Cycle
+		}
+		m.EndCycle = max(m.EndCycle, end)
+	}
+
+	obj := engine.NewObjective(c.OptimizeTargets, c.Processes, c.Stocks)
+	m.Makespan = obj.MakespanOf(replayed, c.Processes)
+	m.TargetQuantity = obj.Evaluate(report.FinalStock, m.Makespan).Quantity
+	for _, o := range report.Orders {
+		switch {
+		case o.Shipped < 0:
+			m.UnfilledOrders++
+		case o.Late > 0:
+			m.LateOrders++
+		}
+		m.Penalty += o.Cost
+	}
+	return m
+}
+
+// process returns the process with the given name, or nil when it is unknown.
+func (c *Checker) process(name string) *process.Process {
+	for _, p := range c.Processes {
+		if p.Name == name {
+			return p
+		}
+	}
 	return nil
 }
@@ -0,0 +1,220 @@
+package checker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jesee-kuya/stock_exchange/engine"
+	"github.com/jesee-kuya/stock_exchange/process"
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// newTableChecker returns a checker where one board makes a leg and four legs make a table.
+func newTableChecker(policy Policy) *Checker {
+	c := NewChecker()
+	c.Policy = policy
+	c.Stocks = map[string]int{"board": 3}
+	c.Processes = []*process.Process{
+		{Name: "do_leg", Needs: map[string]int{"board": 1}, Result: map[string]int{"leg": 1}, Cycle: 5},
+		{Name: "do_table", Needs: map[string]int{"leg": 4}, Result: map[string]int{"table": 1}, Cycle: 10},
+	}
+	c.Log = []engine.ScheduleEntry{
+		{Cycle: 0, ProcessName: "do_leg"},
+		{Cycle: 0, ProcessName: "do_leg"},
+		{Cycle: 0, ProcessName: "do_leg"},
+		{Cycle: 0, ProcessName: "do_leg"},
+		{Cycle: 1, ProcessName: "do_chair"},
+		{Cycle: 5, ProcessName: "do_table"},
+	}
+	return c
+}
+
+// TestVerifyCollectAll tests that the collect-all policies report every violation and
+// attribute the later failures to the violation that caused them.
+func TestVerifyCollectAll(t *testing.T) {
+	testCases := []struct {
+		name       string
+		policy     Policy
+		kinds      []ViolationKind
+		causedBy   []int
+		finalTable int
+	}{
+		{"stop at first", StopAtFirst, []ViolationKind{InsufficientStock}, []int{-1}, 0},
+		{"skip entry", SkipEntry, []ViolationKind{InsufficientStock, UnknownProcess, InsufficientStock}, []int{-1, -1, 0}, 0},
+		{"allow negative", AllowNegative, []ViolationKind{InsufficientStock, UnknownProcess}, []int{-1, -1}, 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			report, err := newTableChecker(tc.policy).Verify()
+			if err == nil {
+				t.Fatal("expected a violation")
+			}
+			if len(report.Violations) != len(tc.kinds) {
+				t.Fatalf("got %d violations, want %d: %+v", len(report.Violations), len(tc.kinds), report.Violations)
+			}
+			for i, v := range report.Violations {
+				if v.Kind != tc.kinds[i] || v.CausedBy != tc.causedBy[i] {
+					t.Errorf("violation %d = %s caused by %d, want %s caused by %d", i, v.Kind, v.CausedBy, tc.kinds[i], tc.causedBy[i])
+				}
+			}
+			if first := report.Violations[0]; first.Missing["board"] != 1 || first.Entry != 3 {
+				t.Errorf("unexpected first violation %+v", first)
+			}
+			if report.FinalStock["table"] != tc.finalTable {
+				t.Errorf("final tables = %d, want %d", report.FinalStock["table"], tc.finalTable)
+			}
+		})
+	}
+}
+
+// TestVerifyCapacity tests that starting a process while every unit of a renewable
+// resource it holds is in use is reported as a capacity violation.
+func TestVerifyCapacity(t *testing.T) {
+	c := NewChecker()
+	c.Stocks = map[string]int{"flour": 3, "oven": 1}
+	c.Processes = []*process.Process{{
+		Name:   "bake",
+		Needs:  map[string]int{"flour": 1, "oven": 1},
+		Result: map[string]int{"bread": 1},
+		Cycle:  5,
+		Holds:  map[string]int{"oven": 1},
+	}}
+	c.Log = []engine.ScheduleEntry{{Cycle: 0, ProcessName: "bake"}, {Cycle: 5, ProcessName: "bake"}}
+	if _, err := c.Verify(); err != nil {
+		t.Fatalf("sequential use of the oven should verify: %v", err)
+	}
+
+	c.Log = append(c.Log, engine.ScheduleEntry{Cycle: 6, ProcessName: "bake"})
+	report, err := c.Verify()
+	if err == nil || report.Violations[0].Kind != CapacityExceeded || report.Violations[0].Entry != 2 {
+		t.Errorf("expected a capacity violation at entry 2, got %+v", report.Violations)
+	}
+}
+
+// TestVerifyStorage tests that a cycle whose starts overflow the storage of an item, counting
+// the production in flight, is a storage violation, whatever the order of its entries.
+func TestVerifyStorage(t *testing.T) {
+	c := NewChecker()
+	c.Stocks = map[string]int{"flour": 3, "bread": 0}
+	c.Capacities = map[string]int{"bread": 1}
+	c.Processes = []*process.Process{
+		{Name: "bake", Needs: map[string]int{"flour": 1}, Result: map[string]int{"bread": 1}, Cycle: 5},
+		{Name: "sell", Needs: map[string]int{"bread": 1}, Result: map[string]int{"euro": 2}, Cycle: 1},
+	}
+	c.Log = []engine.ScheduleEntry{{Cycle: 0, ProcessName: "bake"}, {Cycle: 5, ProcessName: "bake"}, {Cycle: 5, ProcessName: "sell"}}
+	if _, err := c.Verify(); err != nil {
+		t.Fatalf("baking again while selling should verify: %v", err)
+	}
+
+	c.Log = []engine.ScheduleEntry{{Cycle: 0, ProcessName: "bake"}, {Cycle: 1, ProcessName: "bake"}}
+	report, err := c.Verify()
+	if err == nil || report.Violations[0].Kind != StorageOverflow || report.Violations[0].Entry != 1 {
+		t.Errorf("expected a storage violation at entry 1, got %+v", report.Violations)
+	}
+}
+
+// TestVerifyPerishable tests that the replay consumes perishable units oldest first and
+// drops them once expired, as the engine does.
+func TestVerifyPerishable(t *testing.T) {
+	c := NewChecker()
+	c.Stocks = map[string]int{"flour": 2, "dough": 1}
+	c.ShelfLife = map[string]int{"dough": 4}
+	c.Processes = []*process.Process{
+		{Name: "knead", Needs: map[string]int{"flour": 1}, Result: map[string]int{"dough": 1}, Cycle: 2},
+		{Name: "bake", Needs: map[string]int{"dough": 1}, Result: map[string]int{"bread": 1}, Cycle: 5},
+	}
+	c.Log = []engine.ScheduleEntry{{Cycle: 0, ProcessName: "knead"}, {Cycle: 3, ProcessName: "bake"}, {Cycle: 5, ProcessName: "bake"}}
+	report, err := c.Verify()
+	if err != nil {
+		t.Fatalf("baking before the dough expires should verify: %v", err)
+	}
+	if report.FinalStock["bread"] != 2 || len(report.Expired) != 0 {
+		t.Errorf("final stock = %v, expired = %v, want 2 bread and nothing lost", report.FinalStock, report.Expired)
+	}
+
+	// The initial dough is used first, so the kneaded one is gone by cycle 6
+	c.Log = []engine.ScheduleEntry{{Cycle: 0, ProcessName: "knead"}, {Cycle: 2, ProcessName: "bake"}, {Cycle: 6, ProcessName: "bake"}}
+	report, err = c.Verify()
+	if err == nil || report.Violations[0].Kind != InsufficientStock || report.Violations[0].Entry != 2 {
+		t.Errorf("expected a shortage at entry 2, got %+v", report.Violations)
+	}
+	if report.Expired["dough"] != 1 {
+		t.Errorf("expired = %v, want 1 dough", report.Expired)
+	}
+}
+
+// TestVerifyOutcomes tests that a log is replayed with the outcomes of its trace, and
+// that an outcome the process cannot have is reported.
+func TestVerifyOutcomes(t *testing.T) {
+	c := NewChecker()
+	c.Stocks = map[string]int{"dough": 2, "oven": 1}
+	c.Processes = []*process.Process{
+		{
+			Name: "bake", Needs: map[string]int{"dough": 1, "oven": 1}, Result: map[string]int{"bread": 2}, Cycle: 5,
+			Holds: map[string]int{"oven": 1}, Failure: 0.1, Yields: map[string]process.Yield{"bread": {Min: 2, Max: 3}},
+		},
+	}
+	c.Log = []engine.ScheduleEntry{{Cycle: 0, ProcessName: "bake"}, {Cycle: 5, ProcessName: "bake"}}
+	path := filepath.Join(t.TempDir(), "bake.log.outcomes")
+	if err := os.WriteFile(path, []byte("0:bake => failed\n5:bake => (bread:3)\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.LoadOutcomes(path); err != nil {
+		t.Fatal(err)
+	}
+	report, err := c.Verify()
+	if err != nil {
+		t.Fatalf("the traced outcomes should verify: %v", err)
+	}
+	if report.FinalStock["bread"] != 3 || report.FinalStock["oven"] != 1 {
+		t.Errorf("final stock = %v, want 3 bread and the oven", report.FinalStock)
+	}
+
+	c.Outcomes[1] = process.Outcome{Result: map[string]int{"bread": 5}}
+	report, err = c.Verify()
+	if err == nil || report.Violations[0].Kind != ImpossibleOutcome || report.Violations[0].Entry != 1 {
+		t.Errorf("expected an impossible outcome at entry 1, got %+v", report.Violations)
+	}
+
+	if err := os.WriteFile(path, []byte("0:bake => failed\n6:bake => (bread:3)\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.LoadOutcomes(path); err == nil || !strings.Contains(err.Error(), ":2:") {
+		t.Errorf("expected an error on line 2 for a trace that does not match the log, got %v", err)
+	}
+}
+
+// TestVerifyEvents tests that the replay applies the deliveries at their cycle and ships
+// the orders as the engine does, reporting their lateness.
+func TestVerifyEvents(t *testing.T) {
+	c := NewChecker()
+	c.Stocks = map[string]int{"flour": 1}
+	c.Processes = []*process.Process{
+		{Name: "bake", Needs: map[string]int{"flour": 1}, Result: map[string]int{"bread": 1}, Cycle: 5},
+	}
+	c.Deliveries = []util.Delivery{{Cycle: 10, Items: map[string]int{"flour": 1}}}
+	c.Orders = []util.Order{
+		{Due: 5, Items: map[string]int{"bread": 1}, Penalty: 1},
+		{Due: 12, Items: map[string]int{"bread": 1}, Penalty: 2},
+	}
+	c.Log = []engine.ScheduleEntry{{Cycle: 0, ProcessName: "bake"}, {Cycle: 10, ProcessName: "bake"}}
+	report, err := c.Verify()
+	if err != nil {
+		t.Fatalf("baking the delivered flour should verify: %v", err)
+	}
+	if len(report.Orders) != 2 || report.Orders[0].Late != 0 || report.Orders[1].Shipped != 15 || report.Orders[1].Cost != 6 {
+		t.Errorf("orders = %+v, want the first on time and the second shipped 3 cycles late", report.Orders)
+	}
+	if report.FinalStock["bread"] != 0 {
+		t.Errorf("final stock = %v, want the bread shipped", report.FinalStock)
+	}
+
+	c.Log = []engine.ScheduleEntry{{Cycle: 0, ProcessName: "bake"}, {Cycle: 9, ProcessName: "bake"}}
+	report, err = c.Verify()
+	if err == nil || report.Violations[0].Kind != InsufficientStock || report.Violations[0].Entry != 1 {
+		t.Errorf("expected a shortage at entry 1 before the delivery, got %+v", report.Violations)
+	}
+}
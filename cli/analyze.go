@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/jesee-kuya/stock_exchange/analysis"
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// analyze is responsible for the steady-state throughput analysis.
+// It expects the configuration file path, solves the throughput linear programs
+// and prints the maximal rate and process mix of each optimize target.
+func analyze(args []string) int {
+	fs := newFlagSet("analyze")
+	formatName := formatFlag(fs, "format")
+	if !parseArgs(fs, args, 1) {
+		return ExitUsage
+	}
+	format, ok := parseFormat(*formatName)
+	if !ok {
+		return ExitUsage
+	}
+	config, err := util.ParseConfigAs(fs.Arg(0), format)
+	if err != nil {
+		return configError(err)
+	}
+	rates, err := analysis.Throughput(config)
+	if err != nil {
+		return fail(err)
+	}
+	analysis.PrintThroughput(os.Stdout, rates)
+	return ExitOK
+}
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	checker "github.com/jesee-kuya/stock_exchange/checker_util"
+)
+
+// check verifies a schedule log against its configuration and prints the report.
+// It expects two positional arguments: the configuration file path and the log file path.
+//
+// With -outcomes, the runs are replayed with the outcomes of the given trace, as saved by
+// "schedule -seed", instead of their expected results.
+//
+// Returns ExitParse when either file, or the outcome trace, is malformed and ExitInvalid when the log fails
+// verification.
+func check(args []string) int {
+	fs := newFlagSet("check")
+	format := fs.String("format", "text", "report format, one of: text, json, junit")
+	policy := fs.String("policy", "first",
+		"violation policy: first (stop at the first error), skip (skip the entry) or negative (allow negative stock)")
+	configFormatName := formatFlag(fs, "config-format")
+	outcomesPath := fs.String("outcomes", "", "outcome trace of the log, as saved by schedule -seed")
+	if !parseArgs(fs, args, 2) {
+		return ExitUsage
+	}
+	configFormat, ok := parseFormat(*configFormatName)
+	if !ok {
+		return ExitUsage
+	}
+	if *format != "text" && *format != "json" && *format != "junit" {
+		fmt.Fprintf(os.Stderr, "unknown format %q, expected text, json or junit\n", *format)
+		return ExitUsage
+	}
+	violationPolicy, err := checker.ParsePolicy(*policy)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitUsage
+	}
+	configPath, logPath := fs.Arg(0), fs.Arg(1)
+
+	chk := checker.NewChecker()
+	chk.Policy = violationPolicy
+	if err := chk.LoadConfigAs(configPath, configFormat); err != nil {
+		return configError(err)
+	}
+	if err := chk.LoadLog(logPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading log: %v\n", err)
+		if os.IsNotExist(err) {
+			return ExitError
+		}
+		return ExitParse
+	}
+	if *outcomesPath != "" {
+		if err := chk.LoadOutcomes(*outcomesPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading outcomes: %v\n", err)
+			if os.IsNotExist(err) {
+				return ExitError
+			}
+			return ExitParse
+		}
+	}
+
+	report, verifyErr := chk.Verify()
+	switch *format {
+	case "json":
+		err = report.WriteJSON(os.Stdout)
+	case "junit":
+		err = report.WriteJUnit(os.Stdout, logPath)
+	default:
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		return fail(fmt.Errorf("writing report: %w", err))
+	}
+	if verifyErr != nil {
+		return ExitInvalid
+	}
+	return ExitOK
+}
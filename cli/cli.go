@@ -0,0 +1,159 @@
+// Package cli implements the stock_exchange command line: one binary whose subcommands
+// schedule, check, lint, analyze, graph, improve, convert and simulate configurations and logs.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// Exit codes returned by Run, so that shell scripts and CI can tell failures apart.
+const (
+	ExitOK      = 0 // the command succeeded
+	ExitError   = 1 // a runtime failure, such as an unwritable file or nothing runnable
+	ExitUsage   = 2 // bad command, flags or arguments
+	ExitParse   = 3 // the configuration or log file is malformed
+	ExitInvalid = 4 // the log failed verification or the configuration failed lint
+	ExitTimeout = 5 // the scheduler hit the time limit before running out of work
+)
+
+// command is one subcommand of the binary.
+type command struct {
+	usage   string
+	summary string
+	run     func(args []string) int
+}
+
+// commands maps each subcommand name to its implementation.
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"schedule": {"schedule [-scheduler <name>] [-opt key=value]... [-o <path>] [-format <fmt>] [-seed <n> [-outcomes <path>]] <config_file> <wait_time>", "run a scheduler and save the log", schedule},
+		"check":    {"check [-format text|json|junit] [-policy first|skip|negative] [-config-format <fmt>] [-outcomes <path>] <config_file> <log_file>", "verify a log against its configuration", check},
+		"lint":     {"lint [-format <fmt>] <config_file>", "report semantic problems of a configuration", lint},
+		"analyze":  {"analyze [-format <fmt>] <config_file>", "report the steady-state throughput of the optimize targets", analyze},
+		"graph":    {"graph [-format <fmt>] <config_file>", "print the process graph in Graphviz DOT format", drawGraph},
+		"improve":  {"improve [-seed <n>] [-format <fmt>] <config_file> <log_file> <wait_time>", "polish a log by simulated annealing", improveLog},
+		"convert":  {"convert [-from <fmt>] [-to <fmt>] [-o <path>] <config_file>", "translate a configuration between the text, json and yaml formats", convert},
+		"simulate": {"simulate [-runs <n>] [-seed <n>] [-policy planned|eager] [-format <fmt>] <config_file> <log_file>", "replay a log with random process durations and outcomes", simulate},
+	}
+}
+
+// Run executes the command line given without the program name and returns the exit code.
+//
+// Behavior:
+//   - The first argument selects the subcommand; the remaining ones are its flags and arguments.
+//   - For compatibility with earlier versions, arguments that do not start with a subcommand
+//     name are handed to "schedule", so "stock_exchange <config_file> <wait_time>" still works.
+//   - "help", "-h" and "--help" print the usage and succeed; no argument at all is a usage error.
+func Run(args []string) int {
+	if len(args) == 0 {
+		usage(os.Stderr)
+		return ExitUsage
+	}
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		usage(os.Stdout)
+		return ExitOK
+	}
+	if cmd, ok := commands[args[0]]; ok {
+		return cmd.run(args[1:])
+	}
+	return schedule(args)
+}
+
+// usage prints the subcommands and their arguments.
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: stock_exchange <command> [flags] <arguments>")
+	fmt.Fprintln(w, "Commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-9s %s\n", name, commands[name].summary)
+		fmt.Fprintf(w, "            stock_exchange %s\n", commands[name].usage)
+	}
+	fmt.Fprintln(w, "Exit codes: 0 ok, 1 error, 2 usage, 3 parse error, 4 verification or lint failure, 5 time limit exceeded")
+}
+
+// newFlagSet returns the flag set of a subcommand. Flag errors are returned rather than
+// exiting, and the usage line of the subcommand is printed with its flags.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: stock_exchange "+commands[name].usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// isSet reports whether the flag with the given name was given on the command line, for
+// flags whose default value is also a meaningful one.
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
+
+// parseArgs parses the flags of a subcommand and checks its number of positional arguments.
+// It returns false, after printing the usage, when the command line is wrong.
+func parseArgs(fs *flag.FlagSet, args []string, want int) bool {
+	if err := fs.Parse(args); err != nil {
+		return false
+	}
+	if fs.NArg() != want {
+		fmt.Fprintf(fs.Output(), "%s: expected %d arguments, got %d\n", fs.Name(), want, fs.NArg())
+		fs.Usage()
+		return false
+	}
+	return true
+}
+
+// formatFlag defines the flag selecting the configuration format of a subcommand.
+func formatFlag(fs *flag.FlagSet, name string) *string {
+	return fs.String(name, "auto", "configuration format: text, json, yaml, or auto to use the file extension")
+}
+
+// parseFormat resolves the value of a format flag, printing the error when it is unknown.
+func parseFormat(name string) (util.Format, bool) {
+	format, err := util.ParseFormat(name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return format, false
+	}
+	return format, true
+}
+
+// configError reports a configuration loading error on stderr and returns its exit code.
+// Parse errors are rendered compiler-style, with the offending line and a caret under the
+// bad token, and exit with ExitParse.
+func configError(err error) int {
+	var parseErr *util.ParseError
+	if errors.As(err, &parseErr) {
+		fmt.Fprint(os.Stderr, parseErr.Render())
+		return ExitParse
+	}
+	fmt.Fprintln(os.Stderr, strings.TrimSpace(err.Error()))
+	if errors.Is(err, os.ErrNotExist) || errors.Is(err, os.ErrPermission) {
+		return ExitError
+	}
+	return ExitParse
+}
+
+// fail reports a runtime error on stderr and returns ExitError.
+func fail(err error) int {
+	fmt.Fprintln(os.Stderr, err)
+	return ExitError
+}
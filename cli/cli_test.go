@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	checker "github.com/jesee-kuya/stock_exchange/checker_util"
+)
+
+// writeFile writes content to a file in dir and returns its path.
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestRunExitCodes tests that each kind of failure maps to its own exit code.
+func TestRunExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	config := writeFile(t, dir, "shelf.conf", "board:2\ndo_shelf:(board:1):(shelf:1):10\noptimize:(shelf)\n")
+	loop := writeFile(t, dir, "loop.conf", "water:1\ndrink:(water:1):(water:1):1\n")
+	malformed := writeFile(t, dir, "bad.conf", "board:x\n")
+	invalid := writeFile(t, dir, "invalid.conf", "do_shelf:(board:1):(shelf:1):0\n")
+	overflow := writeFile(t, dir, "overflow.conf", "flour:10\nbread:0/2\nbake:(flour:1):(bread:2-3):1\n")
+	goodLog := writeFile(t, dir, "good.log", "0:do_shelf\n0:do_shelf\nNo more process doable at cycle 11\n")
+	badLog := writeFile(t, dir, "bad.log", "0:do_shelf\n0:do_shelf\n0:do_shelf\n")
+	garbledLog := writeFile(t, dir, "garbled.log", "zero:do_shelf\n")
+	jsonConfig := writeFile(t, dir, "shelf.json",
+		`{"stocks": {"board": 2}, "processes": [{"name": "do:shelf", "needs": {"board": 1}, "results": {"shelf": 1}, "cycles": 10}]}`)
+
+	testCases := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"no arguments", nil, ExitUsage},
+		{"unknown flag", []string{"check", "-verbose", config, goodLog}, ExitUsage},
+		{"wrong argument count", []string{"lint"}, ExitUsage},
+		{"legacy schedule", []string{config, "1"}, ExitOK},
+		{"schedule timeout", []string{"schedule", loop, "0.05"}, ExitTimeout},
+		{"schedule parse error", []string{"schedule", malformed, "1"}, ExitParse},
+		{"schedule missing file", []string{"schedule", filepath.Join(dir, "none.conf"), "1"}, ExitError},
+		{"schedule yield over capacity", []string{"schedule", "-seed", "1", overflow, "1"}, ExitInvalid},
+		{"check valid", []string{"check", config, goodLog}, ExitOK},
+		{"check invalid", []string{"check", config, badLog}, ExitInvalid},
+		{"check garbled log", []string{"check", config, garbledLog}, ExitParse},
+		{"lint errors", []string{"lint", invalid}, ExitInvalid},
+		{"analyze", []string{"analyze", config}, ExitOK},
+		{"graph", []string{"graph", config}, ExitOK},
+		{"schedule json", []string{"schedule", "-o", filepath.Join(dir, "json.log"), jsonConfig, "1"}, ExitOK},
+		{"unknown config format", []string{"lint", "-format", "toml", config}, ExitUsage},
+		{"wrong config format", []string{"lint", "-format", "json", config}, ExitParse},
+		{"convert to yaml", []string{"convert", "-o", filepath.Join(dir, "shelf.yaml"), config}, ExitOK},
+		{"convert without output format", []string{"convert", config}, ExitUsage},
+		{"convert unwritable name", []string{"convert", "-to", "text", jsonConfig}, ExitError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Run(tc.args); got != tc.want {
+				t.Errorf("Run(%q) = %d, want %d", tc.args, got, tc.want)
+			}
+		})
+	}
+}
+
+// TestImproveFooter tests that the improved log ends with a footer the checker accepts.
+func TestImproveFooter(t *testing.T) {
+	dir := t.TempDir()
+	config := writeFile(t, dir, "cabinet.conf", `board:7
+do_doorknobs:(board:1):(doorknobs:1):15
+do_background:(board:2):(background:1):20
+do_shelf:(board:1):(shelf:1):10
+do_cabinet:(doorknobs:2;background:1;shelf:3):(cabinet:1):30
+optimize:(time;cabinet)
+`)
+	log := writeFile(t, dir, "cabinet.log",
+		"0:do_shelf\n0:do_shelf\n0:do_shelf\n0:do_doorknobs\n0:do_doorknobs\n10:do_background\n30:do_cabinet\nNo more process doable at cycle 61\n")
+
+	if got := Run([]string{"improve", config, log, "0.1"}); got != ExitOK {
+		t.Fatalf("improve exited with %d", got)
+	}
+	improved := filepath.Join(dir, "cabinet.improved.log")
+	if got := Run([]string{"check", config, improved}); got != ExitOK {
+		t.Errorf("check of the improved log exited with %d", got)
+	}
+
+	chk := checker.NewChecker()
+	if err := chk.LoadLog(improved); err != nil {
+		t.Fatal(err)
+	}
+	if chk.Footer == nil || chk.Footer.TimedOut {
+		t.Errorf("improved log footer = %+v, want a no more process doable footer", chk.Footer)
+	}
+}
+
+// TestSeededScheduleFooter tests that the checker agrees with a seeded run on when no
+// process can run any more, when a varying yield may or may not fill the storage, and
+// that any seed, 0 included, draws the outcomes.
+func TestSeededScheduleFooter(t *testing.T) {
+	dir := t.TempDir()
+	config := writeFile(t, dir, "bread.conf", "flour:10\nbread:0/4\nbake:(flour:1):(bread:2-3):1\noptimize:(bread)\n")
+	log := filepath.Join(dir, "bread.log")
+
+	// Seed 0 comes first, so its outcome trace must be written for the check to pass
+	for _, seed := range []string{"0", "1", "2", "3", "4", "5"} {
+		if got := Run([]string{"schedule", "-seed", seed, "-o", log, config, "1"}); got != ExitOK {
+			t.Fatalf("schedule -seed %s exited with %d", seed, got)
+		}
+		if got := Run([]string{"check", "-outcomes", log + ".outcomes", config, log}); got != ExitOK {
+			t.Errorf("check of the log of seed %s exited with %d", seed, got)
+		}
+	}
+}
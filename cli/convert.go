@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// convert translates a configuration between the text, JSON and YAML formats.
+// It expects the configuration file path.
+// The function performs the following steps:
+//  1. Parses the configuration in the -from format, by default the one of its extension.
+//  2. Writes it in the -to format, by default the one of the -o extension; -to is required
+//     when writing to stdout.
+//  3. Writes the result to the -o path, or to stdout without -o or with "-o -".
+//
+// Returns ExitError, without writing anything, when a name cannot be written in the text format.
+func convert(args []string) int {
+	fs := newFlagSet("convert")
+	fromName := fs.String("from", "auto", "input format: text, json, yaml, or auto to use the file extension")
+	toName := fs.String("to", "auto", "output format: text, json, yaml, or auto to use the -o extension")
+	output := fs.String("o", "-", "output path, \"-\" for stdout")
+	if !parseArgs(fs, args, 1) {
+		return ExitUsage
+	}
+	from, ok := parseFormat(*fromName)
+	if !ok {
+		return ExitUsage
+	}
+	to, ok := parseFormat(*toName)
+	if !ok {
+		return ExitUsage
+	}
+	if to == util.FormatAuto {
+		if *output == "-" {
+			fmt.Fprintln(os.Stderr, "convert: -to is required when writing to stdout")
+			fs.Usage()
+			return ExitUsage
+		}
+		to = util.FormatFromPath(*output)
+	}
+
+	config, err := util.ParseConfigAs(fs.Arg(0), from)
+	if err != nil {
+		return configError(err)
+	}
+	var b bytes.Buffer
+	if err := util.WriteConfig(&b, config, to); err != nil {
+		return fail(err)
+	}
+
+	if *output == "-" {
+		_, err = os.Stdout.Write(b.Bytes())
+	} else {
+		err = os.WriteFile(*output, b.Bytes(), 0o644)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	return ExitOK
+}
@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/jesee-kuya/stock_exchange/graph"
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// drawGraph prints the process graph of a configuration in Graphviz DOT format.
+// It expects the configuration file path.
+func drawGraph(args []string) int {
+	fs := newFlagSet("graph")
+	formatName := formatFlag(fs, "format")
+	if !parseArgs(fs, args, 1) {
+		return ExitUsage
+	}
+	format, ok := parseFormat(*formatName)
+	if !ok {
+		return ExitUsage
+	}
+	config, err := util.ParseConfigAs(fs.Arg(0), format)
+	if err != nil {
+		return configError(err)
+	}
+	if err := graph.WriteDOT(os.Stdout, config); err != nil {
+		return fail(err)
+	}
+	return ExitOK
+}
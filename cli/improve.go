@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	checker "github.com/jesee-kuya/stock_exchange/checker_util"
+	e "github.com/jesee-kuya/stock_exchange/engine"
+	"github.com/jesee-kuya/stock_exchange/improve"
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// improveLog is responsible for running the stock exchange engine in improve mode.
+// It expects three positional arguments: the configuration file path, the log file
+// to improve and the waiting time.
+// The function performs the following steps:
+//  1. Loads the configuration and the log.
+//  2. Improves the log by simulated annealing for the waiting time.
+//  3. Saves the best log next to the original one, as "<log_file>.improved.log" with any ".log" suffix removed first,
+//     ending with the footer the checker expects of it.
+func improveLog(args []string) int {
+	fs := newFlagSet("improve")
+	seed := fs.Int64("seed", 1, "random seed of the annealing moves")
+	formatName := formatFlag(fs, "format")
+	if !parseArgs(fs, args, 3) {
+		return ExitUsage
+	}
+	format, ok := parseFormat(*formatName)
+	if !ok {
+		return ExitUsage
+	}
+	configFile, logFile := fs.Arg(0), fs.Arg(1)
+
+	seconds, err := util.ParseDuration(fs.Arg(2))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitUsage
+	}
+	config, err := util.ParseConfigAs(configFile, format)
+	if err != nil {
+		return configError(err)
+	}
+	chk := checker.NewChecker()
+	if err := chk.LoadLog(logFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading log: %v\n", err)
+		return ExitParse
+	}
+
+	result, err := improve.Anneal(config, chk.Log, time.Duration(seconds*float64(time.Second)), *seed)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitInvalid
+	}
+
+	improved := &e.Engine{Schedule: result.Entries, Cycle: result.Cycle, TimedOut: result.TimedOut}
+	output := strings.TrimSuffix(logFile, ".log") + ".improved.log"
+	if err := improved.SaveLog(output); err != nil {
+		return fail(err)
+	}
+
+	fmt.Printf("Target quantity %d -> %d, makespan %d -> %d (%d of %d moves accepted)\n",
+		result.Before.Quantity, result.After.Quantity, result.Before.Makespan, result.After.Makespan,
+		result.Accepted, result.Tried)
+	if len(config.Orders) > 0 {
+		fmt.Printf("Order penalty %d -> %d, unfilled orders %d -> %d\n",
+			result.Before.Penalty, result.After.Penalty, result.Before.Unfilled, result.After.Unfilled)
+	}
+	fmt.Println("Improved log saved to", output)
+	return ExitOK
+}
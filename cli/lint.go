@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// lint is responsible for the semantic validation of a configuration.
+// It expects the configuration file path, prints every issue found by util.Validate
+// with its line number and returns ExitInvalid when any of them is an error.
+func lint(args []string) int {
+	fs := newFlagSet("lint")
+	formatName := formatFlag(fs, "format")
+	if !parseArgs(fs, args, 1) {
+		return ExitUsage
+	}
+	format, ok := parseFormat(*formatName)
+	if !ok {
+		return ExitUsage
+	}
+	configFile := fs.Arg(0)
+	config, err := util.ParseConfigAs(configFile, format)
+	if err != nil {
+		return configError(err)
+	}
+
+	issues := util.Validate(config)
+	for _, issue := range issues {
+		fmt.Println(issue.Format(configFile))
+	}
+	if util.HasErrors(issues) {
+		return ExitInvalid
+	}
+	if len(issues) == 0 {
+		fmt.Println("No issues found.")
+	}
+	return ExitOK
+}
@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+)
+
+// optionFlags collects repeated key=value command-line flags into a map.
+type optionFlags map[string]string
+
+// String returns the options in key=value form.
+func (o optionFlags) String() string {
+	pairs := make([]string, 0, len(o))
+	for k, v := range o {
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set parses one key=value pair.
+func (o optionFlags) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	o[key] = val
+	return nil
+}
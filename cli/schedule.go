@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+
+	e "github.com/jesee-kuya/stock_exchange/engine"
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// schedule is responsible for running the stock exchange engine in scheduling mode.
+// It expects two positional arguments: the configuration file path and the waiting time.
+// The function performs the following steps:
+//  1. Resolves the scheduler selected with the -scheduler flag and applies any -opt options.
+//  2. Loads the engine configuration from the specified file, refusing with ExitInvalid a
+//     configuration with a process whose results can never fit in storage.
+//  3. Opens the log output: the -o path, stdout for "-o -", or by default a file with the
+//     same name as the configuration file, appended with ".log".
+//  4. Runs the engine with the provided waiting time, streaming the log as entries are scheduled.
+//  5. With -seed, whatever its value, 0 included, draws the outcome of every process that may
+//     fail or yield varying quantities, re-planning after shortfalls, and saves the outcome
+//     trace to the -outcomes path, by default the log path appended with ".outcomes" (the configuration file with
+//     ".log.outcomes" when the log goes to stdout).
+//
+// With "-o -" the human-readable progress goes to stderr, so stdout only carries the log.
+// Returns ExitTimeout, after writing the whole log, when the time limit stopped the run.
+func schedule(args []string) int {
+	fs := newFlagSet("schedule")
+	schedulerName := fs.String("scheduler", e.DefaultScheduler,
+		"scheduling strategy, one of: "+strings.Join(e.SchedulerNames(), ", "))
+	options := optionFlags{}
+	fs.Var(options, "opt", "scheduler option as key=value, may be repeated (e.g. -opt horizon=200)")
+	output := fs.String("o", "", "log output path, \"-\" for stdout (default <config_file>.log)")
+	formatName := formatFlag(fs, "format")
+	seed := fs.Int64("seed", 0, "draw process failures and yields with this seed (default: every run as planned)")
+	outcomesPath := fs.String("outcomes", "", "outcome trace output path with -seed (default <log_path>.outcomes)")
+	if !parseArgs(fs, args, 2) {
+		return ExitUsage
+	}
+	format, ok := parseFormat(*formatName)
+	if !ok {
+		return ExitUsage
+	}
+	configFile, waitTime := fs.Arg(0), fs.Arg(1)
+	drawn := isSet(fs, "seed")
+	if _, err := util.ParseDuration(waitTime); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitUsage
+	}
+
+	scheduler, err := e.LookupScheduler(*schedulerName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitUsage
+	}
+	if len(options) > 0 {
+		configurable, ok := scheduler.(e.Configurable)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "scheduler %s does not accept options\n", scheduler.Name())
+			return ExitUsage
+		}
+		if scheduler, err = configurable.WithOptions(options); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return ExitUsage
+		}
+	}
+
+	engine := e.NewEngine()
+	engine.Scheduler = scheduler
+	if drawn {
+		engine.Random = rand.New(rand.NewSource(*seed))
+	}
+	if err := engine.LoadConfigAs(configFile, format); err != nil {
+		if errors.Is(err, e.ErrInvalidConfig) {
+			fmt.Fprintln(os.Stderr, err)
+			return ExitInvalid
+		}
+		return configError(err)
+	}
+
+	logPath := *output
+	if logPath == "" {
+		logPath = configFile + ".log"
+	}
+	if logPath == "-" {
+		engine.Sink = os.Stdout
+		engine.Out = os.Stderr
+	} else {
+		file, err := os.Create(logPath)
+		if err != nil {
+			return fail(err)
+		}
+		defer file.Close()
+		engine.Sink = file
+	}
+
+	if err := engine.Run(waitTime); err != nil {
+		if logPath != "-" && len(engine.Schedule) == 0 {
+			os.Remove(logPath)
+		}
+		if errors.Is(err, e.ErrNothingRunnable) {
+			return ExitError
+		}
+		return fail(err)
+	}
+	if drawn {
+		if *outcomesPath == "" {
+			*outcomesPath = logPath + ".outcomes"
+			if logPath == "-" {
+				*outcomesPath = configFile + ".log.outcomes"
+			}
+		}
+		if err := engine.SaveOutcomes(*outcomesPath); err != nil {
+			return fail(err)
+		}
+	}
+	if engine.TimedOut {
+		return ExitTimeout
+	}
+	return ExitOK
+}
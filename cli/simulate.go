@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	checker "github.com/jesee-kuya/stock_exchange/checker_util"
+	"github.com/jesee-kuya/stock_exchange/montecarlo"
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// simulate is responsible for running the stock exchange engine in simulate mode.
+// It expects two positional arguments: the configuration file path and the log file
+// to replay.
+// The function performs the following steps:
+//  1. Loads the configuration and the log.
+//  2. Replays the log as many times as requested, drawing the durations of the processes from their distributions.
+//  3. Prints the distribution of the makespan and of the final target stock.
+func simulate(args []string) int {
+	fs := newFlagSet("simulate")
+	runs := fs.Int("runs", 1000, "number of replays")
+	seed := fs.Int64("seed", 1, "random seed of the drawn durations")
+	policyName := fs.String("policy", string(montecarlo.Planned), "when entries start: planned, not before their planned cycle, or eager, as soon as possible")
+	formatName := formatFlag(fs, "format")
+	if !parseArgs(fs, args, 2) {
+		return ExitUsage
+	}
+	format, ok := parseFormat(*formatName)
+	if !ok {
+		return ExitUsage
+	}
+	policy, err := montecarlo.ParsePolicy(*policyName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitUsage
+	}
+	if *runs <= 0 {
+		fmt.Fprintf(os.Stderr, "number of runs must be positive, got %d\n", *runs)
+		return ExitUsage
+	}
+	configFile, logFile := fs.Arg(0), fs.Arg(1)
+
+	config, err := util.ParseConfigAs(configFile, format)
+	if err != nil {
+		return configError(err)
+	}
+	chk := checker.NewChecker()
+	if err := chk.LoadLog(logFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading log: %v\n", err)
+		if os.IsNotExist(err) {
+			return ExitError
+		}
+		return ExitParse
+	}
+
+	result, err := montecarlo.Simulate(config, chk.Log, policy, *runs, *seed)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitInvalid
+	}
+	if err := result.WriteText(os.Stdout); err != nil {
+		return fail(err)
+	}
+	return ExitOK
+}
@@ -0,0 +1,278 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jesee-kuya/stock_exchange/process"
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// BeamSearch is a lookahead scheduler. At every completion event it expands each
+// partial schedule of the beam into several candidate start sets, derived from the
+// greedy priority order, scores them and keeps the Width best ones.
+//
+// Fields:
+//   - Width: the number of partial schedules kept after each event.
+//   - Depth: the number of further events a candidate is rolled out greedily
+//     before being scored; 0 scores it as soon as its starts are made.
+type BeamSearch struct {
+	Width int
+	Depth int
+}
+
+func init() {
+	RegisterScheduler(BeamSearch{Width: 8, Depth: 2})
+}
+
+// Name returns the name the beam search scheduler is registered under.
+func (BeamSearch) Name() string {
+	return "beam"
+}
+
+// WithOptions applies the "width" and "depth" options.
+func (b BeamSearch) WithOptions(opts map[string]string) (Scheduler, error) {
+	for key, value := range opts {
+		n, err := parseIntOption(key, value)
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case "width":
+			if n == 0 {
+				return nil, fmt.Errorf("option width must be at least 1")
+			}
+			b.Width = n
+		case "depth":
+			b.Depth = n
+		default:
+			return nil, fmt.Errorf("unknown option %q for scheduler %s", key, b.Name())
+		}
+	}
+	return b, nil
+}
+
+// beamRank orders partial schedules the way Objective.Better orders scores: fewer
+// unfilled orders first, then the lower order penalty, then more target units (in
+// stock or in flight), then the earlier makespan; ties go to the larger sum of
+// priorities of the processes started, then to the earlier cycle.
+type beamRank struct {
+	Score
+	Priority int
+	Cycle    int
+}
+
+// beamCandidate is a partial schedule together with its rank.
+type beamCandidate struct {
+	State *simState
+	Rank  beamRank
+}
+
+// beamSearch holds the data shared by the steps of one beam search run.
+type beamSearch struct {
+	BeamSearch
+	obj        Objective
+	all        []*process.Process
+	reachable  []*process.Process
+	priorities map[string]int
+	quotas     map[string]int
+	deadline   time.Time
+}
+
+// Schedule runs the beam search within the budget, which is a hard limit: it is
+// checked before every state is expanded, every candidate start set is tried and
+// every rollout step is made, and when it expires the best partial schedule is
+// completed without starting anything new. The greedy plan seeds the result, so the
+// beam never does worse; it runs on a tenth of the budget and the search on what it left.
+func (b BeamSearch) Schedule(config *util.ConfigData, budget time.Duration) (*Plan, error) {
+	deadline := time.Now().Add(budget)
+	if b.Width <= 0 {
+		b.Width = 1
+	}
+
+	// The seed's time counts against the budget
+	best, err := greedyScheduler{}.Schedule(config, budget/10)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := NewObjective(config.OptimizeTargets, config.Processes, config.Stocks)
+	depths := computeDepths(config.Processes, obj.Items)
+	search := &beamSearch{
+		BeamSearch: b,
+		obj:        obj,
+		priorities: computePriorities(config.Processes, obj),
+		quotas:     computeQuotas(config.Processes, depths),
+		deadline:   deadline,
+	}
+	search.all = sortByPriority(config.Processes, search.priorities)
+	search.reachable = search.all
+	if obj.Time {
+		search.reachable = reachableProcesses(search.all, depths)
+	}
+
+	bestScore := obj.EvaluatePlan(best)
+	beam := []*simState{newSimState(config)}
+
+	for len(beam) > 0 {
+		if search.expired() {
+			// Complete the most promising partial schedule without new starts
+			last := beam[0].clone()
+			last.finish(obj)
+			if sc := last.score(obj); obj.Better(sc, bestScore) {
+				best = last.plan()
+				best.Cycle = last.Cycle
+				best.TimedOut = true
+			}
+			break
+		}
+
+		seen := map[string]bool{}
+		children := []beamCandidate{}
+		for _, state := range beam {
+			if search.expired() {
+				break
+			}
+			for _, child := range search.expand(state) {
+				key := child.key()
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				if search.finished(child) {
+					if sc := child.score(obj); obj.Better(sc, bestScore) {
+						bestScore = sc
+						best = child.plan()
+					}
+					continue
+				}
+				children = append(children, beamCandidate{State: child, Rank: search.rank(child)})
+			}
+		}
+
+		if len(children) == 0 && search.expired() {
+			// Complete the current beam rather than what little was expanded of it
+			continue
+		}
+		sort.SliceStable(children, func(i, j int) bool {
+			return search.outranks(children[i].Rank, children[j].Rank)
+		})
+		if len(children) > b.Width {
+			children = children[:b.Width]
+		}
+		beam = beam[:0]
+		for _, c := range children {
+			beam = append(beam, c.State)
+		}
+	}
+
+	return best, nil
+}
+
+// expired reports whether the budget is spent.
+func (s *beamSearch) expired() bool {
+	return time.Now().After(s.deadline)
+}
+
+// finished reports whether a state is a complete schedule: nothing is running,
+// no delivery is expected and no process can start anymore.
+func (s *beamSearch) finished(state *simState) bool {
+	return !state.waiting() && len(state.runnable(state.candidates(s.reachable, s.all))) == 0
+}
+
+// expand returns the successors of a state, each advanced to its next completion event.
+// The candidate start sets are the greedy one, the greedy one with each runnable
+// process moved to the front, the greedy one without each runnable process, and
+// starting nothing when something is already running or a delivery is expected.
+// Once the budget is spent, the start sets not tried yet are left out.
+func (s *beamSearch) expand(state *simState) []*simState {
+	runnable := state.runnable(state.candidates(s.reachable, s.all))
+
+	orders := [][]*process.Process{runnable}
+	skips := []*process.Process{nil}
+	for i, p := range runnable {
+		if i > 0 {
+			order := append([]*process.Process{p}, runnable[:i]...)
+			order = append(order, runnable[i+1:]...)
+			orders = append(orders, order)
+			skips = append(skips, nil)
+		}
+		orders = append(orders, runnable)
+		skips = append(skips, p)
+	}
+
+	children := []*simState{}
+	for i, order := range orders {
+		if s.expired() {
+			return children
+		}
+		child := state.clone()
+		if child.startGreedy(order, s.quotas, skips[i]) == 0 {
+			continue
+		}
+		child.advance(s.obj)
+		children = append(children, child)
+	}
+	if state.waiting() {
+		wait := state.clone()
+		wait.advance(s.obj)
+		children = append(children, wait)
+	}
+	return children
+}
+
+// rank rolls a copy of the state out greedily for Depth events, fewer once the budget is
+// spent, and scores the result.
+func (s *beamSearch) rank(state *simState) beamRank {
+	probe := state.clone()
+	finished := false
+	for d := 0; d < s.Depth && !s.expired(); d++ {
+		probe.startGreedy(probe.runnable(probe.candidates(s.reachable, s.all)), s.quotas, nil)
+		if !probe.advance(s.obj) {
+			finished = true
+			break
+		}
+	}
+
+	r := beamRank{Cycle: probe.Cycle}
+	r.Quantity = s.obj.Evaluate(probe.Stock, 0).Quantity
+	r.Makespan = probe.Makespan
+	// The orders still open are only known to be unfilled once the rollout can go no
+	// further; until then the overdue ones count as shipping right away
+	if finished {
+		sc := probe.score(s.obj)
+		r.Unfilled, r.Penalty = sc.Unfilled, sc.Penalty
+	} else {
+		r.Penalty = probe.Timeline.Penalty(probe.Cycle)
+	}
+	for _, j := range probe.Running {
+		for item, qty := range j.Process.Result {
+			if s.obj.Items[item] {
+				r.Quantity += qty
+				if j.End > r.Makespan {
+					r.Makespan = j.End
+				}
+			}
+		}
+	}
+	for _, entry := range probe.Entries {
+		r.Priority += s.priorities[entry.ProcessName]
+	}
+	return r
+}
+
+// outranks reports whether rank a should be kept before rank b.
+func (s *beamSearch) outranks(a, b beamRank) bool {
+	if s.obj.Better(a.Score, b.Score) {
+		return true
+	}
+	if s.obj.Better(b.Score, a.Score) {
+		return false
+	}
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	return a.Cycle < b.Cycle
+}
@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jesee-kuya/stock_exchange/process"
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// TestBeamSearch tests that the beam search improves on the greedy plan when a
+// different start order pays off, and that it never does worse than greedy.
+func TestBeamSearch(t *testing.T) {
+	config := detourConfig()
+
+	greedy, err := greedyScheduler{}.Schedule(config, time.Second)
+	if err != nil {
+		t.Fatalf("greedy Schedule returned an error: %v", err)
+	}
+	beam, err := BeamSearch{Width: 4, Depth: 1}.Schedule(config, time.Second)
+	if err != nil {
+		t.Fatalf("beam Schedule returned an error: %v", err)
+	}
+
+	if greedy.Stock["t"] != 4 {
+		t.Fatalf("expected greedy to produce 4 t, got %d", greedy.Stock["t"])
+	}
+	if beam.Stock["t"] != 6 {
+		t.Errorf("expected beam search to produce 6 t, got %d", beam.Stock["t"])
+	}
+}
+
+// TestBeamSearchOptions tests option parsing of the beam search scheduler.
+func TestBeamSearchOptions(t *testing.T) {
+	s, err := BeamSearch{}.WithOptions(map[string]string{"width": "3", "depth": "5"})
+	if err != nil {
+		t.Fatalf("WithOptions returned an error: %v", err)
+	}
+	if b := s.(BeamSearch); b.Width != 3 || b.Depth != 5 {
+		t.Errorf("got width %d depth %d, want 3 and 5", b.Width, b.Depth)
+	}
+
+	for _, opts := range []map[string]string{{"width": "0"}, {"depth": "-1"}, {"color": "red"}} {
+		if _, err := (BeamSearch{}).WithOptions(opts); err == nil {
+			t.Errorf("WithOptions(%v) should fail", opts)
+		}
+	}
+}
+
+// TestBeamSearchBudget tests that the beam search, greedy seed included, returns close
+// to its budget on a search it cannot finish.
+func TestBeamSearchBudget(t *testing.T) {
+	for _, budget := range []time.Duration{100 * time.Millisecond, 400 * time.Millisecond} {
+		start := time.Now()
+		plan, err := BeamSearch{Width: 8, Depth: 2}.Schedule(cyclicConfig(), budget)
+		elapsed := time.Since(start)
+		if err != nil {
+			t.Fatalf("Schedule returned an error: %v", err)
+		}
+		if limit := budget + budget/5; elapsed > limit {
+			t.Errorf("budget %v: Schedule took %v, want at most %v", budget, elapsed, limit)
+		}
+		if !plan.TimedOut {
+			t.Errorf("budget %v: expected a timed-out plan", budget)
+		}
+	}
+}
+
+// TestBeamSearchRanksOrders tests that the beam ranks its states as the objective scores
+// them, an order shipped on time before more target units.
+func TestBeamSearchRanksOrders(t *testing.T) {
+	config := &util.ConfigData{
+		Stocks: map[string]int{"a": 1},
+		Processes: []*process.Process{
+			{Name: "make_x", Needs: map[string]int{"a": 1}, Result: map[string]int{"x": 1}, Cycle: 1},
+			{Name: "make_y", Needs: map[string]int{"a": 1}, Result: map[string]int{"y": 5}, Cycle: 1},
+		},
+		OptimizeTargets: []string{"y"},
+		Orders:          []util.Order{{Due: 5, Items: map[string]int{"x": 1}, Penalty: 10}},
+	}
+	obj := NewObjective(config.OptimizeTargets, config.Processes, config.Stocks)
+	search := &beamSearch{
+		BeamSearch: BeamSearch{Width: 1, Depth: 3},
+		obj:        obj,
+		all:        config.Processes,
+		reachable:  config.Processes,
+		priorities: computePriorities(config.Processes, obj),
+		quotas:     map[string]int{"make_x": 1, "make_y": 1},
+		deadline:   time.Now().Add(time.Minute),
+	}
+
+	ranks := map[string]beamRank{}
+	for _, p := range config.Processes {
+		state := newSimState(config)
+		state.start(p)
+		ranks[p.Name] = search.rank(state)
+	}
+	if !search.outranks(ranks["make_x"], ranks["make_y"]) || search.outranks(ranks["make_y"], ranks["make_x"]) {
+		t.Errorf("make_x ranks %+v and make_y %+v, want the order shipped to come first", ranks["make_x"], ranks["make_y"])
+	}
+}
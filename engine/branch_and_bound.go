@@ -0,0 +1,374 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jesee-kuya/stock_exchange/process"
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// unbounded stands for a quantity with no finite upper bound, e.g. an item a
+// self-sustaining recipe can produce forever.
+const unbounded = math.MaxInt32
+
+// defaultMaxStarts is the MaxStarts used when none is configured.
+const defaultMaxStarts = 16
+
+// maxSeenStates caps the number of visited states remembered for duplicate detection.
+const maxSeenStates = 1 << 20
+
+// BranchAndBound is an exact scheduler for small configurations.
+// At every completion event it branches over every feasible combination of
+// process starts (including waiting for the next event) and prunes nodes whose
+// optimistic score (see Objective.Better), made of the least penalty their
+// orders can still cost, their target quantity and makespan, cannot beat
+// the best schedule found so far. The greedy plan seeds the search.
+//
+// Fields:
+//   - Horizon: nodes past this cycle are cut off; 0 means no horizon.
+//   - MaxStarts: cap on the instances of one process started at a single cycle,
+//     which keeps processes without needs from branching forever; 0 selects the default.
+type BranchAndBound struct {
+	Horizon   int
+	MaxStarts int
+}
+
+// Certificate documents how a branch-and-bound plan was obtained.
+//
+// Fields:
+//   - Optimal: true when the whole search space was explored or pruned, neither the
+//     budget, the horizon nor MaxStarts having cut any branch.
+//   - Quantity, Makespan: the score of the returned plan.
+//   - Bound: the optimistic target quantity of the initial state; unbounded for cyclic recipes.
+//   - Nodes, Pruned: how many search nodes were expanded and cut by the bounds.
+//   - Horizon: the horizon the proof is limited to, 0 when there is none.
+//   - MaxStarts: the cap on the instances of a process started in one cycle when it
+//     kept more from starting, 0 when it cut nothing.
+type Certificate struct {
+	Optimal  bool
+	Quantity int
+	Makespan int
+	Bound    int
+	Nodes    int
+	Pruned   int
+	Horizon  int
+
+	MaxStarts int
+}
+
+func init() {
+	RegisterScheduler(BranchAndBound{MaxStarts: defaultMaxStarts})
+}
+
+// Name returns the name the branch-and-bound scheduler is registered under.
+func (BranchAndBound) Name() string {
+	return "exact"
+}
+
+// WithOptions applies the "horizon" and "max_starts" options.
+func (b BranchAndBound) WithOptions(opts map[string]string) (Scheduler, error) {
+	for key, value := range opts {
+		n, err := parseIntOption(key, value)
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case "horizon":
+			b.Horizon = n
+		case "max_starts":
+			b.MaxStarts = n
+		default:
+			return nil, fmt.Errorf("unknown option %q for scheduler %s", key, b.Name())
+		}
+	}
+	return b, nil
+}
+
+// bnbSearch holds the state of one branch-and-bound run.
+type bnbSearch struct {
+	BranchAndBound
+	obj        Objective
+	all        []*process.Process
+	reachable  []*process.Process
+	producers  map[string][]*process.Process
+	deadline   time.Time
+	seen       map[string]bool
+	best       Score
+	bestPlan   *Plan
+	nodes      int
+	pruned     int
+	horizonCut bool
+	startsCut  bool
+	stopped    bool
+}
+
+// Schedule searches for a schedule filling the most orders with the least penalty,
+// then maximizing the target quantity, then minimizing the makespan. The returned plan carries a Certificate stating whether it is
+// proven optimal; when the budget expires the best plan found is returned.
+// The greedy seed is run on a tenth of the budget and the search on what it left.
+func (b BranchAndBound) Schedule(config *util.ConfigData, budget time.Duration) (*Plan, error) {
+	deadline := time.Now().Add(budget)
+	if b.MaxStarts <= 0 {
+		b.MaxStarts = defaultMaxStarts
+	}
+
+	// Seed the incumbent with the greedy plan, its time counting against the budget
+	incumbent, err := greedyScheduler{}.Schedule(config, budget/10)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := NewObjective(config.OptimizeTargets, config.Processes, config.Stocks)
+	depths := computeDepths(config.Processes, obj.Items)
+	priorities := computePriorities(config.Processes, obj)
+
+	ordered := sortByPriority(config.Processes, priorities)
+
+	search := &bnbSearch{
+		BranchAndBound: b,
+		obj:            obj,
+		all:            ordered,
+		reachable:      ordered,
+		producers:      map[string][]*process.Process{},
+		deadline:       deadline,
+		seen:           map[string]bool{},
+		best:           obj.EvaluatePlan(incumbent),
+		bestPlan:       incumbent,
+	}
+	if obj.Time {
+		search.reachable = reachableProcesses(ordered, depths)
+	}
+	for _, p := range ordered {
+		for item := range p.Result {
+			search.producers[item] = append(search.producers[item], p)
+		}
+	}
+
+	root := newSimState(config)
+	bound := search.upperBound(root)
+	search.expand(root)
+
+	plan := search.bestPlan
+	plan.TimedOut = search.stopped
+	plan.Certificate = &Certificate{
+		Optimal:  !search.stopped && !search.horizonCut && !search.startsCut,
+		Quantity: search.best.Quantity,
+		Makespan: search.best.Makespan,
+		Bound:    bound,
+		Nodes:    search.nodes,
+		Pruned:   search.pruned,
+		Horizon:  b.Horizon,
+	}
+	if search.startsCut {
+		plan.Certificate.MaxStarts = b.MaxStarts
+	}
+	return plan, nil
+}
+
+// expand visits a search node: it checks the budget and the bounds, records
+// complete schedules and branches over the start combinations otherwise.
+// The budget is checked at every node, since a node clones states whose cost grows
+// with the length of their schedule.
+func (s *bnbSearch) expand(state *simState) {
+	if s.stopped {
+		return
+	}
+	s.nodes++
+	if time.Now().After(s.deadline) {
+		s.stopped = true
+		return
+	}
+	if s.Horizon > 0 && state.Cycle > s.Horizon {
+		s.horizonCut = true
+		return
+	}
+
+	key := state.key()
+	if s.seen[key] {
+		return
+	}
+	if len(s.seen) < maxSeenStates {
+		s.seen[key] = true
+	}
+
+	// No order left open ends up unfilled and the overdue ones ship right away
+	optimistic := Score{
+		Quantity: s.upperBound(state),
+		Makespan: s.makespanLowerBound(state),
+		Penalty:  state.Timeline.Penalty(state.Cycle),
+	}
+	if !s.obj.Better(optimistic, s.best) {
+		s.pruned++
+		return
+	}
+
+	runnable := state.runnable(state.candidates(s.reachable, s.all))
+
+	if len(runnable) == 0 && !state.waiting() {
+		if sc := state.score(s.obj); s.obj.Better(sc, s.best) {
+			s.best = sc
+			s.bestPlan = state.plan()
+		}
+		return
+	}
+
+	s.branch(state, runnable, 0, false)
+}
+
+// branch enumerates how many instances of runnable[i], runnable[i+1], ... to
+// start at the current cycle, most instances first, then advances each
+// combination to the next completion or delivery event. Starting nothing while
+// nothing is running and no delivery is expected is skipped since the schedule
+// would never progress. When MaxStarts keeps a process from starting again, the
+// search is no longer exhaustive and s.startsCut is set.
+func (s *bnbSearch) branch(state *simState, runnable []*process.Process, i int, started bool) {
+	if s.stopped {
+		return
+	}
+	if i == len(runnable) {
+		if !started && !state.waiting() {
+			return
+		}
+		next := state.clone()
+		next.advance(s.obj)
+		s.expand(next)
+		return
+	}
+
+	p := runnable[i]
+	most := p.MostResult()
+	count := 0
+	for count < s.MaxStarts && state.canStart(p) {
+		count++
+		for item, qty := range p.Needs {
+			state.Stock[item] -= qty
+		}
+		for item, qty := range most {
+			state.Incoming[item] += qty
+		}
+	}
+	if count == s.MaxStarts && state.canStart(p) {
+		s.startsCut = true
+	}
+	// Give the stock back before branching on private copies
+	for item, qty := range p.Needs {
+		state.Stock[item] += qty * count
+	}
+	for item, qty := range most {
+		state.Incoming[item] -= qty * count
+	}
+
+	for c := count; c >= 0 && !s.stopped; c-- {
+		next := state.clone()
+		for n := 0; n < c; n++ {
+			next.start(p)
+		}
+		s.branch(next, runnable, i+1, started || c > 0)
+	}
+}
+
+// upperBound returns an optimistic bound on the final target quantity reachable
+// from the state. Each item is assumed to be available in full to every process
+// needing it, on top of the current stock, the output of running processes and the
+// deliveries to come. Orders are assumed not to take anything.
+// Items that a recipe can regenerate from themselves are unbounded.
+func (s *bnbSearch) upperBound(state *simState) int {
+	avail := cloneStock(state.Stock)
+	for _, j := range state.Running {
+		for item, qty := range j.Process.Output() {
+			avail[item] += qty
+		}
+	}
+	for _, d := range state.Timeline.Upcoming() {
+		for item, qty := range d.Items {
+			avail[item] += qty
+		}
+	}
+
+	memo := map[string]int{}
+	visiting := map[string]bool{}
+	var reach func(item string) int
+	reach = func(item string) int {
+		if v, ok := memo[item]; ok {
+			return v
+		}
+		if visiting[item] {
+			return unbounded
+		}
+		visiting[item] = true
+		total := avail[item]
+		for _, p := range s.producers[item] {
+			runs := unbounded
+			for need, qty := range p.Needs {
+				// Held resources come back, so they never limit the number of runs
+				if qty <= 0 || p.Holds[need] >= qty {
+					continue
+				}
+				if r := reach(need) / qty; r < runs {
+					runs = r
+				}
+			}
+			total = addBounded(total, mulBounded(runs, p.Result[item]))
+		}
+		visiting[item] = false
+		memo[item] = total
+		return total
+	}
+
+	bound := 0
+	for item := range s.obj.Items {
+		bound = addBounded(bound, reach(item))
+	}
+	return bound
+}
+
+// makespanLowerBound returns the earliest makespan any completion of the state
+// can have: running processes that produce a target will complete no earlier
+// than their end cycle.
+func (s *bnbSearch) makespanLowerBound(state *simState) int {
+	lb := state.Makespan
+	for _, j := range state.Running {
+		if j.End > lb && s.obj.ProducesTarget(j.Process) {
+			lb = j.End
+		}
+	}
+	return lb
+}
+
+// addBounded adds two quantities, saturating at unbounded.
+func addBounded(a, b int) int {
+	if a >= unbounded-b {
+		return unbounded
+	}
+	return a + b
+}
+
+// mulBounded multiplies two non-negative quantities, saturating at unbounded.
+func mulBounded(a, b int) int {
+	if a != 0 && b >= unbounded/a {
+		return unbounded
+	}
+	return a * b
+}
+
+// String describes the certificate in one line.
+func (c *Certificate) String() string {
+	bound := "unbounded"
+	if c.Bound < unbounded {
+		bound = fmt.Sprint(c.Bound)
+	}
+	status := "optimal"
+	if !c.Optimal {
+		status = "best found, not proven optimal"
+		if c.Horizon > 0 {
+			status += fmt.Sprintf(" (horizon %d)", c.Horizon)
+		}
+		if c.MaxStarts > 0 {
+			status += fmt.Sprintf(" (at most %d starts of a process per cycle)", c.MaxStarts)
+		}
+	}
+	return fmt.Sprintf("%s: target quantity %d, makespan %d, upper bound %s (%d nodes, %d pruned)",
+		status, c.Quantity, c.Makespan, bound, c.Nodes, c.Pruned)
+}
@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jesee-kuya/stock_exchange/process"
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// detourConfig returns a configuration on which the greedy scheme spends stock on a
+// two-step detour and ends with 4 units of t, while starting p_direct twice yields 6.
+func detourConfig() *util.ConfigData {
+	return &util.ConfigData{
+		Stocks: map[string]int{"a": 2},
+		Processes: []*process.Process{
+			{Name: "p_via", Needs: map[string]int{"a": 1}, Result: map[string]int{"x": 1}, Cycle: 1},
+			{Name: "p_t2", Needs: map[string]int{"x": 1}, Result: map[string]int{"t": 1}, Cycle: 1},
+			{Name: "p_direct", Needs: map[string]int{"a": 1}, Result: map[string]int{"t": 3}, Cycle: 1},
+		},
+		OptimizeTargets: []string{"t"},
+	}
+}
+
+// TestBranchAndBound tests that the exact scheduler proves the optimum on small
+// configurations, including one where the greedy scheme falls short.
+func TestBranchAndBound(t *testing.T) {
+	testCases := []struct {
+		name         string
+		config       *util.ConfigData
+		wantQuantity int
+		wantMakespan int
+	}{
+		{
+			name: "cabinet",
+			config: &util.ConfigData{
+				Stocks:          map[string]int{"board": 7},
+				Processes:       cabinetProcesses(),
+				OptimizeTargets: []string{"time", "cabinet"},
+			},
+			wantQuantity: 1,
+			wantMakespan: 50,
+		},
+		{
+			name:         "greedy detour",
+			config:       detourConfig(),
+			wantQuantity: 6,
+			wantMakespan: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			plan, err := BranchAndBound{}.Schedule(tc.config, 5*time.Second)
+			if err != nil {
+				t.Fatalf("Schedule returned an error: %v", err)
+			}
+			cert := plan.Certificate
+			if cert == nil || !cert.Optimal {
+				t.Fatalf("expected an optimality certificate, got %v", cert)
+			}
+			if cert.Quantity != tc.wantQuantity || cert.Makespan != tc.wantMakespan {
+				t.Errorf("got quantity %d makespan %d, want %d and %d",
+					cert.Quantity, cert.Makespan, tc.wantQuantity, tc.wantMakespan)
+			}
+			if tc.config.Stocks["a"] == 0 && tc.config.Stocks["board"] == 0 {
+				t.Error("Schedule must not modify the configuration stock")
+			}
+		})
+	}
+}
+
+// cyclicConfig returns the self-sustaining configuration of examples/run, on which no
+// search ever finishes, so that the schedulers run until their budget is spent.
+func cyclicConfig() *util.ConfigData {
+	return &util.ConfigData{
+		Stocks: map[string]int{"water": 10, "you": 1},
+		Processes: []*process.Process{
+			{Name: "run", Needs: map[string]int{"water": 5}, Result: map[string]int{"joy": 5}, Cycle: 1},
+			{Name: "rest", Needs: map[string]int{"you": 1}, Result: map[string]int{"water": 2, "you": 1}, Cycle: 3},
+		},
+		OptimizeTargets: []string{"time", "joy"},
+	}
+}
+
+// TestBranchAndBoundBudget tests that the exact scheduler, greedy seed included, returns
+// close to its budget on a search it cannot finish.
+func TestBranchAndBoundBudget(t *testing.T) {
+	for _, budget := range []time.Duration{100 * time.Millisecond, 400 * time.Millisecond} {
+		start := time.Now()
+		plan, err := BranchAndBound{}.Schedule(cyclicConfig(), budget)
+		elapsed := time.Since(start)
+		if err != nil {
+			t.Fatalf("Schedule returned an error: %v", err)
+		}
+		if limit := budget + budget/5; elapsed > limit {
+			t.Errorf("budget %v: Schedule took %v, want at most %v", budget, elapsed, limit)
+		}
+		if !plan.TimedOut || plan.Certificate.Optimal {
+			t.Errorf("budget %v: expected a timed-out plan without a proof, got %v", budget, plan.Certificate)
+		}
+	}
+}
+
+// TestBranchAndBoundMaxStarts tests that a search limited by MaxStarts does not claim
+// to be optimal.
+func TestBranchAndBoundMaxStarts(t *testing.T) {
+	config := &util.ConfigData{
+		Stocks: map[string]int{"a": 6},
+		Processes: []*process.Process{
+			{Name: "p", Needs: map[string]int{"a": 1}, Result: map[string]int{"t": 1}, Cycle: 1},
+		},
+		OptimizeTargets: []string{"t"},
+	}
+
+	plan, err := BranchAndBound{MaxStarts: 4}.Schedule(config, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Schedule returned an error: %v", err)
+	}
+	if cert := plan.Certificate; cert.Optimal || cert.MaxStarts != 4 || !strings.Contains(cert.String(), "at most 4 starts") {
+		t.Errorf("expected the certificate to mention the cap on starts, got %v", cert)
+	}
+
+	plan, err = BranchAndBound{MaxStarts: 6}.Schedule(config, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Schedule returned an error: %v", err)
+	}
+	if cert := plan.Certificate; !cert.Optimal || cert.Quantity != 6 {
+		t.Errorf("expected a proof with every start allowed, got %v", cert)
+	}
+}
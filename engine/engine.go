@@ -1,7 +1,12 @@
 package engine
 
 import (
+	"io"
+	"math/rand"
+	"strconv"
+
 	"github.com/jesee-kuya/stock_exchange/process"
+	"github.com/jesee-kuya/stock_exchange/util"
 )
 
 // Engine is the main structure for executing and optimizing
@@ -9,20 +14,49 @@ import (
 type Engine struct {
 	Stock           *Stock
 	Processes       []*process.Process
-	Schedule        []string
+	Schedule        []ScheduleEntry
 	Cycle           int
+	Makespan        int  // Cycle at which the last target unit was produced
+	TimedOut        bool // Whether Run stopped on the time limit rather than running out of work
 	OptimizeTargets []string
+	Scheduler       Scheduler      // Strategy used by Run; nil selects the greedy scheduler
+	Sink            io.Writer      // Log written by Run, one "cycle:process_name" line per entry then the footer; nil for none
+	Out             io.Writer      // Human-readable progress printed by Run; nil selects os.Stdout
+	Capacities      map[string]int // Storage capacity of the items that have one; nil for none
+	ShelfLife       map[string]int // Shelf life in cycles of the perishable items; nil for none
+	Expired         map[string]int // Quantity of each perishable item lost to expiry during Run
+
+	Random   *rand.Rand        // Draws the outcomes of the processes that may fail or vary; nil runs them as planned
+	Outcomes []process.Outcome // Outcome of each entry of Schedule, recorded by Run when Random is set
+	Replans  int               // Number of new plans Run made after an outcome fell short, when Random is set
+
+	Deliveries   []util.Delivery // Inbound deliveries added to the stock at their cycle; nil for none
+	Orders       []util.Order    // Customer orders shipped from the stock; nil for none
+	OrderResults []OrderResult   // What became of each of Orders, recorded by Run
 }
 
 // Stock represents the available items in the system.
 // The Items map stores item names as keys and their corresponding quantities as values.
+// After Run, Lots holds the units of the perishable items left by production cycle.
 type Stock struct {
 	Items map[string]int
+	Lots  map[string][]Lot
 }
 
 // ScheduleEntry represents a scheduled execution of a process.
 // It records the cycle at which the process is run and the name of the process.
+// Entries recorded by Engine.Run also describe the run itself; entries read from a
+// log or built by a scheduler only carry the cycle and the process name.
 type ScheduleEntry struct {
-	Cycle       int    // The cycle number when the process is executed
-	ProcessName string // The name of the process being executed
+	Cycle       int            // The cycle number when the process is executed, i.e. its start cycle
+	ProcessName string         // The name of the process being executed
+	Instance    int            // The 1-based number of this run among the runs of the same process
+	End         int            // The cycle at which the results are added to the stock
+	Consumed    map[string]int // The quantities taken from the stock at the start
+	Produced    map[string]int // The quantities added to the stock at the end, including released resources
+}
+
+// String returns the entry in the log format "<cycle>:<process_name>".
+func (s ScheduleEntry) String() string {
+	return strconv.Itoa(s.Cycle) + ":" + s.ProcessName
 }
@@ -19,11 +19,11 @@ func TestSaveLog(t *testing.T) {
 
 		// mock schedule data
 		engine := &Engine{
-			Schedule: []string{
-				"1:process_a",
-				"1:process_b",
-				"2:process_c",
-				"3:process_a",
+			Schedule: []ScheduleEntry{
+				{Cycle: 1, ProcessName: "process_a"},
+				{Cycle: 1, ProcessName: "process_b"},
+				{Cycle: 2, ProcessName: "process_c"},
+				{Cycle: 3, ProcessName: "process_a"},
 			},
 		}
 		err := engine.SaveLog(testPath)
@@ -42,9 +42,27 @@ func TestSaveLog(t *testing.T) {
 		}
 	})
 
+	t.Run("footer after run", func(t *testing.T) {
+		testPath := filepath.Join(t.TempDir(), "footer.log")
+		engine := &Engine{Schedule: []ScheduleEntry{{Cycle: 0, ProcessName: "process_a"}}, Cycle: 11}
+
+		if err := engine.SaveLog(testPath); err != nil {
+			t.Fatalf("SaveLog returned an error: %v", err)
+		}
+		content, err := os.ReadFile(testPath)
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+
+		want := "0:process_a\nNo more process doable at cycle 11"
+		if string(content) != want {
+			t.Errorf("File content mismatch. got %q but expected %q", content, want)
+		}
+	})
+
 	t.Run("empty path string", func(t *testing.T) {
 		engine := &Engine{
-			Schedule: []string{"1:test_process"},
+			Schedule: []ScheduleEntry{{Cycle: 1, ProcessName: "test_process"}},
 		}
 
 		err := engine.SaveLog("")
@@ -0,0 +1,35 @@
+package engine
+
+import "github.com/jesee-kuya/stock_exchange/process"
+
+// runningProcess represents a process that is currently executing.
+// It tracks the process instance and the cycle at which it completes.
+type runningProcess struct {
+	Process *process.Process // The process being executed
+	End     int              // Cycle at which the process completes
+}
+
+// eventQueue is a min-heap of running processes ordered by completion cycle.
+// It implements heap.Interface so the engine can jump from one completion
+// event to the next instead of visiting every cycle.
+type eventQueue []runningProcess
+
+// Len returns the number of running processes.
+func (q eventQueue) Len() int { return len(q) }
+
+// Less orders running processes by completion cycle.
+func (q eventQueue) Less(i, j int) bool { return q[i].End < q[j].End }
+
+// Swap exchanges two running processes.
+func (q eventQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+// Push adds a running process; use heap.Push rather than calling it directly.
+func (q *eventQueue) Push(x any) { *q = append(*q, x.(runningProcess)) }
+
+// Pop removes the last running process; use heap.Pop rather than calling it directly.
+func (q *eventQueue) Pop() any {
+	old := *q
+	rp := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return rp
+}
@@ -0,0 +1,312 @@
+package engine
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/jesee-kuya/stock_exchange/process"
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// execution holds the state of a run whose process outcomes are drawn, see execute.
+type execution struct {
+	config    *util.ConfigData
+	scheduler Scheduler
+	rng       *rand.Rand
+	obj       Objective
+	byName    map[string]*process.Process
+	deadline  time.Time
+	emit      func(ScheduleEntry)
+
+	stock    map[string]int
+	incoming map[string]int
+	lots     *Lots
+	expired  map[string]int
+	timeline *Timeline
+	running  []executedRun
+	queue    []ScheduleEntry
+	cycle    int
+	lastEnd  int
+	makespan int
+	timedOut bool
+
+	entries  []ScheduleEntry
+	outcomes []process.Outcome
+	replans  int
+}
+
+// executedRun is a process started by an execution, with the index of its entry.
+type executedRun struct {
+	p     *process.Process
+	entry int
+	end   int
+}
+
+// execute runs a configuration with drawn outcomes: it plans with the scheduler, which
+// counts on the expected results of every process, failures included (see
+// process.Process.PlanExpected), then carries the plan out, drawing the outcome of each run
+// from rng when it completes, and plans again from the actual stock whenever a run fails
+// or falls short of its expected results.
+//
+// Behavior:
+//   - Entries start in the order of the current plan, at their planned cycle, or later
+//     when their needs are not in stock yet or there might not be room for their largest
+//     possible results. An entry that cannot start yet is tried again after the others of
+//     its planned cycle, which may make room for it. An entry that cannot start while
+//     nothing is running is dropped.
+//   - A failed run consumes its needs and produces nothing but the renewable resources it
+//     held; a successful one produces a yield drawn in the range of each varying result.
+//   - After a shortfall, the rest of the plan is replaced by a new plan made from the stock
+//     at hand, the results of the runs still in progress being left out until they arrive.
+//     The new plan starts at the current cycle. Lots keep their actual age, though the new
+//     plan counts them as fresh.
+//   - When the plan is done and nothing runs anymore, but a process can run, as when yields
+//     exceeded their expected quantity, a new plan is made as well.
+//   - Every plan shares the budget; once it is spent, the current plan is carried out to
+//     the end without planning again.
+//   - Deliveries arrive and orders ship as in the schedulers (see Timeline); each new plan
+//     only knows of the deliveries still to come and the orders still open.
+//
+// The outcome of each entry is recorded in e.Outcomes and the number of new plans in
+// e.Replans.
+//
+// Returns:
+//   - The plan actually carried out, or ErrNothingRunnable if no process can run at all,
+//     or the scheduler's error.
+func (e *Engine) execute(scheduler Scheduler, config *util.ConfigData, budget time.Duration, rng *rand.Rand, emit func(ScheduleEntry)) (*Plan, error) {
+	x := &execution{
+		config:    config,
+		scheduler: scheduler,
+		rng:       rng,
+		obj:       NewObjective(config.OptimizeTargets, config.Processes, config.Stocks),
+		byName:    make(map[string]*process.Process, len(config.Processes)),
+		deadline:  time.Now().Add(budget),
+		emit:      emit,
+		stock:     cloneStock(config.Stocks),
+		incoming:  map[string]int{},
+		lots:      NewLots(config.ShelfLife, config.Stocks),
+		expired:   map[string]int{},
+		timeline:  NewTimeline(config.Deliveries, config.Orders),
+		entries:   []ScheduleEntry{},
+	}
+	for _, p := range config.Processes {
+		x.byName[p.Name] = p
+	}
+
+	x.timeline.Advance(0, x.stock, x.receive)
+	if err := x.plan(); err != nil {
+		return nil, err
+	}
+	for {
+		x.startDue()
+		if len(x.running) == 0 && len(x.queue) == 0 {
+			if x.runnable() && time.Now().Before(x.deadline) {
+				if err := x.replan(); err != nil {
+					return nil, err
+				}
+				x.startDue()
+			}
+			if len(x.running) == 0 && len(x.queue) == 0 && !x.timeline.Pending() {
+				break
+			}
+		}
+
+		next := x.timeline.Next()
+		for _, run := range x.running {
+			next = min(next, run.end)
+		}
+		if len(x.queue) > 0 && x.queue[0].Cycle > x.cycle {
+			next = min(next, x.queue[0].Cycle)
+		}
+		if next == math.MaxInt {
+			break
+		}
+		x.cycle = next
+		if err := x.complete(); err != nil {
+			return nil, err
+		}
+	}
+
+	cycle := x.lastEnd + 1
+	if x.timedOut {
+		cycle = max(x.cycle, x.lastEnd)
+	}
+	plan := &Plan{
+		Entries:  x.entries,
+		Stock:    x.stock,
+		Cycle:    cycle,
+		Makespan: x.makespan,
+		TimedOut: x.timedOut,
+		Lots:     x.lots.Items,
+		Expired:  x.expired,
+		Orders:   x.timeline.Results(),
+	}
+	e.Outcomes = x.outcomes
+	e.Replans = x.replans
+	return plan, nil
+}
+
+// plan replaces the rest of the current plan with a new one made from the stock at hand,
+// unless the budget is spent.
+func (x *execution) plan() error {
+	remaining := time.Until(x.deadline)
+	if remaining <= 0 && len(x.entries) > 0 {
+		x.timedOut = true
+		return nil
+	}
+	config := *x.config
+	config.Stocks = cloneStock(x.stock)
+	config.Deliveries, config.Orders = x.timeline.Remaining(x.cycle)
+	plan, err := x.scheduler.Schedule(&config, remaining)
+	if err != nil {
+		x.queue = nil
+		return err
+	}
+	x.timedOut = plan.TimedOut
+	x.queue = make([]ScheduleEntry, len(plan.Entries))
+	for i, entry := range plan.Entries {
+		x.queue[i] = ScheduleEntry{Cycle: x.cycle + entry.Cycle, ProcessName: entry.ProcessName}
+	}
+	return nil
+}
+
+// startDue starts the entries of the plan that are due, one planned cycle at a time, and
+// reports whether it started any. The entries of a planned cycle are started in the order of
+// the plan, those that cannot start yet being tried again after the others, which may make
+// room for them. While some cannot start and a run is in progress, they wait for it with the
+// entries after them; with nothing running, they never will and are dropped, as are entries
+// naming an unknown process.
+func (x *execution) startDue() bool {
+	started := false
+	for len(x.queue) > 0 && x.queue[0].Cycle <= x.cycle {
+		n := 1
+		for n < len(x.queue) && x.queue[n].Cycle == x.queue[0].Cycle {
+			n++
+		}
+		blocked := []ScheduleEntry{}
+		for _, entry := range x.queue[:n] {
+			if p := x.byName[entry.ProcessName]; p != nil {
+				blocked = append(blocked, entry)
+			}
+		}
+		for progress := true; progress && len(blocked) > 0; {
+			progress = false
+			waiting := blocked[:0]
+			for _, entry := range blocked {
+				if p := x.byName[entry.ProcessName]; x.canStart(p) {
+					x.start(p)
+					progress, started = true, true
+				} else {
+					waiting = append(waiting, entry)
+				}
+			}
+			blocked = waiting
+		}
+		if len(blocked) > 0 && len(x.running) > 0 {
+			x.queue = append(blocked, x.queue[n:]...)
+			break
+		}
+		x.queue = x.queue[n:]
+	}
+	return started
+}
+
+// start starts a run of the process at the current cycle and records its entry.
+func (x *execution) start(p *process.Process) {
+	for item, qty := range p.Needs {
+		x.stock[item] -= qty
+		x.lots.Take(item, qty)
+	}
+	for item, qty := range p.MostResult() {
+		x.incoming[item] += qty
+	}
+	entry := ScheduleEntry{Cycle: x.cycle, ProcessName: p.Name}
+	x.running = append(x.running, executedRun{p: p, entry: len(x.entries), end: x.cycle + p.Cycle})
+	x.entries = append(x.entries, entry)
+	x.outcomes = append(x.outcomes, p.NominalOutcome())
+	if x.emit != nil {
+		x.emit(entry)
+	}
+}
+
+// canStart reports whether a process has its needs in stock and room for its largest
+// possible results.
+func (x *execution) canStart(p *process.Process) bool {
+	return p.CanRun(x.stock) && p.Fits(x.stock, x.incoming, x.config.Capacities)
+}
+
+// runnable reports whether any process could start now.
+func (x *execution) runnable() bool {
+	for _, p := range x.config.Processes {
+		if x.canStart(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// complete draws the outcome of the runs ending at the current cycle, in the order they
+// started, adds what they produced to the stock, drops the expired lots, applies the
+// deliveries and orders of the cycle, and plans again if a run fell short.
+func (x *execution) complete() error {
+	shortfall := false
+	remaining := x.running[:0]
+	for _, run := range x.running {
+		if run.end > x.cycle {
+			remaining = append(remaining, run)
+			continue
+		}
+		p := run.p
+		outcome := p.NominalOutcome()
+		if p.Stochastic() {
+			outcome = p.Draw(x.rng)
+		}
+		x.outcomes[run.entry] = outcome
+		for item, qty := range p.OutputOf(outcome) {
+			x.stock[item] += qty
+			x.lots.Add(item, qty, x.cycle)
+		}
+		for item, qty := range p.MostResult() {
+			x.incoming[item] -= qty
+		}
+		if !outcome.Failed && x.obj.ProducesTarget(p) {
+			x.makespan = max(x.makespan, x.cycle)
+		}
+		x.lastEnd = max(x.lastEnd, x.cycle)
+		shortfall = shortfall || p.Shortfall(outcome)
+	}
+	x.running = remaining
+	for item, qty := range x.lots.Expire(x.cycle) {
+		x.stock[item] -= qty
+		x.expired[item] += qty
+	}
+	if x.timeline.Next() <= x.cycle {
+		x.lastEnd = max(x.lastEnd, x.cycle)
+	}
+	x.timeline.Advance(x.cycle, x.stock, x.receive)
+
+	if !shortfall {
+		return nil
+	}
+	return x.replan()
+}
+
+// receive changes the stock of an item by a timeline event at the current cycle.
+func (x *execution) receive(item string, qty int) {
+	x.stock[item] += qty
+	if qty > 0 {
+		x.lots.Add(item, qty, x.cycle)
+	} else {
+		x.lots.Take(item, -qty)
+	}
+}
+
+// replan plans again from the current state; finding nothing to run is not an error.
+func (x *execution) replan() error {
+	x.replans++
+	if err := x.plan(); err != nil && err != ErrNothingRunnable {
+		return err
+	}
+	return nil
+}
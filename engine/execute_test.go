@@ -0,0 +1,179 @@
+package engine
+
+import (
+	"io"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/jesee-kuya/stock_exchange/process"
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// newBakeryEngine returns an engine baking bread from dough with one oven, with a bake
+// that fails half the time and yields 2 to 4 bread.
+func newBakeryEngine(seed int64) *Engine {
+	e := NewEngine()
+	e.Out = io.Discard
+	e.Random = rand.New(rand.NewSource(seed))
+	e.Stock.Items = map[string]int{"dough": 6, "oven": 1}
+	e.Processes = []*process.Process{
+		{
+			Name: "bake", Needs: map[string]int{"dough": 1, "oven": 1}, Result: map[string]int{"bread": 3}, Cycle: 5,
+			Holds: map[string]int{"oven": 1}, Failure: 0.5, Yields: map[string]process.Yield{"bread": {Min: 2, Max: 4}},
+		},
+	}
+	e.OptimizeTargets = []string{"bread"}
+	return e
+}
+
+// TestRunOutcomes tests that a run with drawn outcomes only depends on its seed, that
+// the stock reflects what each run actually produced, and that a run without varying
+// processes follows the plan.
+func TestRunOutcomes(t *testing.T) {
+	e := newBakeryEngine(1)
+	if err := e.Run("1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Outcomes) != len(e.Schedule) || len(e.Schedule) != 6 {
+		t.Fatalf("%d outcomes for %d entries, want one per bake of the 6 dough", len(e.Outcomes), len(e.Schedule))
+	}
+	bread, failed := 0, 0
+	for i, o := range e.Outcomes {
+		if o.Failed {
+			failed++
+		}
+		bread += o.Result["bread"]
+		if e.Schedule[i].Produced["oven"] != 1 {
+			t.Errorf("entry %d produced %v, want the oven back", i, e.Schedule[i].Produced)
+		}
+	}
+	if e.Stock.Items["bread"] != bread || e.Stock.Items["oven"] != 1 {
+		t.Errorf("stock = %v, want the %d bread of the outcomes and the oven", e.Stock.Items, bread)
+	}
+	if failed == 0 || e.Replans == 0 {
+		t.Errorf("%d failures and %d new plans, want the seed to make some bakes fail", failed, e.Replans)
+	}
+
+	again := newBakeryEngine(1)
+	if err := again.Run("1"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again.Outcomes, e.Outcomes) || !reflect.DeepEqual(again.Schedule, e.Schedule) {
+		t.Errorf("the same seed gave %v and %v", e.Outcomes, again.Outcomes)
+	}
+
+	planned := newCabinetEngine("cabinet")
+	planned.Out = io.Discard
+	planned.Run("1")
+	drawn := newCabinetEngine("cabinet")
+	drawn.Out = io.Discard
+	drawn.Random = rand.New(rand.NewSource(1))
+	drawn.Run("1")
+	if !reflect.DeepEqual(drawn.Schedule, planned.Schedule) || drawn.Replans != 0 {
+		t.Errorf("schedule = %v with %d new plans, want the plan %v", drawn.Schedule, drawn.Replans, planned.Schedule)
+	}
+}
+
+// TestRunExpectedResults tests that a plan counts on the expected results of a process
+// that can fail, while a successful run of it produces its full results.
+func TestRunExpectedResults(t *testing.T) {
+	newEngine := func() *Engine {
+		e := NewEngine()
+		e.Out = io.Discard
+		e.Stock.Items = map[string]int{"dough": 6}
+		bake := &process.Process{Name: "bake", Needs: map[string]int{"dough": 1}, Result: map[string]int{"bread": 4}, Cycle: 5, Failure: 0.5}
+		bake.PlanExpected()
+		e.Processes = []*process.Process{bake}
+		e.OptimizeTargets = []string{"bread"}
+		return e
+	}
+
+	planned := newEngine()
+	if err := planned.Run("1"); err != nil {
+		t.Fatal(err)
+	}
+	if planned.Stock.Items["bread"] != 12 {
+		t.Errorf("planned bread = %d, want 2 expected of each of the 6 bakes", planned.Stock.Items["bread"])
+	}
+
+	drawn := newEngine()
+	drawn.Random = rand.New(rand.NewSource(1))
+	if err := drawn.Run("1"); err != nil {
+		t.Fatal(err)
+	}
+	failed := 0
+	for _, o := range drawn.Outcomes {
+		if o.Failed {
+			failed++
+		} else if o.Result["bread"] != 4 {
+			t.Errorf("outcome %v, want a success to make 4 bread", o)
+		}
+	}
+	if failed == 0 || drawn.Replans == 0 || drawn.Stock.Items["bread"] != 4*(6-failed) {
+		t.Errorf("%d failures, %d new plans and %d bread, want failures planned again for and 4 bread per success",
+			failed, drawn.Replans, drawn.Stock.Items["bread"])
+	}
+}
+
+// producersFirst is a scheduler logging the entries of each cycle in the order of their
+// process names, whatever order they can start in.
+type producersFirst struct {
+	Scheduler
+}
+
+func (s producersFirst) Schedule(config *util.ConfigData, budget time.Duration) (*Plan, error) {
+	plan, err := s.Scheduler.Schedule(config, budget)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(plan.Entries, func(i, j int) bool {
+		a, b := plan.Entries[i], plan.Entries[j]
+		if a.Cycle != b.Cycle {
+			return a.Cycle < b.Cycle
+		}
+		return a.ProcessName < b.ProcessName
+	})
+	return plan, nil
+}
+
+// newOvenEngine returns an engine whose two ovens fill the bread storage, so each cycle has
+// to sell before it bakes again, with fixed yields so that nothing is drawn.
+func newOvenEngine() *Engine {
+	e := NewEngine()
+	e.Out = io.Discard
+	e.Scheduler = producersFirst{greedyScheduler{}}
+	e.Stock.Items = map[string]int{"flour": 10, "bread": 0, "oven": 2}
+	e.Capacities = map[string]int{"bread": 6}
+	e.Processes = []*process.Process{
+		{Name: "bake", Needs: map[string]int{"flour": 1, "oven": 1}, Result: map[string]int{"bread": 3}, Cycle: 5,
+			Holds: map[string]int{"oven": 1}, Yields: map[string]process.Yield{"bread": {Min: 3, Max: 3}}},
+		{Name: "sell", Needs: map[string]int{"bread": 3}, Result: map[string]int{"euro": 10}, Cycle: 1},
+	}
+	e.OptimizeTargets = []string{"euro"}
+	return e
+}
+
+// TestRunOutcomesFollowPlan tests that a run with nothing to draw carries the plan out
+// without planning again, even when the plan logs the entries of a cycle ahead of the ones
+// making room for them.
+func TestRunOutcomesFollowPlan(t *testing.T) {
+	planned := newOvenEngine()
+	if err := planned.Run("1"); err != nil {
+		t.Fatal(err)
+	}
+	drawn := newOvenEngine()
+	drawn.Random = rand.New(rand.NewSource(1))
+	if err := drawn.Run("1"); err != nil {
+		t.Fatal(err)
+	}
+	if drawn.Replans != 0 || drawn.Cycle != planned.Cycle || len(drawn.Schedule) != len(planned.Schedule) {
+		t.Errorf("%d entries ending at cycle %d with %d new plans, want the %d entries of the plan ending at cycle %d",
+			len(drawn.Schedule), drawn.Cycle, drawn.Replans, len(planned.Schedule), planned.Cycle)
+	}
+	if drawn.Stock.Items["euro"] != 100 {
+		t.Errorf("euro = %d, want 100", drawn.Stock.Items["euro"])
+	}
+}
@@ -0,0 +1,271 @@
+package engine
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/jesee-kuya/stock_exchange/process"
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// GeneticAlgorithm is a metaheuristic scheduler. A schedule is encoded as an
+// activity list, an ordered list of process names, and decoded with a serial
+// schedule generation scheme: each activity is started at the earliest
+// completion event from which it can run, never before the previous activity.
+// Once the list is exhausted the schedule is completed greedily. The population
+// evolves with one-point crossover, mutation and tournament selection until the
+// budget expires. Its first individual is the greedy plan, which is also returned
+// when no activity list does better.
+//
+// Fields:
+//   - Population: the number of activity lists per generation.
+//   - Mutation: the probability, in percent, that a child is mutated.
+//   - Genes: the maximum length of an activity list.
+//   - Seed: the seed of the random number generator, for reproducible runs.
+type GeneticAlgorithm struct {
+	Population int
+	Mutation   int
+	Genes      int
+	Seed       int64
+}
+
+func init() {
+	RegisterScheduler(GeneticAlgorithm{Population: 40, Mutation: 30, Genes: 500, Seed: 1})
+}
+
+// Name returns the name the genetic algorithm scheduler is registered under.
+func (GeneticAlgorithm) Name() string {
+	return "genetic"
+}
+
+// WithOptions applies the "population", "mutation", "genes" and "seed" options.
+func (g GeneticAlgorithm) WithOptions(opts map[string]string) (Scheduler, error) {
+	for key, value := range opts {
+		n, err := parseIntOption(key, value)
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case "population":
+			if n < 2 {
+				return nil, fmt.Errorf("option population must be at least 2")
+			}
+			g.Population = n
+		case "mutation":
+			if n > 100 {
+				return nil, fmt.Errorf("option mutation is a percentage, got %d", n)
+			}
+			g.Mutation = n
+		case "genes":
+			if n == 0 {
+				return nil, fmt.Errorf("option genes must be at least 1")
+			}
+			g.Genes = n
+		case "seed":
+			g.Seed = int64(n)
+		default:
+			return nil, fmt.Errorf("unknown option %q for scheduler %s", key, g.Name())
+		}
+	}
+	return g, nil
+}
+
+// individual is an activity list together with its decoded schedule and score.
+type individual struct {
+	Genes []*process.Process
+	State *simState
+	Score Score
+	Cut   bool // the greedy completion stopped before the schedule was finished
+}
+
+// gaSearch holds the data shared by the generations of one genetic algorithm run.
+type gaSearch struct {
+	GeneticAlgorithm
+	obj       Objective
+	config    *util.ConfigData
+	all       []*process.Process
+	reachable []*process.Process
+	quotas    map[string]int
+	rng       *rand.Rand
+}
+
+// Schedule evolves activity lists until the budget expires and returns the best
+// decoded schedule.
+func (g GeneticAlgorithm) Schedule(config *util.ConfigData, budget time.Duration) (*Plan, error) {
+	deadline := time.Now().Add(budget)
+	if g.Population < 2 {
+		g.Population = 2
+	}
+	if g.Genes <= 0 {
+		g.Genes = 1
+	}
+
+	greedy, err := greedyScheduler{}.Schedule(config, budget/10)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := NewObjective(config.OptimizeTargets, config.Processes, config.Stocks)
+	depths := computeDepths(config.Processes, obj.Items)
+	priorities := computePriorities(config.Processes, obj)
+	search := &gaSearch{
+		GeneticAlgorithm: g,
+		obj:              obj,
+		config:           config,
+		quotas:           computeQuotas(config.Processes, depths),
+		rng:              rand.New(rand.NewSource(g.Seed)),
+	}
+	search.all = sortByPriority(config.Processes, priorities)
+	search.reachable = search.all
+	if obj.Time {
+		search.reachable = reachableProcesses(search.all, depths)
+	}
+
+	// Seed the population with the greedy activity list, then random ones of the same length
+	byName := map[string]*process.Process{}
+	for _, p := range config.Processes {
+		byName[p.Name] = p
+	}
+	seed := []*process.Process{}
+	for _, entry := range greedy.Entries {
+		if len(seed) == g.Genes {
+			break
+		}
+		seed = append(seed, byName[entry.ProcessName])
+	}
+	population := []individual{search.decode(seed)}
+	for len(population) < g.Population {
+		genes := make([]*process.Process, len(seed))
+		for i := range genes {
+			genes[i] = search.randomGene()
+		}
+		population = append(population, search.decode(genes))
+	}
+	search.sort(population)
+
+	for time.Now().Before(deadline) {
+		// Elitism: the two best individuals survive unchanged
+		next := []individual{population[0], population[1]}
+		for len(next) < g.Population {
+			child := search.crossover(search.tournament(population).Genes, search.tournament(population).Genes)
+			if search.rng.Intn(100) < g.Mutation {
+				child = search.mutate(child)
+			}
+			next = append(next, search.decode(child))
+		}
+		search.sort(next)
+		population = next
+	}
+
+	best := population[0]
+	if s := obj.EvaluatePlan(greedy); obj.Better(s, best.Score) {
+		// The greedy plan may be longer than the activity lists can encode
+		return greedy, nil
+	}
+	plan := best.State.plan()
+	if best.Cut {
+		plan.Cycle = best.State.Cycle
+		plan.TimedOut = true
+	}
+	return plan, nil
+}
+
+// decode builds the schedule of an activity list with the serial schedule
+// generation scheme, completes it greedily and scores it. Activities that can
+// never run once everything started has completed are dropped.
+func (s *gaSearch) decode(genes []*process.Process) individual {
+	state := newSimState(s.config)
+	for _, p := range genes {
+		for !state.canStart(p) && state.advance(s.obj) {
+		}
+		if state.canStart(p) {
+			state.start(p)
+		}
+	}
+
+	// Complete greedily, for at most as many events as there are genes
+	cut := false
+	for events := 0; ; events++ {
+		state.startGreedy(state.runnable(state.candidates(s.reachable, s.all)), s.quotas, nil)
+		if events >= len(genes) && len(state.Running) > 0 {
+			cut = true
+			state.finish(s.obj)
+			break
+		}
+		if !state.advance(s.obj) {
+			break
+		}
+	}
+
+	return individual{
+		Genes: genes,
+		State: state,
+		Score: state.score(s.obj),
+		Cut:   cut,
+	}
+}
+
+// sort orders a population from the best to the worst score.
+func (s *gaSearch) sort(population []individual) {
+	sort.SliceStable(population, func(i, j int) bool {
+		return s.obj.Better(population[i].Score, population[j].Score)
+	})
+}
+
+// tournament returns the best of three individuals drawn at random.
+func (s *gaSearch) tournament(population []individual) individual {
+	best := population[s.rng.Intn(len(population))]
+	for i := 1; i < 3; i++ {
+		if other := population[s.rng.Intn(len(population))]; s.obj.Better(other.Score, best.Score) {
+			best = other
+		}
+	}
+	return best
+}
+
+// crossover returns the head of one parent joined with the tail of the other,
+// cut at a random position, truncated to the maximum number of genes.
+func (s *gaSearch) crossover(a, b []*process.Process) []*process.Process {
+	cutA := s.rng.Intn(len(a) + 1)
+	cutB := s.rng.Intn(len(b) + 1)
+	child := make([]*process.Process, 0, cutA+len(b)-cutB)
+	child = append(child, a[:cutA]...)
+	child = append(child, b[cutB:]...)
+	if len(child) > s.Genes {
+		child = child[:s.Genes]
+	}
+	return child
+}
+
+// mutate applies one random change to a copy of the genes: swapping two
+// activities, replacing one, inserting one or removing one.
+func (s *gaSearch) mutate(genes []*process.Process) []*process.Process {
+	child := make([]*process.Process, len(genes))
+	copy(child, genes)
+	if len(child) == 0 {
+		return append(child, s.randomGene())
+	}
+
+	i := s.rng.Intn(len(child))
+	switch s.rng.Intn(4) {
+	case 0:
+		j := s.rng.Intn(len(child))
+		child[i], child[j] = child[j], child[i]
+	case 1:
+		child[i] = s.randomGene()
+	case 2:
+		if len(child) < s.Genes {
+			child = append(child[:i], append([]*process.Process{s.randomGene()}, child[i:]...)...)
+		}
+	default:
+		child = append(child[:i], child[i+1:]...)
+	}
+	return child
+}
+
+// randomGene returns a process drawn uniformly from the ones contributing to the targets.
+func (s *gaSearch) randomGene() *process.Process {
+	return s.reachable[s.rng.Intn(len(s.reachable))]
+}
@@ -0,0 +1,34 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+// TestGeneticAlgorithm tests that the genetic algorithm finds a better schedule than
+// the greedy scheme on the detour configuration and that activity lists decode in order.
+func TestGeneticAlgorithm(t *testing.T) {
+	ga := GeneticAlgorithm{Population: 10, Mutation: 50, Genes: 10, Seed: 7}
+
+	plan, err := ga.Schedule(detourConfig(), 200*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Schedule returned an error: %v", err)
+	}
+	if plan.Stock["t"] != 6 {
+		t.Errorf("expected 6 t, got %d", plan.Stock["t"])
+	}
+
+	search := &gaSearch{GeneticAlgorithm: ga}
+	search.obj = NewObjective([]string{"t"}, detourConfig().Processes, detourConfig().Stocks)
+	search.config = detourConfig()
+	search.all = detourConfig().Processes
+	search.reachable = search.all
+	search.quotas = computeQuotas(search.all, computeDepths(search.all, search.obj.Items))
+
+	direct := search.all[2]
+	decoded := search.decode(append(search.all[:0:0], direct, direct))
+	if decoded.Score.Quantity != 6 || len(decoded.State.Entries) != 2 {
+		t.Errorf("decoding [p_direct p_direct] gave %d t in %d entries, want 6 in 2",
+			decoded.Score.Quantity, len(decoded.State.Entries))
+	}
+}
@@ -0,0 +1,233 @@
+package engine
+
+import (
+	"container/heap"
+	"time"
+
+	"github.com/jesee-kuya/stock_exchange/process"
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// greedyScheduler is the priority-based parallel schedule generation scheme.
+// At every completion event it starts every process that can run, in priority
+// order, and never revisits a decision. Cycles in which nothing completes are
+// skipped, so its runtime depends on the number of events, not of cycles.
+type greedyScheduler struct{}
+
+func init() {
+	RegisterScheduler(greedyScheduler{})
+}
+
+// Name returns the name the greedy scheduler is registered under.
+func (greedyScheduler) Name() string {
+	return DefaultScheduler
+}
+
+// Schedule runs the greedy scheme on a private copy of the configuration stock.
+// The search stops when no process can run anymore or when the budget is spent,
+// in which case processes already started are still allowed to complete.
+func (g greedyScheduler) Schedule(config *util.ConfigData, budget time.Duration) (*Plan, error) {
+	return g.ScheduleStream(config, budget, nil)
+}
+
+// ScheduleStream runs the greedy scheme like Schedule and hands every entry to emit
+// at the cycle it is started. emit may be nil.
+func (greedyScheduler) ScheduleStream(config *util.ConfigData, budget time.Duration, emit func(ScheduleEntry)) (*Plan, error) {
+	e := &Engine{
+		Stock:           &Stock{Items: cloneStock(config.Stocks)},
+		Processes:       config.Processes,
+		OptimizeTargets: config.OptimizeTargets,
+		Capacities:      config.Capacities,
+		ShelfLife:       config.ShelfLife,
+		Deliveries:      config.Deliveries,
+		Orders:          config.Orders,
+	}
+	return e.runGreedy(budget, emit)
+}
+
+// runGreedy implements a priority-based parallel schedule generation scheme that:
+// 1. Schedules processes based on their priority (derived from optimization targets)
+// 2. Runs multiple processes concurrently when resources allow
+// 3. Respects process dependencies and resource constraints
+// 4. Stops when the time limit is reached or no more processes can be scheduled
+// 5. Jumps from one completion event to the next, since stock only changes then
+// 6. Only starts a process when its results fit in e.Capacities, counting running processes
+// 7. Consumes perishable units oldest first, starting their consumers first, and drops them once expired
+// 8. Produces perishable items on demand, unless nothing else would run (see runnableIndex.surplus)
+// 9. Applies e.Deliveries and ships e.Orders after the completions of every event (see Timeline)
+//
+// The processes are started in passes over the runnable ones (see runnableIndex.startPasses),
+// each pass only revisiting the processes that can still start. With storage capacities, the
+// passes let a consumer started later in a pass make room for a producer that did not fit earlier.
+// The entries of a cycle are logged in the order they were started, so each one can start
+// once the ones before it have, whoever replays the log.
+//
+// When "time" is among the optimize targets, the critical path of each process drives
+// its priority, processes that cannot contribute to a target are only started once
+// the targets are out of reach, and
+// the cycle at which the last target unit is produced is recorded in e.Makespan.
+//
+// The engine's stock is consumed and replenished in place. The run goes on until the last
+// delivery has arrived, even once the budget is spent or nothing can run in the meantime.
+//
+// Parameters:
+//   - budget: Maximum wall-clock time spent starting new processes
+//   - emit: Called with each entry as soon as it is scheduled; may be nil
+//
+// Returns:
+//   - The resulting plan, or ErrNothingRunnable if no process can run at all and no
+//     delivery is expected
+func (e *Engine) runGreedy(budget time.Duration, emit func(ScheduleEntry)) (*Plan, error) {
+	if !e.canRunAny(e.Processes) && len(e.Deliveries) == 0 {
+		return nil, ErrNothingRunnable
+	}
+
+	start := time.Now()
+	e.Cycle = 0
+	e.Makespan = 0
+	running := &eventQueue{}
+	plan := &Plan{Entries: []ScheduleEntry{}}
+
+	obj := NewObjective(e.OptimizeTargets, e.Processes, e.Stock.Items)
+	depths := computeDepths(e.Processes, obj.Items)
+	priorities := computePriorities(e.Processes, obj)
+	quotas := computeQuotas(e.Processes, depths)
+
+	// Under time optimization, processes that cannot lead to a target only
+	// compete for stock with the critical chain, so they are deferred until
+	// the targets are out of reach.
+	candidates := e.Processes
+	if obj.Time {
+		candidates = reachableProcesses(e.Processes, depths)
+	}
+	ordered := sortByPriority(e.Processes, priorities)
+	index := newRunnableIndex(ordered, e.Stock.Items, candidates)
+	index.capacities = e.Capacities
+	index.lots = NewLots(e.ShelfLife, e.Stock.Items)
+	timeline := NewTimeline(e.Deliveries, e.Orders)
+
+	timeExceeded := false
+
+	for {
+		// Check time limit but don't break immediately if processes are still running
+		if time.Since(start) >= budget {
+			timeExceeded = true
+		}
+
+		completeRunningProcesses(running, e, obj, index)
+		timeline.Advance(e.Cycle, index.stock, func(item string, qty int) {
+			index.receive(item, qty, e.Cycle)
+		})
+
+		// Only schedule new processes if time hasn't exceeded
+		if !timeExceeded {
+			// Runnable processes for this cycle, by priority (descending) and then by name (descending)
+			runnable := index.ranks()
+
+			quota := func(rank int) int { return quotas[ordered[rank].Name] }
+			var started []int
+			for _, onDemand := range []bool{true, false} {
+				// Start up to the process quota per pass so intermediate
+				// items are produced in the ratio their consumers need
+				started = index.startPasses(runnable, quota, onDemand)
+				// Surplus perishables are only made when nothing else would keep the run going
+				if running.Len() > 0 || len(started) > 0 {
+					break
+				}
+			}
+
+			// Schedule the processes in the order they were started, so that every entry
+			// of the cycle can start once the ones before it have
+			for _, rank := range started {
+				p := ordered[rank]
+				// Add to running processes; a process takes at least one cycle
+				heap.Push(running, runningProcess{
+					Process: p,
+					End:     e.Cycle + max(p.Cycle, 1),
+				})
+				// Create schedule entry
+				entry := ScheduleEntry{
+					Cycle:       e.Cycle,
+					ProcessName: p.Name,
+				}
+				plan.Entries = append(plan.Entries, entry)
+				if emit != nil {
+					emit(entry)
+				}
+			}
+
+			// Check if we can continue (only if time hasn't exceeded)
+			if running.Len() == 0 && !index.any() {
+				if index.includeAll() {
+					// Let the deferred processes use up the remaining stock
+					continue
+				}
+				if timeline.Pending() {
+					// Wait for the next delivery to bring new stock
+					e.Cycle = timeline.Next()
+					continue
+				}
+				plan.Cycle = e.Cycle + 1
+				break
+			}
+		} else if running.Len() == 0 {
+			if timeline.Pending() {
+				e.Cycle = timeline.Next()
+				continue
+			}
+			// Time exceeded and every running process has completed
+			plan.Cycle = e.Cycle
+			plan.TimedOut = true
+			break
+		}
+
+		// Nothing can change before the next completion or delivery, so jump straight to it
+		e.Cycle = min((*running)[0].End, timeline.Next())
+	}
+
+	plan.Stock = e.Stock.Items
+	plan.Makespan = e.Makespan
+	plan.Lots = index.lots.Items
+	plan.Expired = index.expired
+	plan.Orders = timeline.Results()
+	return plan, nil
+}
+
+// completeRunningProcesses pops every running process whose end cycle has been
+// reached and completes it: its results are added to the engine's stock through
+// the runnable index, and the engine's makespan moves to the current cycle if it
+// produced a target item. The perishable units expired by the current cycle are
+// then removed.
+//
+// Parameters:
+//   - running: Queue of currently running processes
+//   - e: The engine instance whose cycle and makespan are used
+//   - obj: The optimization objective used to track the makespan
+//   - index: The runnable index tracking the engine's stock
+func completeRunningProcesses(running *eventQueue, e *Engine, obj Objective, index *runnableIndex) {
+	for running.Len() > 0 && (*running)[0].End <= e.Cycle {
+		rp := heap.Pop(running).(runningProcess)
+		index.complete(rp.Process, e.Cycle)
+		if obj.ProducesTarget(rp.Process) {
+			e.Makespan = e.Cycle
+		}
+	}
+	index.expire(e.Cycle)
+}
+
+// canRunAny checks if any of the given processes can be executed
+// with the current stock levels.
+//
+// Parameters:
+//   - processes: The processes to consider
+//
+// Returns:
+//   - true if at least one process can run, false otherwise
+func (e *Engine) canRunAny(processes []*process.Process) bool {
+	for _, p := range processes {
+		if p.CanRun(e.Stock.Items) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,9 +1,18 @@
 package engine
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/jesee-kuya/stock_exchange/util"
 )
 
+// ErrInvalidConfig is returned when loading a configuration with a process whose largest
+// results can never fit in storage (util.ErrExceedsCapacity): no schedule could run it, so
+// the configuration is rejected before any scheduling starts.
+var ErrInvalidConfig = errors.New("configuration cannot be scheduled")
+
 // LoadConfig loads and parses a configuration file to initialize the Engine with
 // stock items, processes, and optimization targets.
 //
@@ -21,9 +30,15 @@ import (
 //
 // Behavior:
 //   - Delegates file parsing to util.ParseConfig() which validates the file format.
+//   - Rejects the configuration with ErrInvalidConfig when util.Validate reports that a
+//     process exceeds a capacity, listing those errors one per line; the other issues are
+//     left to the lint command.
 //   - Initializes the Engine's Stock with the parsed stock items and quantities.
 //   - Populates the Engine's Processes slice with the parsed process definitions.
 //   - Sets the Engine's OptimizeTargets with the parsed optimization goals.
+//   - Sets the Engine's Capacities with the storage capacities of the items.
+//   - Sets the Engine's ShelfLife with the shelf life of the perishable items.
+//   - Sets the Engine's Deliveries and Orders with the timed events.
 //   - Maintains the original structure and relationships between parsed data.
 //
 // Parameters:
@@ -31,6 +46,7 @@ import (
 //
 // Returns:
 //   - An error if the file cannot be read, parsed, or contains invalid format.
+//   - An error wrapping ErrInvalidConfig if a process can never fit in storage.
 //   - nil if the configuration is successfully loaded and the Engine is initialized.
 //
 // Example usage:
@@ -41,12 +57,31 @@ import (
 //	    log.Fatal("Failed to load config:", err)
 //	}
 func (e *Engine) LoadConfig(path string) error {
-	config, err := util.ParseConfig(path)
+	return e.LoadConfigAs(path, util.FormatAuto)
+}
+
+// LoadConfigAs is LoadConfig for a configuration in the given format: util.FormatText,
+// util.FormatJSON, util.FormatYAML, or util.FormatAuto to pick it from the file extension.
+func (e *Engine) LoadConfigAs(path string, format util.Format) error {
+	config, err := util.ParseConfigAs(path, format)
 	if err != nil {
 		return err
 	}
+	lines := []string{}
+	for _, issue := range util.Validate(config) {
+		if issue.Code == util.ErrExceedsCapacity {
+			lines = append(lines, issue.Format(path))
+		}
+	}
+	if len(lines) > 0 {
+		return fmt.Errorf("%w:\n%s", ErrInvalidConfig, strings.Join(lines, "\n"))
+	}
 	e.Stock = &Stock{Items: config.Stocks}
 	e.Processes = config.Processes
 	e.OptimizeTargets = config.OptimizeTargets
+	e.Capacities = config.Capacities
+	e.ShelfLife = config.ShelfLife
+	e.Deliveries = config.Deliveries
+	e.Orders = config.Orders
 	return nil
 }
@@ -0,0 +1,165 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Lot is a quantity of a perishable item produced at the same cycle.
+type Lot struct {
+	Cycle int // The cycle the units were produced at, 0 for the initial stock
+	Qty   int // The number of units left in the lot
+}
+
+// Lots tracks the units of perishable items as lots, oldest first, so that they are
+// consumed first in, first out and removed once their shelf life has elapsed. A unit
+// produced at cycle c can be used up to cycle c+ShelfLife-1 and is gone at c+ShelfLife.
+//
+// Lots only records where the units of perishable items come from; the quantities
+// themselves stay in the stock map they describe, which the caller updates with the
+// values Take, Add and Expire work with. Items without a shelf life are ignored.
+//
+// When more units are taken than the lots hold, as when a checker policy lets stocks go
+// negative, the shortfall is owed and repaid by the next units added, which never form
+// a lot of their own, so the lots always sum up to the positive part of the stock.
+//
+// Fields:
+//   - ShelfLife: the shelf life of each perishable item, shared between clones.
+//   - Items: the lots of each perishable item, sorted by production cycle.
+type Lots struct {
+	ShelfLife map[string]int
+	Items     map[string][]Lot
+	owed      map[string]int
+}
+
+// NewLots returns the lots of the initial stock: one lot produced at cycle 0 for every
+// perishable item in stock.
+func NewLots(shelfLife, stock map[string]int) *Lots {
+	l := &Lots{ShelfLife: shelfLife, Items: map[string][]Lot{}, owed: map[string]int{}}
+	for item := range shelfLife {
+		if qty := stock[item]; qty > 0 {
+			l.Items[item] = []Lot{{Cycle: 0, Qty: qty}}
+		} else if qty < 0 {
+			l.owed[item] = -qty
+		}
+	}
+	return l
+}
+
+// Clone returns a copy of the lots that can be modified independently.
+func (l *Lots) Clone() *Lots {
+	c := &Lots{ShelfLife: l.ShelfLife, Items: make(map[string][]Lot, len(l.Items)), owed: cloneStock(l.owed)}
+	for item, lots := range l.Items {
+		c.Items[item] = append([]Lot(nil), lots...)
+	}
+	return c
+}
+
+// Perishable reports whether an item has a shelf life.
+func (l *Lots) Perishable(item string) bool {
+	_, ok := l.ShelfLife[item]
+	return ok
+}
+
+// Take removes qty units of an item from its oldest lots.
+func (l *Lots) Take(item string, qty int) {
+	if !l.Perishable(item) {
+		return
+	}
+	lots := l.Items[item]
+	for qty > 0 && len(lots) > 0 {
+		used := min(qty, lots[0].Qty)
+		lots[0].Qty -= used
+		qty -= used
+		if lots[0].Qty == 0 {
+			lots = lots[1:]
+		}
+	}
+	l.owed[item] += qty
+	l.set(item, lots)
+}
+
+// Add records qty units of an item produced at the given cycle, after repaying what is owed.
+func (l *Lots) Add(item string, qty, cycle int) {
+	if !l.Perishable(item) {
+		return
+	}
+	repaid := min(qty, l.owed[item])
+	l.owed[item] -= repaid
+	if l.owed[item] == 0 {
+		delete(l.owed, item)
+	}
+	if qty -= repaid; qty <= 0 {
+		return
+	}
+
+	lots := l.Items[item]
+	i := sort.Search(len(lots), func(i int) bool { return lots[i].Cycle > cycle })
+	if i > 0 && lots[i-1].Cycle == cycle {
+		lots[i-1].Qty += qty
+		return
+	}
+	lots = append(lots, Lot{})
+	copy(lots[i+1:], lots[i:])
+	lots[i] = Lot{Cycle: cycle, Qty: qty}
+	l.Items[item] = lots
+}
+
+// Expire removes the lots whose shelf life has elapsed at the given cycle.
+//
+// Returns:
+//   - The quantity removed of each item, for the caller to take from the stock; nil when
+//     nothing expired.
+func (l *Lots) Expire(cycle int) map[string]int {
+	var expired map[string]int
+	for item, lots := range l.Items {
+		life := l.ShelfLife[item]
+		n := 0
+		for n < len(lots) && lots[n].Cycle+life <= cycle {
+			if expired == nil {
+				expired = map[string]int{}
+			}
+			expired[item] += lots[n].Qty
+			n++
+		}
+		if n > 0 {
+			l.set(item, lots[n:])
+		}
+	}
+	return expired
+}
+
+// NextExpiry returns the cycle at which the oldest lot of an item expires, or math.MaxInt
+// when the item has no lot.
+func (l *Lots) NextExpiry(item string) int {
+	lots := l.Items[item]
+	if len(lots) == 0 {
+		return math.MaxInt
+	}
+	return lots[0].Cycle + l.ShelfLife[item]
+}
+
+// set stores the lots of an item, dropping the item when it has none left.
+func (l *Lots) set(item string, lots []Lot) {
+	if len(lots) == 0 {
+		delete(l.Items, item)
+		return
+	}
+	l.Items[item] = lots
+}
+
+// key returns a canonical representation of the lots, for simState.key.
+func (l *Lots) key() string {
+	items := make([]string, 0, len(l.Items))
+	for item, lots := range l.Items {
+		parts := make([]string, len(lots))
+		for i, lot := range lots {
+			parts[i] = fmt.Sprintf("%d@%d", lot.Qty, lot.Cycle)
+		}
+		items = append(items, item+"="+strings.Join(parts, ","))
+	}
+	sort.Strings(items)
+	return strings.Join(items, ";")
+}
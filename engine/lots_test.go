@@ -0,0 +1,34 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestLots tests that lots are consumed oldest first, expire after their shelf life and
+// that a shortfall is repaid by the next units added.
+func TestLots(t *testing.T) {
+	lots := NewLots(map[string]int{"dough": 5}, map[string]int{"dough": 2, "flour": 9})
+	lots.Add("dough", 3, 4)
+	lots.Add("flour", 1, 4)
+	lots.Take("dough", 3)
+	if got := fmt.Sprint(lots.Items); got != "map[dough:[{4 2}]]" {
+		t.Errorf("after taking 3, lots = %s, want the 2 initial units and 1 of cycle 4 gone", got)
+	}
+	if lots.NextExpiry("dough") != 9 || lots.NextExpiry("flour") < 1<<30 {
+		t.Errorf("NextExpiry = %d and %d, want 9 and none", lots.NextExpiry("dough"), lots.NextExpiry("flour"))
+	}
+
+	if expired := lots.Expire(8); expired != nil {
+		t.Errorf("Expire(8) = %v, want nothing", expired)
+	}
+	if expired := lots.Expire(9); expired["dough"] != 2 || len(lots.Items) != 0 {
+		t.Errorf("Expire(9) = %v leaving %v, want the 2 units of cycle 4", expired, lots.Items)
+	}
+
+	lots.Take("dough", 1)
+	lots.Add("dough", 3, 10)
+	if got := fmt.Sprint(lots.Items); got != "map[dough:[{10 2}]]" {
+		t.Errorf("after repaying 1, lots = %s, want 2 units of cycle 10", got)
+	}
+}
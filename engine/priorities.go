@@ -0,0 +1,235 @@
+package engine
+
+import (
+	"sort"
+
+	"github.com/jesee-kuya/stock_exchange/process"
+)
+
+// computePriorities calculates priority values for all processes based on
+// their relationship to the optimization objective. Each process is weighted by
+// two measures:
+//   - its dependency depth (see computeDepths): how many production steps separate it from a target;
+//   - its critical-path length (see computeCriticalPaths): the longest chain of process
+//     durations, its own included, between starting it and producing a target unit.
+//
+// By default depth dominates and the critical path breaks ties between processes
+// at the same depth. When the objective asks for time, the critical path dominates
+// so that the processes holding up the makespan are started first.
+// Processes not reachable from the targets get the lowest depth (max + 1) and a
+// critical path of 0.
+//
+// Parameters:
+//   - processes: All available processes
+//   - obj: The resolved optimization objective
+//
+// Returns:
+//   - Map of process names to their priority values (higher = scheduled first)
+func computePriorities(processes []*process.Process, obj Objective) map[string]int {
+	depths := computeDepths(processes, obj.Items)
+	tails := computeCriticalPaths(processes, depths)
+
+	maxDepth, maxTail := 0, 0
+	for _, v := range depths {
+		if v > maxDepth {
+			maxDepth = v
+		}
+	}
+	for _, v := range tails {
+		if v > maxTail {
+			maxTail = v
+		}
+	}
+
+	prio := map[string]int{}
+	for _, p := range processes {
+		depth, ok := depths[p.Name]
+		if !ok {
+			depth = maxDepth + 1
+		}
+		if obj.Time {
+			prio[p.Name] = tails[p.Name]*(maxDepth+2) + depth
+		} else {
+			prio[p.Name] = depth*(maxTail+1) + tails[p.Name]
+		}
+	}
+	return prio
+}
+
+// computeDepths assigns a dependency depth to every process that contributes to
+// the optimization targets, using a breadth-first backward chaining search.
+// Processes that directly produce optimization targets get depth 0,
+// processes that produce inputs for those get depth 1, and so on.
+// When a process is reachable through several chains the longest one wins.
+// Depths are capped at the number of processes so cyclic recipes (e.g. a process
+// that regenerates its own input) terminate.
+//
+// Parameters:
+//   - processes: All available processes
+//   - targets: Map of optimization target items
+//
+// Returns:
+//   - Map of process names to their depth; unreachable processes are absent
+func computeDepths(processes []*process.Process, targets map[string]bool) map[string]int {
+	depth := map[string]int{}
+	queue := []*process.Process{}
+	producers := processesByItem(processes, func(p *process.Process) map[string]int { return p.Result })
+
+	// Initialize with processes that directly produce optimization targets
+	for _, p := range processes {
+		for result := range p.Result {
+			if targets[result] {
+				depth[p.Name] = 0
+				queue = append(queue, p)
+				break
+			}
+		}
+	}
+
+	// Find processes that produce what the current process needs
+	for len(queue) > 0 {
+		curr := queue[0]
+		queue = queue[1:]
+		next := depth[curr.Name] + 1
+		if next >= len(processes) {
+			continue
+		}
+
+		for need := range curr.Needs {
+			for _, p := range producers[need] {
+				if d, seen := depth[p.Name]; !seen || d < next {
+					depth[p.Name] = next
+					queue = append(queue, p)
+				}
+			}
+		}
+	}
+
+	return depth
+}
+
+// computeCriticalPaths computes, for every process reachable from the targets,
+// the length in cycles of the longest chain of processes starting with it and
+// ending with a direct producer of a target. A direct producer's critical path
+// is its own duration. Only consumers closer to the targets (lower depth) are
+// followed, which keeps the computation well defined on cyclic recipes.
+//
+// Parameters:
+//   - processes: All available processes
+//   - depths: Dependency depths as returned by computeDepths
+//
+// Returns:
+//   - Map of process names to their critical-path length; unreachable processes map to 0
+func computeCriticalPaths(processes []*process.Process, depths map[string]int) map[string]int {
+	ordered := make([]*process.Process, 0, len(depths))
+	for _, p := range processes {
+		if _, ok := depths[p.Name]; ok {
+			ordered = append(ordered, p)
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return depths[ordered[i].Name] < depths[ordered[j].Name]
+	})
+
+	consumers := processesByItem(ordered, func(p *process.Process) map[string]int { return p.Needs })
+	tails := map[string]int{}
+	for _, p := range ordered {
+		longest := 0
+		for item := range p.Result {
+			for _, q := range consumers[item] {
+				if depths[q.Name] < depths[p.Name] && tails[q.Name] > longest {
+					longest = tails[q.Name]
+				}
+			}
+		}
+		tails[p.Name] = p.Cycle + longest
+	}
+	return tails
+}
+
+// computeQuotas derives, for every process, how many instances should be started
+// per scheduling pass so that intermediate items are produced in the ratio their
+// consumers need them (a bill of materials explosion). Direct producers of the
+// targets get a quota of 1; a producer of an intermediate item gets enough runs to
+// cover one pass of its most demanding consumer. Unreachable processes get 1.
+//
+// Parameters:
+//   - processes: All available processes
+//   - depths: Dependency depths as returned by computeDepths
+//
+// Returns:
+//   - Map of process names to the number of instances to start per pass
+func computeQuotas(processes []*process.Process, depths map[string]int) map[string]int {
+	ordered := make([]*process.Process, len(processes))
+	copy(ordered, processes)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return depths[ordered[i].Name] < depths[ordered[j].Name]
+	})
+
+	consumers := processesByItem(ordered, func(p *process.Process) map[string]int { return p.Needs })
+	quotas := map[string]int{}
+	for _, p := range ordered {
+		quota := 1
+		if d, reachable := depths[p.Name]; reachable {
+			for item, produced := range p.Result {
+				if produced <= 0 {
+					continue
+				}
+				for _, q := range consumers[item] {
+					if qd, ok := depths[q.Name]; !ok || qd >= d {
+						continue
+					}
+					needed := q.Needs[item] * quotas[q.Name]
+					if runs := (needed + produced - 1) / produced; runs > quota {
+						quota = runs
+					}
+				}
+			}
+		}
+		quotas[p.Name] = quota
+	}
+	return quotas
+}
+
+// processesByItem maps every item to the processes whose needs or results,
+// as selected by field, mention it, keeping the order of processes.
+func processesByItem(processes []*process.Process, field func(*process.Process) map[string]int) map[string][]*process.Process {
+	index := map[string][]*process.Process{}
+	for _, p := range processes {
+		for item := range field(p) {
+			index[item] = append(index[item], p)
+		}
+	}
+	return index
+}
+
+// reachableProcesses returns the processes that contribute to the targets, i.e.
+// the ones with a depth, in their original order. If none do, all processes are
+// returned so that a schedule can still be built.
+func reachableProcesses(processes []*process.Process, depths map[string]int) []*process.Process {
+	if len(depths) == 0 {
+		return processes
+	}
+	reachable := []*process.Process{}
+	for _, p := range processes {
+		if _, ok := depths[p.Name]; ok {
+			reachable = append(reachable, p)
+		}
+	}
+	return reachable
+}
+
+// sortByPriority returns a copy of the processes ordered the way the greedy
+// scheduler considers them: by priority (descending) and then by name (descending).
+func sortByPriority(processes []*process.Process, priorities map[string]int) []*process.Process {
+	ordered := make([]*process.Process, len(processes))
+	copy(ordered, processes)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		pi, pj := priorities[ordered[i].Name], priorities[ordered[j].Name]
+		if pi == pj {
+			return ordered[i].Name > ordered[j].Name
+		}
+		return pi > pj
+	})
+	return ordered
+}
@@ -1,7 +1,11 @@
 package engine
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"maps"
+	"os"
 	"sort"
 	"time"
 
@@ -9,291 +13,268 @@ import (
 	"github.com/jesee-kuya/stock_exchange/util"
 )
 
-// runningProcess represents a process that is currently executing.
-// It tracks the process instance and the remaining delay until completion.
-type runningProcess struct {
-	Process *process.Process // The process being executed
-	Delay   int              // Remaining cycles until the process completes
-}
-
 // Run executes the stock exchange optimization algorithm for the specified duration.
-// It implements a priority-based parallel schedule generation scheme that:
-// 1. Schedules processes based on their priority (derived from optimization targets)
-// 2. Runs multiple processes concurrently when resources allow
-// 3. Respects process dependencies and resource constraints
-// 4. Stops when the time limit is reached or no more processes can be scheduled
+// It hands the engine's configuration to the selected Scheduler (the greedy
+// priority-based parallel scheme unless e.Scheduler is set), then records the
+// resulting schedule, final cycle, makespan and stock on the engine.
 //
 // Parameters:
-//   - waitingTime: Maximum execution time in string format (e.g., "10s", "5m")
+//   - waitingTime: Maximum execution time in seconds (e.g., "10", "0.5")
+//
+// The function prints the execution schedule and final stock state to e.Out.
+// The schedule is kept in e.Schedule, each entry completed with its instance number,
+// end cycle and the quantities it consumes and produces; it is only formatted as
+// "cycle:process_name" text when printed or written to the sink.
+// When e.Random is set, the outcome of every run that may fail or yield varying
+// quantities is drawn from it instead, the plan being carried out and re-planned after
+// shortfalls (see execute); the outcomes are kept in e.Outcomes, parallel to e.Schedule.
+// The deliveries of e.Deliveries are added to the stock at their cycle and the orders
+// of e.Orders shipped as soon as their items are in stock; what became of each order
+// is kept in e.OrderResults and printed with its lateness.
+// When e.Sink is set, each entry is also written to it as a "cycle:process_name"
+// line, followed by the footer. Schedulers implementing Streamer hand their
+// entries out as soon as they are decided, so long runs can be followed live;
+// the others are written once the plan is complete.
 //
-// The function prints the execution schedule and final stock state.
-// It generates a log file with the schedule in the format "cycle:process_name".
-func (e *Engine) Run(waitingTime string) {
+// Returns:
+//   - An error if the waiting time is invalid, ErrNothingRunnable if no process can
+//     start, the scheduler's error, or the first error writing to e.Sink. Hitting the
+//     time limit is not an error; it is recorded in e.TimedOut.
+func (e *Engine) Run(waitingTime string) error {
+	out := e.Out
+	if out == nil {
+		out = os.Stdout
+	}
 	maxSeconds, err := util.ParseDuration(waitingTime)
 	if err != nil {
-		fmt.Println("Invalid waiting time format:", err)
-		return
+		fmt.Fprintln(out, "Invalid waiting time format:", err)
+		return err
 	}
 
-	if !e.canRunAny() {
-		fmt.Println(" Missing processes\n Exiting... ")
-		return
+	scheduler := e.Scheduler
+	if scheduler == nil {
+		scheduler = greedyScheduler{}
 	}
 
-	start := time.Now()
-	e.Schedule = []string{}
-	e.Cycle = 0
-	running := []runningProcess{}
-
-	fmt.Println("Main Processes :")
-
-	targets := map[string]bool{}
-	for _, t := range e.OptimizeTargets {
-		if _, ok := e.Stock.Items[t]; ok {
-			targets[t] = true
-		}
+	config := &util.ConfigData{
+		Stocks:          e.Stock.Items,
+		Processes:       e.Processes,
+		OptimizeTargets: e.OptimizeTargets,
+		Capacities:      e.Capacities,
+		ShelfLife:       e.ShelfLife,
+		Deliveries:      e.Deliveries,
+		Orders:          e.Orders,
 	}
 
-	priorities := computePriorities(e.Processes, targets)
-	timeExceeded := false
-
-	for {
-		// Check time limit but don't break immediately if processes are still running
-		if time.Since(start).Seconds() >= float64(maxSeconds) {
-			timeExceeded = true
+	e.Schedule = []ScheduleEntry{}
+	var sinkErr error
+	describe := entryDescriber(e.Processes)
+	emit := func(entry ScheduleEntry) {
+		if len(e.Schedule) == 0 {
+			fmt.Fprintln(out, "Main Processes :")
 		}
+		e.Schedule = append(e.Schedule, describe(entry))
+		line := entry.String()
+		fmt.Fprintln(out, " "+line)
+		e.writeSink(&sinkErr, "%s\n", line)
+	}
 
-		running = updateRunningProcesses(running, e)
-
-		// Only schedule new processes if time hasn't exceeded
-		if !timeExceeded {
-			// Get all runnable processes for this cycle
-			runnable := []*process.Process{}
-			for _, p := range e.Processes {
-				if p.CanRun(e.Stock.Items) {
-					runnable = append(runnable, p)
-				}
-			}
-
-			// Sort by priority (descending) and then by name (descending)
-			sort.Slice(runnable, func(i, j int) bool {
-				pi, pj := priorities[runnable[i].Name], priorities[runnable[j].Name]
-				if pi == pj {
-					return runnable[i].Name > runnable[j].Name
-				}
-				return pi > pj
-			})
-
-			// Use a copy of stock for simulation
-			stockCopy := make(map[string]int)
-			for k, v := range e.Stock.Items {
-				stockCopy[k] = v
-			}
-
-			scheduledCount := make(map[*process.Process]int)
-			changed := true
-			for changed {
-				changed = false
-				for _, p := range runnable {
-					if p.CanRun(stockCopy) {
-						// Consume resources in the simulated stock
-						for item, qty := range p.Needs {
-							stockCopy[item] -= qty
-						}
-						scheduledCount[p]++
-						changed = true
-					}
-				}
-			}
-
-			// Schedule the processes and update real stock
-			scheduledEntries := []string{}
-			for _, p := range runnable {
-				count := scheduledCount[p]
-				for i := 0; i < count; i++ {
-					// Update real stock
-					for item, qty := range p.Needs {
-						e.Stock.Items[item] -= qty
-					}
-					// Add to running processes
-					running = append(running, runningProcess{
-						Process: p,
-						Delay:   p.Cycle,
-					})
-					// Create schedule entry
-					entry := fmt.Sprintf(" %d:%s", e.Cycle, p.Name)
-					scheduledEntries = append(scheduledEntries, entry)
-					e.Schedule = append(e.Schedule, entry)
-				}
-			}
-
-			// Print all entries for this cycle
-			for _, entry := range scheduledEntries {
-				fmt.Println(entry)
-			}
-
-			// Check if we can continue (only if time hasn't exceeded)
-			if len(running) == 0 && !e.canRunAny() {
-				fmt.Printf("No more process doable at cycle %d\n", e.Cycle+1)
-				break
+	budget := time.Duration(maxSeconds * float64(time.Second))
+	var plan *Plan
+	if e.Random != nil {
+		plan, err = e.execute(scheduler, config, budget, e.Random, emit)
+	} else if streamer, ok := scheduler.(Streamer); ok {
+		plan, err = streamer.ScheduleStream(config, budget, emit)
+	} else {
+		plan, err = scheduler.Schedule(config, budget)
+		if err == nil {
+			// The plan is complete, so there is nothing to follow live: buffer the lines
+			// instead of making a write per line past the budget
+			flush := e.bufferOutput(&out)
+			for _, entry := range plan.Entries {
+				emit(entry)
 			}
-		} else {
-			// Time exceeded, just let running processes complete
-			if len(running) == 0 {
-				break
+			if err := flush(); err != nil && sinkErr == nil {
+				sinkErr = err
 			}
 		}
+	}
+	if err == ErrNothingRunnable {
+		fmt.Fprintln(out, " Missing processes\n Exiting... ")
+		return err
+	}
+	if err != nil {
+		fmt.Fprintf(out, " Scheduler %s failed: %v\n Exiting... \n", scheduler.Name(), err)
+		return fmt.Errorf("scheduler %s: %w", scheduler.Name(), err)
+	}
 
-		e.Cycle++
+	if len(plan.Entries) == 0 {
+		fmt.Fprintln(out, "Main Processes :")
+	}
+	fmt.Fprintln(out, footer(plan.Cycle, plan.TimedOut))
+	e.writeSink(&sinkErr, "%s\n", footer(plan.Cycle, plan.TimedOut))
 
-		// If time exceeded and no processes are running, we can safely exit
-		if timeExceeded && len(running) == 0 {
-			fmt.Printf("Time limit exceeded after %d cycles\n", e.Cycle)
-			break
+	if e.Random != nil {
+		// The entries were described before their outcome was drawn
+		byName := make(map[string]*process.Process, len(e.Processes))
+		for _, p := range e.Processes {
+			byName[p.Name] = p
+		}
+		for i, outcome := range e.Outcomes {
+			e.Schedule[i].Produced = byName[e.Schedule[i].ProcessName].OutputOf(outcome)
 		}
 	}
 
-	printStock(e.Stock)
+	e.Cycle = plan.Cycle
+	e.TimedOut = plan.TimedOut
+	e.Makespan = plan.Makespan
+	e.Stock.Items = plan.Stock
+	e.Stock.Lots = plan.Lots
+	e.Expired = plan.Expired
+	e.OrderResults = plan.Orders
+	printStock(out, e.Stock)
+	printExpired(out, e.Expired)
+	printOrders(out, e.OrderResults)
+	if e.Random != nil {
+		printOutcomes(out, e.Outcomes, e.Replans)
+	}
+	if plan.Certificate != nil {
+		fmt.Fprintln(out, "Certificate:", plan.Certificate)
+	}
+	if sinkErr != nil {
+		return fmt.Errorf("writing log: %w", sinkErr)
+	}
+	return nil
 }
 
-// updateRunningProcesses decrements the delay of all running processes and
-// completes those whose delay has reached zero.
-// When a process completes, its results are added to the engine's stock.
-//
-// Parameters:
-//   - running: Slice of currently running processes
-//   - e: The engine instance to update stock when processes complete
-//
-// Returns:
-//   - Updated slice of running processes with completed ones removed
-func updateRunningProcesses(running []runningProcess, e *Engine) []runningProcess {
-	next := []runningProcess{}
-	for _, rp := range running {
-		rp.Delay--
-		if rp.Delay <= 0 {
-			for item, qty := range rp.Process.Result {
-				e.Stock.Items[item] += qty
-			}
-		} else {
-			next = append(next, rp)
+// bufferOutput buffers the writes to *out and to e.Sink until the returned function is
+// called, which flushes them and restores both writers. It returns the first error
+// flushing e.Sink; errors writing *out are ignored, as for the other printed lines.
+func (e *Engine) bufferOutput(out *io.Writer) func() error {
+	origOut, origSink := *out, e.Sink
+	bufOut := bufio.NewWriter(origOut)
+	*out = bufOut
+	var bufSink *bufio.Writer
+	if origSink != nil {
+		bufSink = bufio.NewWriter(origSink)
+		e.Sink = bufSink
+	}
+	return func() error {
+		bufOut.Flush()
+		*out, e.Sink = origOut, origSink
+		if bufSink != nil {
+			return bufSink.Flush()
 		}
+		return nil
 	}
-	return next
 }
 
-// canRunAny checks if any process in the engine can be executed
-// with the current stock levels.
-//
-// Returns:
-//   - true if at least one process can run, false otherwise
-func (e *Engine) canRunAny() bool {
-	for _, p := range e.Processes {
-		if p.CanRun(e.Stock.Items) {
-			return true
-		}
+// writeSink writes a formatted line to e.Sink, if any, unless an earlier write failed.
+// The first failure is kept in *errp.
+func (e *Engine) writeSink(errp *error, format string, args ...any) {
+	if e.Sink == nil || *errp != nil {
+		return
 	}
-	return false
+	_, *errp = fmt.Fprintf(e.Sink, format, args...)
 }
 
-// computePriorities calculates priority values for all processes based on
-// their relationship to optimization targets using a breadth-first search approach.
-// Processes that directly produce optimization targets get priority 0,
-// processes that produce inputs for those get priority 1, and so on.
-// This implements a backward chaining algorithm to establish process dependencies.
-//
-// Priority calculation logic:
-// - Direct producers of optimization targets: priority 0 (highest)
-// - Processes that support direct producers: priority 1
-// - Processes that support priority 1 processes: priority 2
-// - And so on...
-// - Unreachable processes get the lowest priority (max + 1)
+// printStock displays the final state of all stock items in alphabetical order.
+// This provides a clear summary of remaining resources after process execution.
 //
 // Parameters:
-//   - processes: All available processes
-//   - targets: Map of optimization target items
-//
-// Returns:
-//   - Map of process names to their priority values (lower = higher priority)
-func computePriorities(processes []*process.Process, targets map[string]bool) map[string]int {
-	prio := map[string]int{}
-	visited := map[string]bool{}
-	queue := []struct {
-		Name  string
-		Depth int
-	}{}
+//   - w: The destination of the listing
+//   - stock: The stock instance containing all items and their quantities
+func printStock(w io.Writer, stock *Stock) {
+	fmt.Fprintln(w, "Stock:")
+	keys := make([]string, 0, len(stock.Items))
+	for k := range stock.Items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, " %s => %d\n", k, stock.Items[k])
+	}
+}
 
-	// Initialize with processes that directly produce optimization targets
-	for _, p := range processes {
-		for result := range p.Result {
-			if targets[result] {
-				prio[p.Name] = 0
-				queue = append(queue, struct {
-					Name  string
-					Depth int
-				}{p.Name, 0})
-			}
+// printExpired lists the quantities of the perishable items lost to expiry, in
+// alphabetical order, and prints nothing when none expired.
+func printExpired(w io.Writer, expired map[string]int) {
+	keys := make([]string, 0, len(expired))
+	for k, qty := range expired {
+		if qty > 0 {
+			keys = append(keys, k)
 		}
 	}
+	if len(keys) == 0 {
+		return
+	}
+	sort.Strings(keys)
+	fmt.Fprintln(w, "Expired:")
+	for _, k := range keys {
+		fmt.Fprintf(w, " %s => %d\n", k, expired[k])
+	}
+}
 
-	// Breadth-first search to assign priorities based on dependency depth
-	for len(queue) > 0 {
-		curr := queue[0]
-		queue = queue[1:]
-		visited[curr.Name] = true
-
-		var proc *process.Process
-		for _, p := range processes {
-			if p.Name == curr.Name {
-				proc = p
-				break
-			}
+// printOrders lists what became of each customer order, with its lateness, and the total
+// penalty of the late ones. It prints nothing when there are no orders.
+func printOrders(w io.Writer, orders []OrderResult) {
+	if len(orders) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Orders:")
+	penalty, late, unfilled := 0, 0, 0
+	for _, r := range orders {
+		fmt.Fprintf(w, " %s\n", r)
+		penalty += r.Cost()
+		if r.Late() > 0 {
+			late++
 		}
-
-		// Find processes that produce what this process needs
-		for need := range proc.Needs {
-			for _, p := range processes {
-				if _, ok := p.Result[need]; ok {
-					if prio[p.Name] < curr.Depth+1 {
-						prio[p.Name] = curr.Depth + 1
-						queue = append(queue, struct {
-							Name  string
-							Depth int
-						}{p.Name, curr.Depth + 1})
-					}
-				}
-			}
+		if !r.Filled() {
+			unfilled++
 		}
 	}
+	fmt.Fprintf(w, " %d late, %d unfilled, total penalty %d\n", late, unfilled, penalty)
+}
 
-	// Assign fallback priority for processes not reachable from targets
-	max := 0
-	for _, v := range prio {
-		if v > max {
-			max = v
-		}
-	}
-	for _, p := range processes {
-		if _, ok := prio[p.Name]; !ok {
-			prio[p.Name] = max + 1
+// printOutcomes summarizes the drawn outcomes: how many runs failed and how many times
+// the engine planned again.
+func printOutcomes(w io.Writer, outcomes []process.Outcome, replans int) {
+	failed := 0
+	for _, o := range outcomes {
+		if o.Failed {
+			failed++
 		}
 	}
+	fmt.Fprintf(w, "Outcomes: %d of %d runs failed, %d new plans\n", failed, len(outcomes), replans)
+}
 
-	return prio
+// footer returns the line closing a schedule: the cycle at which the run stopped and why.
+func footer(cycle int, timedOut bool) string {
+	if timedOut {
+		return fmt.Sprintf("Time limit exceeded after %d cycles", cycle)
+	}
+	return fmt.Sprintf("No more process doable at cycle %d", cycle)
 }
 
-// printStock displays the final state of all stock items in alphabetical order.
-// This provides a clear summary of remaining resources after process execution.
-//
-// Parameters:
-//   - stock: The stock instance containing all items and their quantities
-func printStock(stock *Stock) {
-	fmt.Println("Stock:")
-	keys := make([]string, 0, len(stock.Items))
-	for k := range stock.Items {
-		keys = append(keys, k)
+// entryDescriber returns a function completing schedule entries with the details of
+// their run: the instance number among the runs of the same process, the end cycle and
+// copies of the quantities consumed and produced, the latter including the renewable
+// resources given back. Entries must be given in schedule order.
+func entryDescriber(processes []*process.Process) func(ScheduleEntry) ScheduleEntry {
+	byName := make(map[string]*process.Process, len(processes))
+	for _, p := range processes {
+		byName[p.Name] = p
 	}
-	sort.Strings(keys)
-	for _, k := range keys {
-		fmt.Printf(" %s => %d\n", k, stock.Items[k])
+	instances := map[string]int{}
+	return func(entry ScheduleEntry) ScheduleEntry {
+		instances[entry.ProcessName]++
+		entry.Instance = instances[entry.ProcessName]
+		entry.End = entry.Cycle
+		if p, ok := byName[entry.ProcessName]; ok {
+			entry.End += p.Cycle
+			entry.Consumed = maps.Clone(p.Needs)
+			entry.Produced = maps.Clone(p.Output())
+		}
+		return entry
 	}
 }
@@ -0,0 +1,320 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/jesee-kuya/stock_exchange/process"
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// cabinetProcesses returns the process definitions of the cabinet example from the README.
+func cabinetProcesses() []*process.Process {
+	return []*process.Process{
+		{Name: "do_doorknobs", Needs: map[string]int{"board": 1}, Result: map[string]int{"doorknobs": 1}, Cycle: 15},
+		{Name: "do_background", Needs: map[string]int{"board": 2}, Result: map[string]int{"background": 1}, Cycle: 20},
+		{Name: "do_shelf", Needs: map[string]int{"board": 1}, Result: map[string]int{"shelf": 1}, Cycle: 10},
+		{Name: "do_cabinet", Needs: map[string]int{"doorknobs": 2, "background": 1, "shelf": 3}, Result: map[string]int{"cabinet": 1}, Cycle: 30},
+	}
+}
+
+// newCabinetEngine returns an engine loaded with the cabinet example and the given optimize targets.
+func newCabinetEngine(targets ...string) *Engine {
+	e := NewEngine()
+	e.Stock.Items["board"] = 7
+	e.Processes = cabinetProcesses()
+	e.OptimizeTargets = targets
+	return e
+}
+
+// TestNewObjective tests that the "time" keyword is recognized and that item targets
+// are kept when they are produced even if they are absent from the initial stock.
+func TestNewObjective(t *testing.T) {
+	testCases := []struct {
+		name     string
+		targets  []string
+		wantTime bool
+		wantItem string
+	}{
+		{"time and produced item", []string{"time", "cabinet"}, true, "cabinet"},
+		{"time only falls back to terminal products", []string{"time"}, true, "cabinet"},
+		{"stock item", []string{"board"}, false, "board"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			obj := NewObjective(tc.targets, cabinetProcesses(), map[string]int{"board": 7})
+			if obj.Time != tc.wantTime {
+				t.Errorf("Time = %v, want %v", obj.Time, tc.wantTime)
+			}
+			if !obj.Items[tc.wantItem] {
+				t.Errorf("Items = %v, want %q included", obj.Items, tc.wantItem)
+			}
+			if obj.Items[timeTarget] {
+				t.Errorf("Items should not contain %q", timeTarget)
+			}
+		})
+	}
+}
+
+// TestComputePriorities tests that time optimization ranks processes by critical path
+// and that the quotas follow the cabinet bill of materials.
+func TestComputePriorities(t *testing.T) {
+	processes := cabinetProcesses()
+	obj := NewObjective([]string{"time", "cabinet"}, processes, map[string]int{"board": 7})
+
+	prio := computePriorities(processes, obj)
+	if !(prio["do_background"] > prio["do_doorknobs"] && prio["do_doorknobs"] > prio["do_shelf"] && prio["do_shelf"] > prio["do_cabinet"]) {
+		t.Errorf("unexpected priority order: %v", prio)
+	}
+
+	quotas := computeQuotas(processes, computeDepths(processes, obj.Items))
+	want := map[string]int{"do_cabinet": 1, "do_background": 1, "do_doorknobs": 2, "do_shelf": 3}
+	for name, q := range want {
+		if quotas[name] != q {
+			t.Errorf("quota of %s = %d, want %d", name, quotas[name], q)
+		}
+	}
+}
+
+// TestRunMakespan tests that Run records the cycle at which the last cabinet is produced.
+func TestRunMakespan(t *testing.T) {
+	e := newCabinetEngine("time", "cabinet")
+	e.Run("1")
+
+	if e.Stock.Items["cabinet"] != 1 {
+		t.Fatalf("expected 1 cabinet, got %d", e.Stock.Items["cabinet"])
+	}
+	if e.Makespan != 50 {
+		t.Errorf("Makespan = %d, want 50", e.Makespan)
+	}
+}
+
+// TestRunLongProcesses tests that cycles without events are skipped, so processes
+// lasting billions of cycles are scheduled well within the time limit.
+func TestRunLongProcesses(t *testing.T) {
+	e := NewEngine()
+	e.Stock.Items["ore"] = 2
+	e.Processes = []*process.Process{
+		{Name: "smelt", Needs: map[string]int{"ore": 1}, Result: map[string]int{"iron": 1}, Cycle: 1_000_000_000},
+		{Name: "forge", Needs: map[string]int{"iron": 2}, Result: map[string]int{"sword": 1}, Cycle: 500_000_000},
+	}
+	e.OptimizeTargets = []string{"sword"}
+	e.Run("1")
+
+	if e.Stock.Items["sword"] != 1 {
+		t.Fatalf("expected 1 sword, got %d", e.Stock.Items["sword"])
+	}
+	if e.Cycle != 1_500_000_001 {
+		t.Errorf("Cycle = %d, want 1500000001", e.Cycle)
+	}
+	if len(e.Schedule) != 3 || e.Schedule[2].String() != "1000000000:forge" {
+		t.Errorf("unexpected schedule %v", e.Schedule)
+	}
+}
+
+// TestRunScheduleEntries tests that Run records each run with its instance number,
+// end cycle and the quantities it consumes and produces.
+func TestRunScheduleEntries(t *testing.T) {
+	e := newCabinetEngine("time", "cabinet")
+	e.Out = io.Discard
+	if err := e.Run("1"); err != nil {
+		t.Fatal(err)
+	}
+
+	instances := map[string]int{}
+	for _, entry := range e.Schedule {
+		instances[entry.ProcessName]++
+		if entry.Instance != instances[entry.ProcessName] {
+			t.Errorf("%v: Instance = %d, want %d", entry, entry.Instance, instances[entry.ProcessName])
+		}
+	}
+	last := e.Schedule[len(e.Schedule)-1]
+	if last.ProcessName != "do_cabinet" || last.Cycle != 20 || last.End != 50 {
+		t.Errorf("last entry = %+v, want do_cabinet from 20 to 50", last)
+	}
+	if last.Consumed["shelf"] != 3 || last.Produced["cabinet"] != 1 {
+		t.Errorf("last entry quantities = %v -> %v", last.Consumed, last.Produced)
+	}
+}
+
+// TestRunRenewable tests that a renewable resource limits the number of concurrent runs
+// to its capacity and is given back once they complete.
+func TestRunRenewable(t *testing.T) {
+	e := NewEngine()
+	e.Out = io.Discard
+	e.Stock.Items = map[string]int{"flour": 4, "oven": 2}
+	e.Processes = []*process.Process{{
+		Name:   "bake",
+		Needs:  map[string]int{"flour": 1, "oven": 1},
+		Result: map[string]int{"bread": 1},
+		Cycle:  5,
+		Holds:  map[string]int{"oven": 1},
+	}}
+	e.OptimizeTargets = []string{"time", "bread"}
+	if err := e.Run("1"); err != nil {
+		t.Fatal(err)
+	}
+
+	starts := []int{}
+	for _, entry := range e.Schedule {
+		starts = append(starts, entry.Cycle)
+	}
+	if fmt.Sprint(starts) != "[0 0 5 5]" {
+		t.Errorf("start cycles = %v, want two ovens used twice", starts)
+	}
+	if e.Stock.Items["bread"] != 4 || e.Stock.Items["oven"] != 2 || e.Makespan != 10 {
+		t.Errorf("stock = %v, makespan = %d, want 4 bread, both ovens back and makespan 10", e.Stock.Items, e.Makespan)
+	}
+}
+
+// TestRunCapacity tests that a producer waits for a consumer to make room in storage,
+// counting the production still in flight.
+func TestRunCapacity(t *testing.T) {
+	e := NewEngine()
+	e.Out = io.Discard
+	e.Stock.Items = map[string]int{"flour": 3, "bread": 0}
+	e.Capacities = map[string]int{"bread": 1}
+	e.Processes = []*process.Process{
+		{Name: "bake", Needs: map[string]int{"flour": 1}, Result: map[string]int{"bread": 1}, Cycle: 5},
+		{Name: "sell", Needs: map[string]int{"bread": 1}, Result: map[string]int{"euro": 2}, Cycle: 1},
+	}
+	e.OptimizeTargets = []string{"euro"}
+	if err := e.Run("1"); err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, entry := range e.Schedule {
+		got = append(got, entry.String())
+	}
+	if fmt.Sprint(got) != "[0:bake 5:sell 5:bake 10:sell 10:bake 15:sell]" {
+		t.Errorf("schedule = %v, want each bread sold as the next one goes in the oven", got)
+	}
+	if e.Stock.Items["euro"] != 6 {
+		t.Errorf("euro = %d, want 6", e.Stock.Items["euro"])
+	}
+}
+
+// TestRunCapacityOrder tests that the entries of a cycle come in an order they can start
+// in under storage capacities, by replaying the log one entry at a time, each one only
+// starting once its needs are in stock and its results fit.
+func TestRunCapacityOrder(t *testing.T) {
+	e := NewEngine()
+	e.Out = io.Discard
+	e.Stock.Items = map[string]int{"flour": 10, "bread": 0, "oven": 2}
+	e.Capacities = map[string]int{"bread": 6}
+	e.Processes = []*process.Process{
+		{Name: "bake", Needs: map[string]int{"flour": 1, "oven": 1}, Result: map[string]int{"bread": 3}, Cycle: 5,
+			Holds: map[string]int{"oven": 1}, Yields: map[string]process.Yield{"bread": {Min: 3, Max: 3}}},
+		{Name: "sell", Needs: map[string]int{"bread": 3}, Result: map[string]int{"euro": 10}, Cycle: 1},
+	}
+	e.OptimizeTargets = []string{"euro"}
+	if err := e.Run("1"); err != nil {
+		t.Fatal(err)
+	}
+
+	byName := map[string]*process.Process{}
+	for _, p := range e.Processes {
+		byName[p.Name] = p
+	}
+	stock := map[string]int{"flour": 10, "bread": 0, "oven": 2}
+	incoming := map[string]int{}
+	type run struct {
+		p   *process.Process
+		end int
+	}
+	running := []run{}
+	for i, entry := range e.Schedule {
+		remaining := running[:0]
+		for _, r := range running {
+			if r.end > entry.Cycle {
+				remaining = append(remaining, r)
+				continue
+			}
+			for item, qty := range r.p.Output() {
+				stock[item] += qty
+			}
+			for item, qty := range r.p.MostResult() {
+				incoming[item] -= qty
+			}
+		}
+		running = remaining
+
+		p := byName[entry.ProcessName]
+		if !p.CanRun(stock) || !p.Fits(stock, incoming, e.Capacities) {
+			t.Fatalf("entry %d %s cannot start in order (stock %v, incoming %v); schedule %v", i, entry, stock, incoming, e.Schedule)
+		}
+		for item, qty := range p.Needs {
+			stock[item] -= qty
+		}
+		for item, qty := range p.MostResult() {
+			incoming[item] += qty
+		}
+		running = append(running, run{p: p, end: entry.Cycle + p.Cycle})
+	}
+	if e.Stock.Items["euro"] != 100 {
+		t.Errorf("euro = %d, want 100", e.Stock.Items["euro"])
+	}
+}
+
+// TestRunPerishable tests that perishable units expire after their shelf life and that
+// a perishable intermediate is made on demand, so that none of it spoils.
+func TestRunPerishable(t *testing.T) {
+	e := NewEngine()
+	e.Out = io.Discard
+	e.Stock.Items = map[string]int{"flour": 4, "oven": 1, "yeast": 3}
+	e.ShelfLife = map[string]int{"dough": 6, "yeast": 4}
+	e.Processes = []*process.Process{
+		{Name: "knead", Needs: map[string]int{"flour": 1}, Result: map[string]int{"dough": 1}, Cycle: 2},
+		{Name: "bake", Needs: map[string]int{"dough": 1, "oven": 1}, Result: map[string]int{"bread": 1}, Cycle: 5, Holds: map[string]int{"oven": 1}},
+	}
+	e.OptimizeTargets = []string{"bread"}
+	if err := e.Run("1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if e.Stock.Items["bread"] != 4 || e.Expired["dough"] != 0 {
+		t.Errorf("bread = %d, expired = %v, want 4 bread and no dough lost", e.Stock.Items["bread"], e.Expired)
+	}
+	if e.Stock.Items["yeast"] != 0 || e.Expired["yeast"] != 3 {
+		t.Errorf("yeast = %d, expired = %v, want the unused yeast expired", e.Stock.Items["yeast"], e.Expired)
+	}
+}
+
+// TestRunEvents tests that every scheduler waits for a delivery to run and reports the
+// lateness of the orders.
+func TestRunEvents(t *testing.T) {
+	for _, name := range []string{DefaultScheduler, "beam", "exact", "genetic"} {
+		t.Run(name, func(t *testing.T) {
+			scheduler, err := LookupScheduler(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			e := NewEngine()
+			e.Out = io.Discard
+			e.Scheduler = scheduler
+			e.Stock.Items = map[string]int{"flour": 1}
+			e.Processes = []*process.Process{
+				{Name: "bake", Needs: map[string]int{"flour": 1}, Result: map[string]int{"bread": 1}, Cycle: 5},
+			}
+			e.OptimizeTargets = []string{"bread"}
+			e.Deliveries = []util.Delivery{{Cycle: 20, Items: map[string]int{"flour": 2}}}
+			e.Orders = []util.Order{
+				{Due: 10, Items: map[string]int{"bread": 1}, Penalty: 1},
+				{Due: 15, Items: map[string]int{"bread": 2}, Penalty: 3},
+			}
+			if err := e.Run("0.2"); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(e.Schedule) != 3 || e.Schedule[1].Cycle != 20 || e.Cycle != 26 {
+				t.Errorf("Schedule = %v ending at %d, want the last two runs at cycle 20 and the end at 26", e.Schedule, e.Cycle)
+			}
+			if got := e.OrderResults; len(got) != 2 || got[0].Shipped != 5 || got[1].Shipped != 25 || got[1].Cost() != 30 {
+				t.Errorf("OrderResults = %v, want the first order on time and the second 10 cycles late", got)
+			}
+		})
+	}
+}
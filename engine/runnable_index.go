@@ -0,0 +1,335 @@
+package engine
+
+import (
+	"container/heap"
+	"sort"
+
+	"github.com/jesee-kuya/stock_exchange/process"
+)
+
+// need is one required item of a process, identified by its rank in the index.
+type need struct {
+	Rank int
+	Qty  int
+}
+
+// runnableIndex keeps the set of processes that can run up to date as a stock
+// changes. Each item maps to the processes needing it, sorted by the quantity
+// they need, and each process counts its unmet needs, so a stock change only
+// touches the consumers of that item whose need it crosses instead of rescanning
+// every process.
+//
+// Processes are identified by their rank, their position in the priority order
+// the index was built with. Only eligible processes are reported as runnable,
+// which lets the engine defer processes that do not contribute to the targets.
+//
+// Storage capacities are not indexed: a process whose needs are met is only
+// started if its results fit (see process.Fits), which is checked on demand
+// against the largest results of the running processes kept in incoming. The producers
+// of each item are indexed instead, so that startPasses can revisit them when
+// the item is consumed.
+//
+// The lots of perishable items follow the stock: starts take from the oldest lots,
+// completions add a lot, and expire removes the lots whose shelf life has elapsed.
+type runnableIndex struct {
+	processes  []*process.Process
+	stock      map[string]int
+	consumers  map[string][]need
+	producers  map[string][]int
+	missing    []int
+	eligible   []bool
+	runnable   map[int]struct{}
+	incoming   map[string]int
+	capacities map[string]int
+	lots       *Lots
+	expired    map[string]int
+}
+
+// newRunnableIndex builds the index for processes given in priority order over
+// the stock, which the index modifies in place from then on.
+//
+// Parameters:
+//   - ordered: All processes, highest priority first
+//   - stock: The stock to track
+//   - eligible: The processes reported as runnable; the others are tracked but hidden
+//
+// Returns:
+//   - The index, with the runnable set computed for the current stock
+func newRunnableIndex(ordered []*process.Process, stock map[string]int, eligible []*process.Process) *runnableIndex {
+	ix := &runnableIndex{
+		processes: ordered,
+		stock:     stock,
+		consumers: map[string][]need{},
+		producers: map[string][]int{},
+		missing:   make([]int, len(ordered)),
+		eligible:  make([]bool, len(ordered)),
+		runnable:  map[int]struct{}{},
+		incoming:  map[string]int{},
+		lots:      NewLots(nil, stock),
+		expired:   map[string]int{},
+	}
+
+	isEligible := make(map[*process.Process]bool, len(eligible))
+	for _, p := range eligible {
+		isEligible[p] = true
+	}
+	for rank, p := range ordered {
+		ix.eligible[rank] = isEligible[p]
+		for item, qty := range p.Needs {
+			ix.consumers[item] = append(ix.consumers[item], need{Rank: rank, Qty: qty})
+			if stock[item] < qty {
+				ix.missing[rank]++
+			}
+		}
+		for item := range p.Result {
+			ix.producers[item] = append(ix.producers[item], rank)
+		}
+		if ix.missing[rank] == 0 {
+			ix.runnable[rank] = struct{}{}
+		}
+	}
+	for _, needs := range ix.consumers {
+		sort.SliceStable(needs, func(i, j int) bool { return needs[i].Qty < needs[j].Qty })
+	}
+	return ix
+}
+
+// adjust changes the quantity of an item by delta and updates the processes needing it.
+func (ix *runnableIndex) adjust(item string, delta int) {
+	before := ix.stock[item]
+	after := before + delta
+	ix.stock[item] = after
+
+	// Only the needs between the two quantities change from met to unmet or back
+	needs := ix.consumers[item]
+	low, high := min(before, after), max(before, after)
+	from := sort.Search(len(needs), func(i int) bool { return needs[i].Qty > low })
+	for _, n := range needs[from:] {
+		if n.Qty > high {
+			break
+		}
+		if delta < 0 {
+			ix.missing[n.Rank]++
+			if ix.missing[n.Rank] == 1 {
+				delete(ix.runnable, n.Rank)
+			}
+		} else {
+			ix.missing[n.Rank]--
+			if ix.missing[n.Rank] == 0 {
+				ix.runnable[n.Rank] = struct{}{}
+			}
+		}
+	}
+}
+
+// canRun reports whether the process with the given rank can run with the current
+// stock and whether its results fit in storage.
+func (ix *runnableIndex) canRun(rank int) bool {
+	if ix.missing[rank] != 0 {
+		return false
+	}
+	return ix.processes[rank].Fits(ix.stock, ix.incoming, ix.capacities)
+}
+
+// start consumes the needs of the process with the given rank.
+func (ix *runnableIndex) start(rank int) {
+	p := ix.processes[rank]
+	for item, qty := range p.Needs {
+		ix.adjust(item, -qty)
+		ix.lots.Take(item, qty)
+	}
+	for item, qty := range p.MostResult() {
+		ix.incoming[item] += qty
+	}
+}
+
+// complete adds the results of a process completing at the given cycle, and the
+// resources it held, to the stock.
+func (ix *runnableIndex) complete(p *process.Process, cycle int) {
+	for item, qty := range p.Output() {
+		ix.adjust(item, qty)
+		ix.lots.Add(item, qty, cycle)
+	}
+	for item, qty := range p.MostResult() {
+		ix.incoming[item] -= qty
+	}
+}
+
+// receive changes the stock of an item by a timeline event at the given cycle: a delivery
+// adds qty units as a new lot, a shipment takes -qty units from the oldest lots.
+func (ix *runnableIndex) receive(item string, qty, cycle int) {
+	ix.adjust(item, qty)
+	if qty > 0 {
+		ix.lots.Add(item, qty, cycle)
+	} else {
+		ix.lots.Take(item, -qty)
+	}
+}
+
+// startPasses starts the processes with the given ranks the way the greedy scheme does:
+// pass after pass over the ranks in the given order, each process up to its quota per
+// pass, until none can start anymore.
+//
+// Rather than rescanning every rank, each pass is a worklist of the processes that can
+// still start: the ones that used up their quota in the previous pass, and the producers
+// of the items consumed since they were last visited, which may fit in storage again or
+// no longer be a surplus. Within a cycle the stock only goes down, so no other process
+// can become startable. A producer woken by a process of lower priority is visited later
+// in the same pass, as a full rescan would, and in the next pass otherwise.
+//
+// Parameters:
+//   - ranks: The runnable ranks, in the order they are visited in each pass
+//   - quota: The number of starts of a process allowed in one pass
+//   - onDemand: Whether the processes that would make a surplus of perishable units
+//     (see surplus) are left out
+//
+// Returns:
+//   - The rank of every start, in the order the starts were made. Each start was feasible
+//     given the ones before it, so replaying them in this order starts them all.
+func (ix *runnableIndex) startPasses(ranks []int, quota func(rank int) int, onDemand bool) []int {
+	position := make(map[int]int, len(ranks))
+	for i, rank := range ranks {
+		position[rank] = i
+	}
+
+	// A sorted slice is already a heap
+	pass := &positionQueue{IntSlice: make([]int, len(ranks))}
+	queued := make([]bool, len(ranks))
+	for i := range ranks {
+		pass.IntSlice[i] = i
+		queued[i] = true
+	}
+	next := &positionQueue{}
+	queuedNext := make([]bool, len(ranks))
+	enqueue := func(q *positionQueue, inQueue []bool, pos int) {
+		if !inQueue[pos] {
+			inQueue[pos] = true
+			heap.Push(q, pos)
+		}
+	}
+
+	started := []int{}
+	for pass.Len() > 0 {
+		for pass.Len() > 0 {
+			pos := heap.Pop(pass).(int)
+			queued[pos] = false
+			rank := ranks[pos]
+			if onDemand && ix.surplus(rank) {
+				continue
+			}
+
+			n := 0
+			for ; n < quota(rank) && ix.canRun(rank); n++ {
+				ix.start(rank)
+				started = append(started, rank)
+				for item := range ix.processes[rank].Needs {
+					for _, producer := range ix.producers[item] {
+						at, ok := position[producer]
+						switch {
+						case !ok:
+						case at > pos:
+							enqueue(pass, queued, at)
+						default:
+							enqueue(next, queuedNext, at)
+						}
+					}
+				}
+			}
+			if n > 0 && ix.canRun(rank) {
+				enqueue(next, queuedNext, pos)
+			}
+		}
+		pass, next = next, pass
+		queued, queuedNext = queuedNext, queued
+	}
+	return started
+}
+
+// positionQueue is a min-heap of positions in the rank order of a startPasses call.
+type positionQueue struct {
+	sort.IntSlice
+}
+
+// Push adds a position; use heap.Push rather than calling it directly.
+func (q *positionQueue) Push(x any) { q.IntSlice = append(q.IntSlice, x.(int)) }
+
+// Pop removes the last position; use heap.Pop rather than calling it directly.
+func (q *positionQueue) Pop() any {
+	old := q.IntSlice
+	pos := old[len(old)-1]
+	q.IntSlice = old[:len(old)-1]
+	return pos
+}
+
+// surplus reports whether the process with the given rank would only produce perishable
+// units nobody is waiting for: every item it produces is perishable, and the stock plus
+// the units on their way already cover one run of every process consuming it. Making
+// more then risks seeing the units expire before they are used.
+func (ix *runnableIndex) surplus(rank int) bool {
+	p := ix.processes[rank]
+	if len(p.Result) == 0 {
+		return false
+	}
+	for item := range p.Result {
+		if !ix.lots.Perishable(item) {
+			return false
+		}
+		demand := 0
+		for _, n := range ix.consumers[item] {
+			demand += n.Qty
+		}
+		if ix.stock[item]+ix.incoming[item] < demand {
+			return false
+		}
+	}
+	return true
+}
+
+// expire removes from the stock the perishable units whose shelf life has elapsed at the
+// given cycle, and adds them to the expired quantities.
+func (ix *runnableIndex) expire(cycle int) {
+	for item, qty := range ix.lots.Expire(cycle) {
+		ix.adjust(item, -qty)
+		ix.expired[item] += qty
+	}
+}
+
+// ranks returns the ranks of the eligible runnable processes, highest priority first,
+// except that the consumers of perishable units come first (see preferPerishable).
+func (ix *runnableIndex) ranks() []int {
+	ranks := make([]int, 0, len(ix.runnable))
+	for rank := range ix.runnable {
+		if ix.eligible[rank] {
+			ranks = append(ranks, rank)
+		}
+	}
+	sort.Ints(ranks)
+	if len(ix.lots.Items) > 0 {
+		sort.SliceStable(ranks, func(i, j int) bool {
+			return urgency(ix.processes[ranks[i]], ix.lots) < urgency(ix.processes[ranks[j]], ix.lots)
+		})
+	}
+	return ranks
+}
+
+// any reports whether at least one eligible process can run.
+func (ix *runnableIndex) any() bool {
+	for rank := range ix.runnable {
+		if ix.eligible[rank] && ix.canRun(rank) {
+			return true
+		}
+	}
+	return false
+}
+
+// includeAll makes every process eligible and reports whether any was not before.
+func (ix *runnableIndex) includeAll() bool {
+	changed := false
+	for rank, ok := range ix.eligible {
+		if !ok {
+			ix.eligible[rank] = true
+			changed = true
+		}
+	}
+	return changed
+}
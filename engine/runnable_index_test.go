@@ -0,0 +1,215 @@
+package engine
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/jesee-kuya/stock_exchange/process"
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// TestRunnableIndex tests that the incrementally maintained runnable set always
+// matches a full scan with Process.CanRun while random stock changes are applied.
+func TestRunnableIndex(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	items := []string{"a", "b", "c", "d"}
+
+	processes := []*process.Process{}
+	for i := 0; i < 20; i++ {
+		needs := map[string]int{}
+		for _, item := range items {
+			if rng.Intn(2) == 0 {
+				needs[item] = 1 + rng.Intn(3)
+			}
+		}
+		processes = append(processes, &process.Process{Name: fmt.Sprintf("p%d", i), Needs: needs, Cycle: 1})
+	}
+
+	stock := map[string]int{"a": 2, "b": 0, "c": 5, "d": 1}
+	index := newRunnableIndex(processes, stock, processes)
+
+	for step := 0; step < 500; step++ {
+		index.adjust(items[rng.Intn(len(items))], rng.Intn(5)-2)
+
+		want := []int{}
+		for rank, p := range processes {
+			if p.CanRun(stock) {
+				want = append(want, rank)
+			}
+		}
+		got := index.ranks()
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("step %d: runnable ranks %v, want %v (stock %v)", step, got, want, stock)
+		}
+	}
+}
+
+// TestStartPasses tests that the worklist of startPasses starts the same processes as
+// full passes over the runnable ranks, storage capacities letting consumers make room for
+// producers within a cycle.
+func TestStartPasses(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	items := []string{"a", "b", "c", "d", "e"}
+
+	for round := 0; round < 200; round++ {
+		processes := []*process.Process{}
+		for i := 0; i < 8; i++ {
+			p := &process.Process{Name: fmt.Sprintf("p%d", i), Needs: map[string]int{}, Result: map[string]int{}, Cycle: 1}
+			for _, item := range items {
+				switch rng.Intn(4) {
+				case 0:
+					p.Needs[item] = 1 + rng.Intn(2)
+				case 1:
+					p.Result[item] = 1 + rng.Intn(2)
+				}
+			}
+			if len(p.Needs) == 0 {
+				p.Needs[items[0]] = 1
+			}
+			processes = append(processes, p)
+		}
+		stock := map[string]int{}
+		capacities := map[string]int{}
+		for _, item := range items {
+			stock[item] = rng.Intn(6)
+			if rng.Intn(2) == 0 {
+				capacities[item] = stock[item] + rng.Intn(3)
+			}
+		}
+		quota := func(rank int) int { return 1 + rank%2 }
+
+		want := []int{}
+		full := newRunnableIndex(processes, cloneStock(stock), processes)
+		full.capacities = capacities
+		ranks := full.ranks()
+		for changed := true; changed; {
+			changed = false
+			for _, rank := range ranks {
+				for n := 0; n < quota(rank) && full.canRun(rank); n++ {
+					full.start(rank)
+					want = append(want, rank)
+					changed = true
+				}
+			}
+		}
+
+		index := newRunnableIndex(processes, cloneStock(stock), processes)
+		index.capacities = capacities
+		got := index.startPasses(index.ranks(), quota, false)
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("round %d: starts %v, want %v (stock %v, capacities %v)", round, got, want, stock, capacities)
+		}
+	}
+}
+
+// chainConfig returns a configuration of n processes, each turning one unit of the
+// previous part into one unit of the next, so only a few processes are runnable at
+// any time while every cycle used to rescan all of them.
+func chainConfig(n int) *util.ConfigData {
+	processes := make([]*process.Process, n)
+	for i := range processes {
+		processes[i] = &process.Process{
+			Name:   fmt.Sprintf("step_%d", i),
+			Needs:  map[string]int{fmt.Sprintf("part_%d", i): 1},
+			Result: map[string]int{fmt.Sprintf("part_%d", i+1): 1},
+			Cycle:  1 + i%5,
+		}
+	}
+	return &util.ConfigData{
+		Stocks:          map[string]int{"part_0": 3},
+		Processes:       processes,
+		OptimizeTargets: []string{fmt.Sprintf("part_%d", n)},
+	}
+}
+
+// BenchmarkGreedyChain measures the greedy scheduler on chains of increasing size.
+// With the runnable index the time per process stays roughly constant up to 10k processes.
+func BenchmarkGreedyChain(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000} {
+		config := chainConfig(n)
+		b.Run(fmt.Sprintf("processes=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				plan, err := greedyScheduler{}.Schedule(config, time.Minute)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if plan.Stock[fmt.Sprintf("part_%d", n)] != 3 {
+					b.Fatalf("expected 3 final parts, got %v", plan.Stock[fmt.Sprintf("part_%d", n)])
+				}
+			}
+		})
+	}
+}
+
+// fanOutConfig returns a configuration of n processes sharing a root item, each also
+// needing a part of its own. Only the first process has enough parts to run n times, so
+// after the first pass of a cycle a single process is left to start, while every pass
+// used to rescan all n of them.
+func fanOutConfig(n int) *util.ConfigData {
+	processes := make([]*process.Process, n)
+	stocks := map[string]int{"root": 2 * n}
+	for i := range processes {
+		part := fmt.Sprintf("part_%d", i)
+		processes[i] = &process.Process{
+			Name:   fmt.Sprintf("fan_%d", i),
+			Needs:  map[string]int{"root": 1, part: 1},
+			Result: map[string]int{fmt.Sprintf("product_%d", i): 1},
+			Cycle:  1,
+		}
+		stocks[part] = 1
+	}
+	stocks["part_0"] = n
+	return &util.ConfigData{
+		Stocks:          stocks,
+		Processes:       processes,
+		OptimizeTargets: []string{"product_0"},
+	}
+}
+
+// BenchmarkGreedyFanOut measures the greedy scheduler on fan-outs of increasing width.
+// Visiting only the processes that can still start keeps the time per process roughly
+// constant, where repeated full passes grew with the square of the width.
+func BenchmarkGreedyFanOut(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000} {
+		config := fanOutConfig(n)
+		b.Run(fmt.Sprintf("processes=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				plan, err := greedyScheduler{}.Schedule(config, time.Minute)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if plan.Stock["product_0"] != n {
+					b.Fatalf("expected %d product_0, got %d", n, plan.Stock["product_0"])
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkRunnableIndexAdjust measures a stock change in an index of 10k processes
+// sharing a pool of 1k items.
+func BenchmarkRunnableIndexAdjust(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	processes := make([]*process.Process, 10000)
+	for i := range processes {
+		processes[i] = &process.Process{
+			Name: fmt.Sprintf("p%d", i),
+			Needs: map[string]int{
+				fmt.Sprintf("item_%d", rng.Intn(1000)): 1 + rng.Intn(3),
+				fmt.Sprintf("item_%d", rng.Intn(1000)): 1 + rng.Intn(3),
+			},
+		}
+	}
+	index := newRunnableIndex(processes, map[string]int{}, processes)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		delta := 1
+		if i%2 == 1 {
+			delta = -1
+		}
+		index.adjust(fmt.Sprintf("item_%d", (i/2)%1000), delta)
+	}
+}
@@ -8,7 +8,16 @@ import (
 // SaveLog persists the simulation log to a specified file.
 // Each line of the log follows the format: <cycle>:<process_name>
 // It tracks the exact order and timing of process executions for future analysis.
+// Once Run has completed (e.Cycle > 0), the log ends with the same footer Run prints,
+// so the checker can confirm the final cycle.
 func (e *Engine) SaveLog(path string) error {
-	content := strings.Join(e.Schedule, "\n")
+	lines := make([]string, 0, len(e.Schedule)+1)
+	for _, entry := range e.Schedule {
+		lines = append(lines, entry.String())
+	}
+	if e.Cycle > 0 {
+		lines = append(lines, footer(e.Cycle, e.TimedOut))
+	}
+	content := strings.Join(lines, "\n")
 	return os.WriteFile(path, []byte(content), 0o644)
 }
@@ -0,0 +1,33 @@
+package engine
+
+import (
+	"os"
+	"strings"
+
+	"github.com/jesee-kuya/stock_exchange/process"
+)
+
+// SaveOutcomes writes the outcome trace of a run with drawn outcomes, which the checker
+// reads alongside the log to replay the run exactly. Each line gives an entry of the
+// schedule, in order, and what its run produced, in the format
+// "<cycle>:<process_name> => <outcome>", such as "5:bake => (bread:3)" or "10:bake => failed"
+// (see process.Outcome.String). Entries whose outcome was not drawn, as after a run
+// without e.Random, get the one the schedulers planned with (see
+// process.Process.NominalOutcome).
+func (e *Engine) SaveOutcomes(path string) error {
+	byName := make(map[string]*process.Process, len(e.Processes))
+	for _, p := range e.Processes {
+		byName[p.Name] = p
+	}
+	lines := make([]string, 0, len(e.Schedule))
+	for i, entry := range e.Schedule {
+		var outcome process.Outcome
+		if i < len(e.Outcomes) {
+			outcome = e.Outcomes[i]
+		} else if p, ok := byName[entry.ProcessName]; ok {
+			outcome = p.NominalOutcome()
+		}
+		lines = append(lines, entry.String()+" => "+outcome.String())
+	}
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644)
+}
@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// DefaultScheduler is the name of the scheduler used when none is selected.
+const DefaultScheduler = "greedy"
+
+// ErrNothingRunnable is returned by a Scheduler when no process can run with the initial stock.
+var ErrNothingRunnable = errors.New("no process can run with the initial stock")
+
+// Scheduler builds a schedule for a parsed configuration.
+// Implementations must not modify the configuration they are given and must
+// return within the wall-clock budget, handing back the best plan found so far.
+type Scheduler interface {
+	// Name returns the unique name the scheduler is selected by.
+	Name() string
+	// Schedule computes a plan for the configuration within the given budget.
+	Schedule(config *util.ConfigData, budget time.Duration) (*Plan, error)
+}
+
+// Configurable is implemented by schedulers that accept tuning options, such as
+// a search width or a horizon. Options are given as key/value strings so they
+// can come straight from the command line.
+type Configurable interface {
+	// WithOptions returns a copy of the scheduler with the options applied,
+	// or an error if an option is unknown or its value is invalid.
+	WithOptions(opts map[string]string) (Scheduler, error)
+}
+
+// Streamer is implemented by schedulers that decide entries in cycle order and never
+// revisit them, so each entry can be handed out as soon as it is decided. Engine.Run
+// uses it to stream long schedules to its log sink while the scheduler is still running.
+type Streamer interface {
+	Scheduler
+	// ScheduleStream behaves like Schedule and also calls emit with every entry of the
+	// plan, in order, as soon as it is decided.
+	ScheduleStream(config *util.ConfigData, budget time.Duration, emit func(ScheduleEntry)) (*Plan, error)
+}
+
+// Plan is the outcome of a Scheduler run.
+//
+// Fields:
+//   - Entries: the process starts ordered by cycle.
+//   - Stock: the stock once every started process has completed.
+//   - Cycle: the last cycle of the run, as reported in the log footer.
+//   - Makespan: the cycle at which the last target unit was produced.
+//   - TimedOut: true when the budget expired before the scheduler was done.
+//   - Certificate: how close to optimal the plan is proven to be, for exact schedulers; nil otherwise.
+//   - Lots: the lots of the perishable items left in Stock.
+//   - Expired: the quantity of each perishable item lost to expiry.
+//   - Orders: what became of each customer order, in configuration order.
+type Plan struct {
+	Entries     []ScheduleEntry
+	Stock       map[string]int
+	Cycle       int
+	Makespan    int
+	TimedOut    bool
+	Certificate *Certificate
+	Lots        map[string][]Lot
+	Expired     map[string]int
+	Orders      []OrderResult
+}
+
+// schedulers holds every registered scheduler by name.
+var schedulers = map[string]Scheduler{}
+
+// RegisterScheduler makes a scheduler available by its name.
+// It panics if a scheduler with the same name is already registered, since that
+// can only be a programming error.
+func RegisterScheduler(s Scheduler) {
+	name := s.Name()
+	if _, exists := schedulers[name]; exists {
+		panic(fmt.Sprintf("engine: scheduler %q registered twice", name))
+	}
+	schedulers[name] = s
+}
+
+// LookupScheduler returns the scheduler registered under the given name.
+// It returns an error listing the known names if there is none.
+func LookupScheduler(name string) (Scheduler, error) {
+	s, ok := schedulers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown scheduler %q (available: %v)", name, SchedulerNames())
+	}
+	return s, nil
+}
+
+// SchedulerNames returns the names of all registered schedulers in alphabetical order.
+func SchedulerNames() []string {
+	names := make([]string, 0, len(schedulers))
+	for name := range schedulers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseIntOption parses a non-negative integer scheduler option.
+func parseIntOption(key, value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid value %q for option %s", value, key)
+	}
+	return n, nil
+}
+
+// cloneStock returns an independent copy of a stock map.
+func cloneStock(stock map[string]int) map[string]int {
+	clone := make(map[string]int, len(stock))
+	for k, v := range stock {
+		clone[k] = v
+	}
+	return clone
+}
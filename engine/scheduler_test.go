@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// fixedScheduler is a test Scheduler that always returns the same plan.
+type fixedScheduler struct {
+	plan *Plan
+}
+
+func (fixedScheduler) Name() string { return "fixed" }
+
+func (s fixedScheduler) Schedule(config *util.ConfigData, budget time.Duration) (*Plan, error) {
+	return s.plan, nil
+}
+
+// TestLookupScheduler tests that the default scheduler is registered and that
+// unknown names are reported as errors.
+func TestLookupScheduler(t *testing.T) {
+	s, err := LookupScheduler(DefaultScheduler)
+	if err != nil {
+		t.Fatalf("LookupScheduler(%q) returned an error: %v", DefaultScheduler, err)
+	}
+	if s.Name() != DefaultScheduler {
+		t.Errorf("Name() = %q, want %q", s.Name(), DefaultScheduler)
+	}
+
+	if _, err := LookupScheduler("no_such_scheduler"); err == nil {
+		t.Error("LookupScheduler should fail for an unknown name")
+	}
+}
+
+// TestRunWithScheduler tests that Run records the plan of the selected scheduler.
+func TestRunWithScheduler(t *testing.T) {
+	e := newCabinetEngine("cabinet")
+	e.Scheduler = fixedScheduler{plan: &Plan{
+		Entries: []ScheduleEntry{{Cycle: 0, ProcessName: "do_shelf"}},
+		Stock:   map[string]int{"board": 6, "shelf": 1},
+		Cycle:   11,
+	}}
+	e.Run("1")
+
+	if len(e.Schedule) != 1 || e.Schedule[0].String() != "0:do_shelf" {
+		t.Errorf("Schedule = %v, want [0:do_shelf]", e.Schedule)
+	}
+	if e.Cycle != 11 || e.Stock.Items["shelf"] != 1 {
+		t.Errorf("engine state not taken from the plan: cycle %d, stock %v", e.Cycle, e.Stock.Items)
+	}
+}
+
+// streamingScheduler is a test Streamer that records what the sink held right after
+// its first entry was emitted, before the plan is returned.
+type streamingScheduler struct {
+	fixedScheduler
+	sink    *bytes.Buffer
+	midPlan *string
+}
+
+func (s streamingScheduler) ScheduleStream(config *util.ConfigData, budget time.Duration, emit func(ScheduleEntry)) (*Plan, error) {
+	emit(s.plan.Entries[0])
+	*s.midPlan = s.sink.String()
+	return s.plan, nil
+}
+
+// TestRunSink tests that Run writes the log and its footer to the sink, streaming the
+// entries of a Streamer before its plan is complete.
+func TestRunSink(t *testing.T) {
+	plan := &Plan{
+		Entries: []ScheduleEntry{{Cycle: 0, ProcessName: "do_shelf"}},
+		Stock:   map[string]int{"board": 6, "shelf": 1},
+		Cycle:   11,
+	}
+	want := "0:do_shelf\nNo more process doable at cycle 11\n"
+
+	t.Run("plan", func(t *testing.T) {
+		var sink, out bytes.Buffer
+		e := newCabinetEngine("cabinet")
+		e.Scheduler = fixedScheduler{plan: plan}
+		e.Sink, e.Out = &sink, &out
+		if err := e.Run("1"); err != nil {
+			t.Fatal(err)
+		}
+		if sink.String() != want {
+			t.Errorf("sink = %q, want %q", sink.String(), want)
+		}
+		if !strings.Contains(out.String(), " 0:do_shelf\n") {
+			t.Errorf("progress output is missing the entry: %q", out.String())
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		var sink bytes.Buffer
+		midPlan := ""
+		e := newCabinetEngine("cabinet")
+		e.Scheduler = streamingScheduler{fixedScheduler{plan}, &sink, &midPlan}
+		e.Sink, e.Out = &sink, io.Discard
+		if err := e.Run("1"); err != nil {
+			t.Fatal(err)
+		}
+		if midPlan != "0:do_shelf\n" {
+			t.Errorf("sink held %q before the plan was returned, want the first entry", midPlan)
+		}
+		if sink.String() != want {
+			t.Errorf("sink = %q, want %q", sink.String(), want)
+		}
+	})
+}
@@ -0,0 +1,299 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/jesee-kuya/stock_exchange/process"
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// job is a started process instance together with the cycle it completes at.
+type job struct {
+	Process *process.Process
+	End     int
+}
+
+// simState is a snapshot of a schedule under construction. Unlike the greedy
+// loop, it advances from one completion event to the next, which is all the
+// search-based schedulers need since stock only grows when a process completes
+// or a delivery arrives.
+//
+// Fields:
+//   - Stock: the quantities currently available.
+//   - Running: the started processes that have not completed, sorted by End.
+//   - Cycle: the current decision cycle.
+//   - Entries: the process starts made so far.
+//   - Makespan: the cycle at which the last target unit was produced so far.
+//   - Incoming: the largest results of the running processes, not yet in Stock.
+//   - Capacities: the storage capacity of the items that have one, shared between clones.
+//   - Lots: the lots of the perishable items in Stock.
+//   - Expired: the quantity of each perishable item lost to expiry so far.
+//   - Timeline: the deliveries and orders, applied up to Cycle.
+type simState struct {
+	Stock      map[string]int
+	Running    []job
+	Cycle      int
+	Entries    []ScheduleEntry
+	Makespan   int
+	Incoming   map[string]int
+	Capacities map[string]int
+	Lots       *Lots
+	Expired    map[string]int
+	Timeline   *Timeline
+}
+
+// newSimState returns the state at cycle 0 with a copy of the stock of the configuration,
+// its storage capacities, the lots of its perishable items and its timed events, the
+// ones of cycle 0 applied.
+func newSimState(config *util.ConfigData) *simState {
+	s := &simState{
+		Stock:      cloneStock(config.Stocks),
+		Incoming:   map[string]int{},
+		Capacities: config.Capacities,
+		Lots:       NewLots(config.ShelfLife, config.Stocks),
+		Expired:    map[string]int{},
+		Timeline:   NewTimeline(config.Deliveries, config.Orders),
+	}
+	s.Timeline.Advance(0, s.Stock, s.receive)
+	return s
+}
+
+// clone returns a deep copy of the state that can be modified independently.
+func (s *simState) clone() *simState {
+	c := &simState{
+		Stock:      cloneStock(s.Stock),
+		Running:    make([]job, len(s.Running)),
+		Cycle:      s.Cycle,
+		Entries:    make([]ScheduleEntry, len(s.Entries)),
+		Makespan:   s.Makespan,
+		Incoming:   cloneStock(s.Incoming),
+		Capacities: s.Capacities,
+		Lots:       s.Lots.Clone(),
+		Expired:    cloneStock(s.Expired),
+		Timeline:   s.Timeline.Clone(),
+	}
+	copy(c.Running, s.Running)
+	copy(c.Entries, s.Entries)
+	return c
+}
+
+// start consumes the needs of the process from the stock, oldest lots first, records
+// a schedule entry for the current cycle and adds the process to the running set.
+// The caller is responsible for checking canStart beforehand.
+func (s *simState) start(p *process.Process) {
+	for item, qty := range p.Needs {
+		s.Stock[item] -= qty
+		s.Lots.Take(item, qty)
+	}
+	for item, qty := range p.MostResult() {
+		s.Incoming[item] += qty
+	}
+	s.Entries = append(s.Entries, ScheduleEntry{Cycle: s.Cycle, ProcessName: p.Name})
+
+	j := job{Process: p, End: s.Cycle + p.Cycle}
+	i := sort.Search(len(s.Running), func(i int) bool { return s.Running[i].End > j.End })
+	s.Running = append(s.Running, job{})
+	copy(s.Running[i+1:], s.Running[i:])
+	s.Running[i] = j
+}
+
+// advance moves the state to the next completion or delivery cycle and adds the results
+// of every process completing at that cycle, and the resources they held, to the stock.
+// The perishable units whose shelf life has elapsed by then are removed; since expiry
+// only takes from the stock, it never needs an event of its own. The deliveries and
+// orders of the cycle are applied last.
+// It returns false, leaving the state untouched, when nothing is running and no delivery
+// is expected.
+func (s *simState) advance(obj Objective) bool {
+	if len(s.Running) == 0 && !s.Timeline.Pending() {
+		return false
+	}
+	s.Cycle = s.Timeline.Next()
+	if len(s.Running) > 0 {
+		s.Cycle = min(s.Cycle, s.Running[0].End)
+	}
+	n := 0
+	for n < len(s.Running) && s.Running[n].End == s.Cycle {
+		p := s.Running[n].Process
+		for item, qty := range p.Output() {
+			s.Stock[item] += qty
+			s.Lots.Add(item, qty, s.Cycle)
+		}
+		for item, qty := range p.MostResult() {
+			s.Incoming[item] -= qty
+		}
+		if obj.ProducesTarget(p) {
+			s.Makespan = s.Cycle
+		}
+		n++
+	}
+	s.Running = s.Running[n:]
+	for item, qty := range s.Lots.Expire(s.Cycle) {
+		s.Stock[item] -= qty
+		s.Expired[item] += qty
+	}
+	s.Timeline.Advance(s.Cycle, s.Stock, s.receive)
+	return true
+}
+
+// receive changes the stock of an item by a timeline event at the current cycle.
+func (s *simState) receive(item string, qty int) {
+	s.Stock[item] += qty
+	if qty > 0 {
+		s.Lots.Add(item, qty, s.Cycle)
+	} else {
+		s.Lots.Take(item, -qty)
+	}
+}
+
+// waiting reports whether the state can still change without starting anything: a process
+// is running or a delivery is expected.
+func (s *simState) waiting() bool {
+	return len(s.Running) > 0 || s.Timeline.Pending()
+}
+
+// finish completes every running process and applies the remaining deliveries, leaving
+// the state at the last event cycle.
+func (s *simState) finish(obj Objective) {
+	for s.advance(obj) {
+	}
+}
+
+// canStart reports whether a process can start in the state: its needs are in stock
+// and its results fit in storage.
+func (s *simState) canStart(p *process.Process) bool {
+	return p.CanRun(s.Stock) && p.Fits(s.Stock, s.Incoming, s.Capacities)
+}
+
+// canRunAny reports whether at least one of the processes can start with the current stock.
+func (s *simState) canRunAny(processes []*process.Process) bool {
+	for _, p := range processes {
+		if s.canStart(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// candidates returns the processes worth considering in the state: the ones that
+// contribute to the targets, or all of them once nothing is running and no
+// contributing process can start anymore.
+func (s *simState) candidates(reachable, all []*process.Process) []*process.Process {
+	if len(s.Running) == 0 && !s.canRunAny(reachable) {
+		return all
+	}
+	return reachable
+}
+
+// runnable returns the processes that can start with the current stock, in the given
+// order except that the consumers of perishable units come first (see preferPerishable).
+func (s *simState) runnable(processes []*process.Process) []*process.Process {
+	runnable := []*process.Process{}
+	for _, p := range processes {
+		if s.canStart(p) {
+			runnable = append(runnable, p)
+		}
+	}
+	preferPerishable(runnable, s.Lots)
+	return runnable
+}
+
+// startGreedy starts processes the way the greedy scheduler does: pass after
+// pass over the ordered processes, each one up to its quota, until no process
+// can start anymore. The skip process, if not nil, is left out.
+// It returns the number of processes started.
+func (s *simState) startGreedy(ordered []*process.Process, quotas map[string]int, skip *process.Process) int {
+	started := 0
+	changed := true
+	for changed {
+		changed = false
+		for _, p := range ordered {
+			if p == skip {
+				continue
+			}
+			for n := 0; n < quotas[p.Name] && s.canStart(p); n++ {
+				s.start(p)
+				started++
+				changed = true
+			}
+		}
+	}
+	return started
+}
+
+// plan converts a finished state into a Plan. The reported cycle follows the
+// greedy scheduler's convention of the cycle after the last event.
+func (s *simState) plan() *Plan {
+	return &Plan{
+		Entries:  s.Entries,
+		Stock:    s.Stock,
+		Cycle:    s.Cycle + 1,
+		Makespan: s.Makespan,
+		Lots:     s.Lots.Items,
+		Expired:  s.Expired,
+		Orders:   s.Timeline.Results(),
+	}
+}
+
+// score scores the state as a finished schedule, its open orders counting as unfilled.
+func (s *simState) score(obj Objective) Score {
+	return obj.EvaluatePlan(s.plan())
+}
+
+// key returns a canonical representation of the state, ignoring the entries
+// that led to it, so that equivalent states reached through different start
+// orders can be recognized.
+func (s *simState) key() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d|%d|", s.Cycle, s.Makespan)
+
+	items := make([]string, 0, len(s.Stock))
+	for item, qty := range s.Stock {
+		if qty != 0 {
+			items = append(items, fmt.Sprintf("%s=%d", item, qty))
+		}
+	}
+	sort.Strings(items)
+	b.WriteString(strings.Join(items, ";"))
+	b.WriteByte('|')
+
+	// Running is sorted by End only; sort names within equal ends
+	jobs := make([]string, len(s.Running))
+	for i, j := range s.Running {
+		jobs[i] = fmt.Sprintf("%d:%s", j.End, j.Process.Name)
+	}
+	sort.Strings(jobs)
+	b.WriteString(strings.Join(jobs, ";"))
+	b.WriteByte('|')
+	b.WriteString(s.Lots.key())
+	b.WriteByte('|')
+	b.WriteString(s.Timeline.key())
+	return b.String()
+}
+
+// preferPerishable moves the processes consuming perishable units in stock to the front,
+// the ones using the units closest to expiry first, so that they are used before they
+// spoil. The other processes keep their order, as do processes with the same urgency.
+func preferPerishable(processes []*process.Process, lots *Lots) {
+	if len(lots.Items) == 0 {
+		return
+	}
+	sort.SliceStable(processes, func(i, j int) bool {
+		return urgency(processes[i], lots) < urgency(processes[j], lots)
+	})
+}
+
+// urgency returns the earliest expiry among the perishable units a process would consume,
+// or math.MaxInt when it consumes none.
+func urgency(p *process.Process, lots *Lots) int {
+	soonest := math.MaxInt
+	for item, qty := range p.Needs {
+		if qty > 0 {
+			soonest = min(soonest, lots.NextExpiry(item))
+		}
+	}
+	return soonest
+}
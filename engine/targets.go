@@ -0,0 +1,144 @@
+package engine
+
+import (
+	"github.com/jesee-kuya/stock_exchange/process"
+)
+
+// timeTarget is the reserved optimize keyword asking the engine to minimize
+// the makespan, i.e. the cycle at which the last target unit is produced.
+const timeTarget = "time"
+
+// Objective describes what a schedule is optimized for.
+// Items holds the item names whose final quantity should be maximized and
+// Time reports whether the makespan should be minimized as well.
+type Objective struct {
+	Items map[string]bool
+	Time  bool
+}
+
+// NewObjective resolves the raw optimize targets of a configuration into an objective.
+//
+// Behavior:
+//   - The "time" keyword sets Time instead of being treated as an item.
+//   - Any other target is kept when it is an initial stock or produced by at least one process.
+//   - When only "time" is given, the terminal products (items produced but never consumed)
+//     become the item targets so the makespan still has something to measure.
+//
+// Parameters:
+//   - targets: the optimize targets in the order they appear in the configuration.
+//   - processes: all available processes.
+//   - stocks: the initial stock quantities.
+//
+// Returns:
+//   - The resolved objective.
+func NewObjective(targets []string, processes []*process.Process, stocks map[string]int) Objective {
+	obj := Objective{Items: map[string]bool{}}
+
+	produced := map[string]bool{}
+	consumed := map[string]bool{}
+	for _, p := range processes {
+		for item := range p.Result {
+			produced[item] = true
+		}
+		for item := range p.Needs {
+			consumed[item] = true
+		}
+	}
+
+	for _, t := range targets {
+		if t == timeTarget {
+			obj.Time = true
+			continue
+		}
+		if _, ok := stocks[t]; ok || produced[t] {
+			obj.Items[t] = true
+		}
+	}
+
+	if obj.Time && len(obj.Items) == 0 {
+		for item := range produced {
+			if !consumed[item] {
+				obj.Items[item] = true
+			}
+		}
+	}
+
+	return obj
+}
+
+// ProducesTarget reports whether the process yields at least one item of the objective.
+func (o Objective) ProducesTarget(p *process.Process) bool {
+	for item := range p.Result {
+		if o.Items[item] {
+			return true
+		}
+	}
+	return false
+}
+
+// Score measures how well a schedule meets an objective.
+// Quantity is the total final stock of the target items and Makespan the cycle
+// at which the last target unit was produced. Unfilled counts the customer orders
+// that never shipped and Penalty sums the cost of the ones that shipped late.
+type Score struct {
+	Quantity int
+	Makespan int
+
+	Unfilled int
+	Penalty  int
+}
+
+// Evaluate scores a final stock and makespan against the objective.
+func (o Objective) Evaluate(stock map[string]int, makespan int) Score {
+	s := Score{Makespan: makespan}
+	for item := range o.Items {
+		s.Quantity += stock[item]
+	}
+	return s
+}
+
+// EvaluatePlan scores a plan like Evaluate, counting the orders it left unfilled and
+// the penalty of the ones it shipped late as well.
+func (o Objective) EvaluatePlan(p *Plan) Score {
+	s := o.Evaluate(p.Stock, p.Makespan)
+	for _, r := range p.Orders {
+		if !r.Filled() {
+			s.Unfilled++
+		}
+		s.Penalty += r.Cost()
+	}
+	return s
+}
+
+// Better reports whether score a is strictly better than score b:
+// fewer unfilled orders win, then a lower penalty, then a larger target
+// quantity, and equal quantities are decided by the shorter makespan.
+func (o Objective) Better(a, b Score) bool {
+	if a.Unfilled != b.Unfilled {
+		return a.Unfilled < b.Unfilled
+	}
+	if a.Penalty != b.Penalty {
+		return a.Penalty < b.Penalty
+	}
+	if a.Quantity != b.Quantity {
+		return a.Quantity > b.Quantity
+	}
+	return a.Makespan < b.Makespan
+}
+
+// MakespanOf returns the makespan of a schedule: the latest end cycle among the
+// entries whose process produces a target. Entries naming unknown processes are ignored.
+func (o Objective) MakespanOf(entries []ScheduleEntry, processes []*process.Process) int {
+	byName := make(map[string]*process.Process, len(processes))
+	for _, p := range processes {
+		byName[p.Name] = p
+	}
+	makespan := 0
+	for _, entry := range entries {
+		p, ok := byName[entry.ProcessName]
+		if ok && o.ProducesTarget(p) && entry.Cycle+p.Cycle > makespan {
+			makespan = entry.Cycle + p.Cycle
+		}
+	}
+	return makespan
+}
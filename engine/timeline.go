@@ -0,0 +1,215 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// Timeline applies the timed events of a configuration to a run: the inbound deliveries,
+// added to the stock at their cycle, and the customer orders, taken from the stock and
+// shipped as soon as all their items are in stock.
+//
+// Orders are served earliest due first, ties in configuration order. An order waiting for
+// an item blocks the orders due after it that need the same item, so a large order is not
+// starved by smaller ones; the other items stay free for the processes meanwhile.
+//
+// Fields:
+//   - Deliveries: the deliveries, sorted by cycle, configuration order kept within a cycle.
+//   - Orders: the orders in configuration order.
+//   - Shipped: the cycle each order shipped at, -1 while it is open.
+type Timeline struct {
+	Deliveries []util.Delivery
+	Orders     []util.Order
+	Shipped    []int
+	next       int
+	queue      []int
+}
+
+// OrderResult is what became of a customer order at the end of a run.
+type OrderResult struct {
+	util.Order
+	Shipped int // The cycle the order shipped at, -1 when it never did
+}
+
+// Filled reports whether the order shipped.
+func (r OrderResult) Filled() bool {
+	return r.Shipped >= 0
+}
+
+// Late returns the number of cycles the order shipped after its due cycle, 0 when it
+// shipped on time or never did.
+func (r OrderResult) Late() int {
+	if !r.Filled() {
+		return 0
+	}
+	return max(0, r.Shipped-r.Due)
+}
+
+// Cost returns the penalty of a late order: its penalty for every cycle late.
+func (r OrderResult) Cost() int {
+	return r.Late() * r.Penalty
+}
+
+// String describes the result, such as "bread:10 due 150: shipped at 162, 12 cycles late,
+// penalty 60" or "bread:10 due 150: unfilled".
+func (r OrderResult) String() string {
+	switch {
+	case !r.Filled():
+		return fmt.Sprintf("%s: unfilled", r.Order)
+	case r.Late() == 0:
+		return fmt.Sprintf("%s: shipped at %d, on time", r.Order, r.Shipped)
+	default:
+		return fmt.Sprintf("%s: shipped at %d, %d cycles late, penalty %d", r.Order, r.Shipped, r.Late(), r.Cost())
+	}
+}
+
+// NewTimeline returns the timeline of the given deliveries and orders, nothing applied yet.
+func NewTimeline(deliveries []util.Delivery, orders []util.Order) *Timeline {
+	t := &Timeline{
+		Deliveries: append([]util.Delivery(nil), deliveries...),
+		Orders:     orders,
+		Shipped:    make([]int, len(orders)),
+		queue:      make([]int, len(orders)),
+	}
+	sort.SliceStable(t.Deliveries, func(i, j int) bool { return t.Deliveries[i].Cycle < t.Deliveries[j].Cycle })
+	for i := range orders {
+		t.Shipped[i] = -1
+		t.queue[i] = i
+	}
+	sort.SliceStable(t.queue, func(i, j int) bool { return orders[t.queue[i]].Due < orders[t.queue[j]].Due })
+	return t
+}
+
+// Clone returns a copy of the timeline that can be advanced independently.
+func (t *Timeline) Clone() *Timeline {
+	c := *t
+	c.Shipped = append([]int(nil), t.Shipped...)
+	c.queue = append([]int(nil), t.queue...)
+	return &c
+}
+
+// Next returns the cycle of the next delivery not applied yet, or math.MaxInt when every
+// delivery has been applied.
+func (t *Timeline) Next() int {
+	if !t.Pending() {
+		return math.MaxInt
+	}
+	return t.Deliveries[t.next].Cycle
+}
+
+// Pending reports whether some deliveries have not been applied yet.
+func (t *Timeline) Pending() bool {
+	return t.next < len(t.Deliveries)
+}
+
+// Upcoming returns the deliveries not applied yet, in cycle order.
+func (t *Timeline) Upcoming() []util.Delivery {
+	return t.Deliveries[t.next:]
+}
+
+// Remaining returns the deliveries not applied yet and the open orders, their cycles
+// made relative to the given one, for a plan starting there.
+func (t *Timeline) Remaining(cycle int) ([]util.Delivery, []util.Order) {
+	deliveries := make([]util.Delivery, 0, len(t.Upcoming()))
+	for _, d := range t.Upcoming() {
+		deliveries = append(deliveries, util.Delivery{Cycle: d.Cycle - cycle, Items: d.Items})
+	}
+	orders := make([]util.Order, 0, len(t.queue))
+	for i, o := range t.Orders {
+		if t.Shipped[i] < 0 {
+			o.Due -= cycle
+			orders = append(orders, o)
+		}
+	}
+	return deliveries, orders
+}
+
+// Advance applies the events of the given cycle: the deliveries due by then, then every
+// open order that can ship (see Timeline).
+//
+// Parameters:
+//   - cycle: the current cycle, never less than the one of the previous call.
+//   - stock: the quantities currently in stock, read to decide which orders ship.
+//   - add: called to change the stock, with a positive quantity for each item delivered
+//     and a negative one for each item shipped; it must update stock accordingly.
+func (t *Timeline) Advance(cycle int, stock map[string]int, add func(item string, qty int)) {
+	for t.Pending() && t.Deliveries[t.next].Cycle <= cycle {
+		items := t.Deliveries[t.next].Items
+		for _, item := range sortedItems(items) {
+			add(item, items[item])
+		}
+		t.next++
+	}
+
+	blocked := map[string]bool{}
+	open := t.queue[:0]
+	for _, i := range t.queue {
+		items := t.Orders[i].Items
+		ready := true
+		for item, qty := range items {
+			if blocked[item] || stock[item] < qty {
+				ready = false
+			}
+		}
+		if !ready {
+			for item := range items {
+				blocked[item] = true
+			}
+			open = append(open, i)
+			continue
+		}
+		for _, item := range sortedItems(items) {
+			add(item, -items[item])
+		}
+		t.Shipped[i] = cycle
+	}
+	t.queue = open
+}
+
+// Penalty returns the least penalty the orders can cost once the given cycle is reached:
+// the cost of the late orders shipped, plus what the open orders already overdue cost if
+// they ship right away.
+func (t *Timeline) Penalty(cycle int) int {
+	penalty := 0
+	for _, r := range t.Results() {
+		if r.Filled() {
+			penalty += r.Cost()
+		} else if cycle > r.Due {
+			penalty += (cycle - r.Due) * r.Penalty
+		}
+	}
+	return penalty
+}
+
+// Results returns what became of each order so far, in configuration order.
+func (t *Timeline) Results() []OrderResult {
+	results := make([]OrderResult, len(t.Orders))
+	for i, o := range t.Orders {
+		results[i] = OrderResult{Order: o, Shipped: t.Shipped[i]}
+	}
+	return results
+}
+
+// key returns a canonical representation of the timeline state, for simState.key.
+func (t *Timeline) key() string {
+	parts := make([]string, len(t.Shipped))
+	for i, cycle := range t.Shipped {
+		parts[i] = fmt.Sprint(cycle)
+	}
+	return fmt.Sprintf("%d/%s", t.next, strings.Join(parts, ","))
+}
+
+// sortedItems returns the items of a quantity map in lexical order, so that events
+// change the stock in a reproducible order.
+func sortedItems(quantities map[string]int) []string {
+	items := make([]string, 0, len(quantities))
+	for item := range quantities {
+		items = append(items, item)
+	}
+	sort.Strings(items)
+	return items
+}
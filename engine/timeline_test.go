@@ -0,0 +1,41 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// TestTimeline tests that deliveries arrive at their cycle and that orders ship earliest
+// due first, an order waiting for an item holding back the later ones needing it.
+func TestTimeline(t *testing.T) {
+	timeline := NewTimeline(
+		[]util.Delivery{{Cycle: 20, Items: map[string]int{"bread": 5}}, {Cycle: 10, Items: map[string]int{"jam": 1}}},
+		[]util.Order{
+			{Due: 30, Items: map[string]int{"bread": 1}, Penalty: 1},
+			{Due: 15, Items: map[string]int{"bread": 4}, Penalty: 2},
+			{Due: 15, Items: map[string]int{"jam": 1}, Penalty: 1},
+		},
+	)
+	stock := map[string]int{"bread": 2}
+	add := func(item string, qty int) { stock[item] += qty }
+
+	timeline.Advance(0, stock, add)
+	if !reflect.DeepEqual(timeline.Shipped, []int{-1, -1, -1}) || timeline.Next() != 10 {
+		t.Fatalf("Shipped = %v, Next = %d, want nothing shipped before the jam arrives at 10", timeline.Shipped, timeline.Next())
+	}
+	timeline.Advance(10, stock, add)
+	timeline.Advance(25, stock, add)
+	if !reflect.DeepEqual(timeline.Shipped, []int{25, 25, 10}) || timeline.Pending() {
+		t.Errorf("Shipped = %v, want the jam at 10 and the bread at 25", timeline.Shipped)
+	}
+	if stock["bread"] != 2 || stock["jam"] != 0 {
+		t.Errorf("stock = %v, want 2 bread left", stock)
+	}
+
+	results := timeline.Results()
+	if results[1].Late() != 10 || results[1].Cost() != 20 || results[0].Late() != 0 {
+		t.Errorf("results = %v, want the second order 10 cycles late for a penalty of 20", results)
+	}
+}
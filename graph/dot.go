@@ -0,0 +1,155 @@
+// Package graph renders the process graph of a configuration.
+package graph
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jesee-kuya/stock_exchange/process"
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// WriteDOT writes the bipartite process graph of a configuration in Graphviz DOT format.
+//
+// Behavior:
+//   - Items are ellipses, labelled with their initial stock when they have one, followed by
+//     "/capacity" for items with a storage capacity, and with their shelf life when they
+//     are perishable; optimize
+//     targets are drawn with a double border. Renewable resources are hexagons labelled
+//     with their capacity.
+//   - Processes are boxes labelled with their name and cycle count.
+//   - An edge goes from each needed item to the process, and from the process to each item
+//     it produces, labelled with the quantity. Edges to held renewable resources are dashed
+//     and point both ways, since the resource is given back.
+//   - Nodes and edges are sorted by name, so the output is stable.
+//
+// Parameters:
+//   - w: the destination of the DOT document.
+//   - config: the parsed configuration.
+//
+// Returns:
+//   - The first write error, nil otherwise.
+//
+// Example usage:
+//
+//	stock_exchange graph examples/bread | dot -Tsvg > bread.svg
+func WriteDOT(w io.Writer, config *util.ConfigData) error {
+	targets := map[string]bool{}
+	for _, t := range config.OptimizeTargets {
+		targets[t] = true
+	}
+	items := map[string]bool{}
+	for item := range config.Stocks {
+		items[item] = true
+	}
+	for _, p := range config.Processes {
+		for item := range p.Needs {
+			items[item] = true
+		}
+		for item := range p.Result {
+			items[item] = true
+		}
+	}
+
+	lines := []string{"digraph processes {", "  rankdir=LR;"}
+	for _, item := range sortedNames(items) {
+		parts := []string{item}
+		if qty, ok := config.Stocks[item]; ok {
+			parts = append(parts, strconv.Itoa(qty))
+			if capacity, ok := config.Capacities[item]; ok {
+				parts[1] = fmt.Sprintf("%d/%d", qty, capacity)
+			}
+		}
+		peripheries := 1
+		if targets[item] {
+			peripheries = 2
+		}
+		shape := "ellipse"
+		if capacity, ok := config.Renewables[item]; ok {
+			shape = "hexagon"
+			parts = []string{item, fmt.Sprintf("capacity %d", capacity)}
+		}
+		if life, ok := config.ShelfLife[item]; ok {
+			parts = append(parts, fmt.Sprintf("shelf life %d", life))
+		}
+		label := dotLabel(parts...)
+		lines = append(lines, fmt.Sprintf("  %s [shape=%s, peripheries=%d, label=%s];", itemID(item), shape, peripheries, label))
+	}
+
+	processes := append(config.Processes[:0:0], config.Processes...)
+	sort.SliceStable(processes, func(i, j int) bool { return processes[i].Name < processes[j].Name })
+	for _, p := range processes {
+		lines = append(lines, fmt.Sprintf("  %s [shape=box, label=%s];",
+			processID(p.Name), dotLabel(p.Name, cycles(p))))
+		for _, item := range sortedKeys(p.Needs) {
+			style := ""
+			if p.Holds[item] > 0 {
+				style = ", style=dashed, dir=both"
+			}
+			lines = append(lines, fmt.Sprintf("  %s -> %s [label=\"%d\"%s];", itemID(item), processID(p.Name), p.Needs[item], style))
+		}
+		for _, item := range sortedKeys(p.Result) {
+			lines = append(lines, fmt.Sprintf("  %s -> %s [label=\"%d\"];", processID(p.Name), itemID(item), p.Result[item]))
+		}
+	}
+	lines = append(lines, "}")
+
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dotLabel returns a quoted DOT string showing each part on its own line.
+func dotLabel(parts ...string) string {
+	escaped := make([]string, len(parts))
+	for i, part := range parts {
+		escaped[i] = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(part)
+	}
+	return `"` + strings.Join(escaped, `\n`) + `"`
+}
+
+// cycles returns the duration shown on a process node: its cycle count, or its
+// distribution for a process whose duration varies.
+func cycles(p *process.Process) string {
+	if p.Duration != nil {
+		return p.Duration.String() + " cycles"
+	}
+	return fmt.Sprintf("%d cycles", p.Cycle)
+}
+
+// itemID returns the DOT node identifier of an item. Items and processes live in
+// separate namespaces, since a process may share its name with an item.
+func itemID(name string) string {
+	return dotLabel("item:" + name)
+}
+
+// processID returns the DOT node identifier of a process.
+func processID(name string) string {
+	return dotLabel("process:" + name)
+}
+
+// sortedNames returns the keys of a set in lexical order.
+func sortedNames(set map[string]bool) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedKeys returns the keys of a quantity map in lexical order.
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
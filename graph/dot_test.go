@@ -0,0 +1,39 @@
+package graph
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jesee-kuya/stock_exchange/process"
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// TestWriteDOT tests that items, processes and quantity edges are rendered, and that
+// a process sharing its name with an item gets its own node.
+func TestWriteDOT(t *testing.T) {
+	config := &util.ConfigData{
+		Stocks: map[string]int{"flour": 3},
+		Processes: []*process.Process{
+			{Name: "bread", Needs: map[string]int{"flour": 2}, Result: map[string]int{"bread": 1}, Cycle: 40},
+		},
+		OptimizeTargets: []string{"bread"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteDOT(&buf, config); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`"item:flour" [shape=ellipse, peripheries=1, label="flour\n3"];`,
+		`"item:bread" [shape=ellipse, peripheries=2, label="bread"];`,
+		`"process:bread" [shape=box, label="bread\n40 cycles"];`,
+		`"item:flour" -> "process:bread" [label="2"];`,
+		`"process:bread" -> "item:bread" [label="1"];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %s:\n%s", want, out)
+		}
+	}
+}
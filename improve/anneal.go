@@ -0,0 +1,210 @@
+package improve
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	checker "github.com/jesee-kuya/stock_exchange/checker_util"
+	"github.com/jesee-kuya/stock_exchange/engine"
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// finalTemperature is the temperature the annealing schedule cools down to by the end
+// of the budget. At that point a move making the makespan one cycle worse is accepted
+// with a probability of about 13%.
+const finalTemperature = 0.5
+
+// restartAfter is the number of iterations without a new best schedule after which
+// the search resumes from the best schedule, so it does not drift away for good.
+const restartAfter = 2000
+
+// Result is the outcome of an improvement run.
+//
+// Fields:
+//   - Entries: the best schedule found, ordered by cycle.
+//   - Before, After: the score of the original and of the returned schedule.
+//   - Tried, Accepted: how many candidate schedules were evaluated and accepted.
+//   - Cycle, TimedOut: the footer of the returned schedule (see checker.Checker.Ending),
+//     to be written at the end of its log.
+type Result struct {
+	Entries  []engine.ScheduleEntry
+	Before   engine.Score
+	After    engine.Score
+	Tried    int
+	Accepted int
+
+	Cycle    int
+	TimedOut bool
+}
+
+// annealer holds the data shared by the iterations of one annealing run.
+type annealer struct {
+	config  *util.ConfigData
+	obj     engine.Objective
+	checker *checker.Checker
+	rng     *rand.Rand
+	horizon int
+	maxSpan int
+}
+
+// Anneal improves a valid schedule by simulated annealing. Each iteration perturbs the
+// current schedule with one random move (shifting a start cycle, swapping the processes
+// of two entries, inserting a run or removing one), replays it with the checker rules
+// and accepts it if it scores better, or with a probability that shrinks as the
+// temperature cools over the budget otherwise. Invalid candidates are always rejected,
+// and the search restarts from the best schedule when it stops finding better ones.
+//
+// Parameters:
+//   - config: the parsed configuration the schedule was made for.
+//   - log: the schedule to improve; it must pass verification.
+//   - budget: the wall-clock time to spend.
+//   - seed: the seed of the random number generator.
+//
+// Returns:
+//   - The best schedule found, which is the original one if nothing better was found,
+//     with the footer its log can end with, or an error if the original schedule is invalid.
+func Anneal(config *util.ConfigData, log []engine.ScheduleEntry, budget time.Duration, seed int64) (*Result, error) {
+	a := &annealer{
+		config:  config,
+		obj:     engine.NewObjective(config.OptimizeTargets, config.Processes, config.Stocks),
+		checker: &checker.Checker{Stocks: config.Stocks, Processes: config.Processes, Capacities: config.Capacities, ShelfLife: config.ShelfLife, Deliveries: config.Deliveries, Orders: config.Orders},
+		rng:     rand.New(rand.NewSource(seed)),
+	}
+	if len(config.Processes) == 0 {
+		return nil, fmt.Errorf("configuration has no process")
+	}
+
+	current := sortedCopy(log)
+	currentScore, err := a.score(current)
+	if err != nil {
+		return nil, fmt.Errorf("initial log is invalid: %w", err)
+	}
+
+	for _, p := range config.Processes {
+		if p.Cycle > a.maxSpan {
+			a.maxSpan = p.Cycle
+		}
+	}
+	for _, entry := range current {
+		if entry.Cycle > a.horizon {
+			a.horizon = entry.Cycle
+		}
+	}
+	a.horizon += a.maxSpan
+
+	result := &Result{
+		Entries: current,
+		Before:  currentScore,
+		After:   currentScore,
+	}
+
+	// One target unit outweighs any makespan a candidate can have; a unit of order
+	// penalty weighs as much as a target unit and an unfilled order as a whole horizon
+	// of them, which only guides the walk: the best schedule is still picked by Better
+	scale := float64(a.horizon + a.maxSpan + 1)
+	energy := func(s engine.Score) float64 {
+		return float64(s.Unfilled)*scale*scale + float64(s.Penalty-s.Quantity)*scale + float64(s.Makespan)
+	}
+
+	// The initial temperature makes a move costing the longest process duration
+	// in makespan as likely to be accepted as not
+	initial := float64(max(a.maxSpan, 1)) / math.Ln2
+	if initial < finalTemperature {
+		initial = finalTemperature
+	}
+
+	sinceBest := 0
+	start := time.Now()
+	for elapsed := time.Duration(0); elapsed < budget; elapsed = time.Since(start) {
+		temperature := initial * math.Pow(finalTemperature/initial, float64(elapsed)/float64(budget))
+
+		sinceBest++
+		if sinceBest > restartAfter {
+			current, currentScore = result.Entries, result.After
+			sinceBest = 0
+		}
+
+		candidate := a.perturb(current)
+		result.Tried++
+		candidateScore, err := a.score(candidate)
+		if err != nil {
+			continue
+		}
+
+		delta := energy(candidateScore) - energy(currentScore)
+		if delta > 0 && a.rng.Float64() >= math.Exp(-delta/temperature) {
+			continue
+		}
+		current, currentScore = candidate, candidateScore
+		result.Accepted++
+
+		if a.obj.Better(currentScore, result.After) {
+			result.Entries, result.After = current, currentScore
+			sinceBest = 0
+		}
+	}
+
+	a.checker.Log = result.Entries
+	ending, err := a.checker.Ending()
+	if err != nil {
+		return nil, fmt.Errorf("improved log is invalid: %w", err)
+	}
+	result.Cycle, result.TimedOut = ending.Cycle, ending.TimedOut
+	return result, nil
+}
+
+// score replays a schedule with the checker rules and scores its final stock and orders.
+func (a *annealer) score(entries []engine.ScheduleEntry) (engine.Score, error) {
+	a.checker.Log = entries
+	stocks, orders, err := a.checker.ReplayOrders()
+	if err != nil {
+		return engine.Score{}, err
+	}
+	score := a.obj.Evaluate(stocks, a.obj.MakespanOf(entries, a.config.Processes))
+	for _, o := range orders {
+		if o.Shipped < 0 {
+			score.Unfilled++
+		}
+		score.Penalty += o.Cost
+	}
+	return score, nil
+}
+
+// perturb returns a copy of the schedule changed by one random move, ordered by cycle.
+func (a *annealer) perturb(entries []engine.ScheduleEntry) []engine.ScheduleEntry {
+	next := sortedCopy(entries)
+
+	move := a.rng.Intn(4)
+	if len(next) == 0 {
+		move = 2
+	}
+	switch move {
+	case 0: // shift a start cycle
+		i := a.rng.Intn(len(next))
+		cycle := next[i].Cycle + a.rng.Intn(2*a.maxSpan+1) - a.maxSpan
+		next[i].Cycle = min(max(cycle, 0), a.horizon)
+	case 1: // swap the processes of two entries
+		i, j := a.rng.Intn(len(next)), a.rng.Intn(len(next))
+		next[i].ProcessName, next[j].ProcessName = next[j].ProcessName, next[i].ProcessName
+	case 2: // insert a run
+		p := a.config.Processes[a.rng.Intn(len(a.config.Processes))]
+		next = append(next, engine.ScheduleEntry{Cycle: a.rng.Intn(a.horizon + 1), ProcessName: p.Name})
+	default: // remove a run
+		i := a.rng.Intn(len(next))
+		next = append(next[:i], next[i+1:]...)
+	}
+
+	sort.SliceStable(next, func(i, j int) bool { return next[i].Cycle < next[j].Cycle })
+	return next
+}
+
+// sortedCopy returns a copy of the entries ordered by cycle.
+func sortedCopy(entries []engine.ScheduleEntry) []engine.ScheduleEntry {
+	sorted := make([]engine.ScheduleEntry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Cycle < sorted[j].Cycle })
+	return sorted
+}
@@ -0,0 +1,64 @@
+package improve
+
+import (
+	"testing"
+	"time"
+
+	checker "github.com/jesee-kuya/stock_exchange/checker_util"
+	"github.com/jesee-kuya/stock_exchange/engine"
+	"github.com/jesee-kuya/stock_exchange/process"
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// cabinetConfig returns the cabinet example from the README.
+func cabinetConfig() *util.ConfigData {
+	return &util.ConfigData{
+		Stocks: map[string]int{"board": 7},
+		Processes: []*process.Process{
+			{Name: "do_doorknobs", Needs: map[string]int{"board": 1}, Result: map[string]int{"doorknobs": 1}, Cycle: 15},
+			{Name: "do_background", Needs: map[string]int{"board": 2}, Result: map[string]int{"background": 1}, Cycle: 20},
+			{Name: "do_shelf", Needs: map[string]int{"board": 1}, Result: map[string]int{"shelf": 1}, Cycle: 10},
+			{Name: "do_cabinet", Needs: map[string]int{"doorknobs": 2, "background": 1, "shelf": 3}, Result: map[string]int{"cabinet": 1}, Cycle: 30},
+		},
+		OptimizeTargets: []string{"time", "cabinet"},
+	}
+}
+
+// TestAnneal tests that annealing shortens a valid but late cabinet schedule and
+// that the result still passes the checker.
+func TestAnneal(t *testing.T) {
+	config := cabinetConfig()
+	log := []engine.ScheduleEntry{
+		{Cycle: 0, ProcessName: "do_shelf"},
+		{Cycle: 0, ProcessName: "do_shelf"},
+		{Cycle: 0, ProcessName: "do_shelf"},
+		{Cycle: 0, ProcessName: "do_doorknobs"},
+		{Cycle: 0, ProcessName: "do_doorknobs"},
+		{Cycle: 10, ProcessName: "do_background"},
+		{Cycle: 30, ProcessName: "do_cabinet"},
+	}
+
+	result, err := Anneal(config, log, 500*time.Millisecond, 1)
+	if err != nil {
+		t.Fatalf("Anneal returned an error: %v", err)
+	}
+	if result.Before.Makespan != 60 {
+		t.Errorf("Before.Makespan = %d, want 60", result.Before.Makespan)
+	}
+	if result.After.Quantity != 1 || result.After.Makespan >= 60 {
+		t.Errorf("expected 1 cabinet before cycle 60, got %+v", result.After)
+	}
+
+	chk := &checker.Checker{Stocks: config.Stocks, Processes: config.Processes, Log: result.Entries}
+	if _, err := chk.Replay(); err != nil {
+		t.Errorf("improved log does not verify: %v", err)
+	}
+}
+
+// TestAnnealInvalidLog tests that an invalid starting log is rejected.
+func TestAnnealInvalidLog(t *testing.T) {
+	log := []engine.ScheduleEntry{{Cycle: 0, ProcessName: "do_cabinet"}}
+	if _, err := Anneal(cabinetConfig(), log, time.Millisecond, 1); err == nil {
+		t.Error("Anneal should reject a log that does not verify")
+	}
+}
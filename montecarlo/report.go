@@ -0,0 +1,50 @@
+package montecarlo
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// WriteText writes the result as a table with one row per measure: the value planned
+// with the nominal durations and expected results, then the mean, median, 95th percentile
+// and worst value over the runs.
+func (r *Result) WriteText(w io.Writer) error {
+	lines := []string{
+		fmt.Sprintf("Simulated %d runs", r.Runs),
+		fmt.Sprintf("  %-20s %8s %8s %8s %8s %8s", "measure", "planned", "mean", "p50", "p95", "worst"),
+		statsRow("makespan", r.Planned.Makespan, r.Makespan),
+		statsRow("target quantity", r.Planned.Quantity, r.Quantity),
+	}
+	targets := make([]string, 0, len(r.Targets))
+	for item := range r.Targets {
+		targets = append(targets, item)
+	}
+	sort.Strings(targets)
+	if len(targets) > 1 {
+		for _, item := range targets {
+			lines = append(lines, statsRow("  "+item, r.PlannedStock[item], r.Targets[item]))
+		}
+	}
+	if r.Planned.Penalty > 0 || r.Penalty.Worst > 0 {
+		lines = append(lines, statsRow("order penalty", r.Planned.Penalty, r.Penalty))
+	}
+	if r.Unfilled > 0 {
+		lines = append(lines, fmt.Sprintf("%d of %d runs left an order unfilled", r.Unfilled, r.Runs))
+	}
+	if r.Incomplete > 0 {
+		lines = append(lines, fmt.Sprintf("%d of %d runs could not start every entry of the schedule", r.Incomplete, r.Runs))
+	}
+
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statsRow formats one row of the table.
+func statsRow(name string, planned int, s Stats) string {
+	return fmt.Sprintf("  %-20s %8d %8.2f %8d %8d %8d", name, planned, s.Mean, s.P50, s.P95, s.Worst)
+}
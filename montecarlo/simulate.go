@@ -0,0 +1,354 @@
+// Package montecarlo evaluates a schedule against the duration distributions, failures
+// and yield ranges of its processes, replaying it many times with random durations and
+// outcomes to measure how late it can finish and how much it can fall short.
+package montecarlo
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/jesee-kuya/stock_exchange/engine"
+	"github.com/jesee-kuya/stock_exchange/process"
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// Policy decides when a replayed entry starts once its durations no longer match the plan.
+type Policy string
+
+// Replay policies.
+const (
+	// Planned starts each entry at its planned cycle, or later when its needs arrive late.
+	Planned Policy = "planned"
+	// Eager starts each entry as soon as its needs are in stock, keeping the planned order.
+	Eager Policy = "eager"
+)
+
+// ParsePolicy returns the policy with the given name.
+func ParsePolicy(name string) (Policy, error) {
+	switch policy := Policy(name); policy {
+	case Planned, Eager:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown policy '%s', expected planned or eager", name)
+}
+
+// Stats summarizes one measure over the runs of a simulation, from the point of view of
+// someone quoting it: P50 and P95 are the values that half and 95% of the runs meet or
+// beat, and Worst is the value all of them do. For makespans that means the runs that
+// finish no later; for quantities, the runs that produce no less.
+type Stats struct {
+	Mean  float64
+	P50   int
+	P95   int
+	Worst int
+}
+
+// Result is the outcome of a simulation.
+//
+// Fields:
+//   - Runs: the number of replays.
+//   - Planned: the score of the schedule with the nominal durations and the expected
+//     results it was planned with (see process.Process.PlanExpected).
+//   - PlannedStock: the final stock with the nominal durations and expected results.
+//   - Makespan: the cycle at which the last target unit was produced.
+//   - Quantity: the final stock of all the targets together.
+//   - Targets: the final stock of each target.
+//   - Incomplete: the number of runs in which at least one entry could never start, its
+//     needs having been used up or gone bad while other entries ran late or early.
+//   - Penalty: the total cost of the orders shipped late, when the configuration has orders.
+//   - Unfilled: the number of runs in which at least one order never shipped.
+type Result struct {
+	Runs         int
+	Planned      engine.Score
+	PlannedStock map[string]int
+	Makespan     Stats
+	Quantity     Stats
+	Targets      map[string]Stats
+	Incomplete   int
+
+	Penalty  Stats
+	Unfilled int
+}
+
+// replay holds the data shared by the runs of one simulation.
+type replay struct {
+	config  *util.ConfigData
+	obj     engine.Objective
+	entries []engine.ScheduleEntry
+	byEntry []*process.Process
+	policy  Policy
+}
+
+// outcome is the result of one run.
+type outcome struct {
+	makespan int
+	stock    map[string]int
+	dropped  int
+	orders   []engine.OrderResult
+}
+
+// score scores the run like the schedulers do.
+func (r *replay) score(o outcome) engine.Score {
+	return r.obj.EvaluatePlan(&engine.Plan{Stock: o.stock, Makespan: o.makespan, Orders: o.orders})
+}
+
+// running is a process started during a run.
+type running struct {
+	p   *process.Process
+	end int
+}
+
+// Simulate replays a schedule runs times, drawing the duration of every process with a
+// duration distribution and the outcome of every process that can fail or has a yield
+// range anew each time, and reports the distribution of its makespan and final target
+// stock.
+//
+// Behavior:
+//   - The entries are started in the order of the schedule, one planned cycle at a time. An
+//     entry starts once its needs are in stock and its results fit in storage, and, with
+//     the Planned policy, not before its planned cycle. The entries of a planned cycle that
+//     cannot start yet are tried again after the others of that cycle, which may make room
+//     for them; a late entry holds back the cycles after its own.
+//   - An entry that cannot start while nothing is running and no delivery is expected never
+//     will, and is dropped.
+//   - Processes without a distribution always take their cycle count.
+//   - A failed run produces nothing but the renewable resources it held, and does not count
+//     toward the makespan, while a successful one produces the full results drawn in their
+//     ranges; the plan counted on their expected quantities instead. The schedule is
+//     replayed as is, without planning again.
+//   - Perishable items expire as in the schedulers, so a late consumer can find its
+//     ingredients gone.
+//   - Deliveries arrive and orders ship as in the schedulers (see engine.Timeline), so a
+//     late producer can make an order late.
+//   - The runs only depend on the seed, so a simulation can be repeated exactly.
+//
+// Parameters:
+//   - config: the parsed configuration the schedule was made for.
+//   - log: the schedule to replay.
+//   - policy: when to start the entries.
+//   - runs: the number of replays, at least one.
+//   - seed: the seed of the random number generator.
+//
+// Returns:
+//   - The statistics of the runs, or an error if runs is not positive or the schedule
+//     names an unknown process.
+func Simulate(config *util.ConfigData, log []engine.ScheduleEntry, policy Policy, runs int, seed int64) (*Result, error) {
+	if runs <= 0 {
+		return nil, fmt.Errorf("number of runs must be positive, got %d", runs)
+	}
+	byName := make(map[string]*process.Process, len(config.Processes))
+	for _, p := range config.Processes {
+		byName[p.Name] = p
+	}
+
+	r := &replay{
+		config:  config,
+		obj:     engine.NewObjective(config.OptimizeTargets, config.Processes, config.Stocks),
+		entries: make([]engine.ScheduleEntry, len(log)),
+		byEntry: make([]*process.Process, len(log)),
+		policy:  policy,
+	}
+	copy(r.entries, log)
+	sort.SliceStable(r.entries, func(i, j int) bool { return r.entries[i].Cycle < r.entries[j].Cycle })
+	for i, entry := range r.entries {
+		p, ok := byName[entry.ProcessName]
+		if !ok {
+			return nil, fmt.Errorf("cycle %d: unknown process '%s'", entry.Cycle, entry.ProcessName)
+		}
+		r.byEntry[i] = p
+	}
+
+	planned := r.run(nil)
+	result := &Result{
+		Runs:         runs,
+		Planned:      r.score(planned),
+		PlannedStock: planned.stock,
+		Targets:      map[string]Stats{},
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	makespans := make([]int, runs)
+	quantities := make([]int, runs)
+	penalties := make([]int, runs)
+	targets := map[string][]int{}
+	for i := range runs {
+		o := r.run(rng)
+		score := r.score(o)
+		makespans[i], quantities[i], penalties[i] = score.Makespan, score.Quantity, score.Penalty
+		if score.Unfilled > 0 {
+			result.Unfilled++
+		}
+		for item := range r.obj.Items {
+			targets[item] = append(targets[item], o.stock[item])
+		}
+		if o.dropped > 0 {
+			result.Incomplete++
+		}
+	}
+
+	result.Makespan = newStats(makespans, false)
+	result.Quantity = newStats(quantities, true)
+	result.Penalty = newStats(penalties, false)
+	for item, values := range targets {
+		result.Targets[item] = newStats(values, true)
+	}
+	return result, nil
+}
+
+// run replays the schedule once, drawing the durations and outcomes from rng, or using
+// the nominal durations and the expected results when rng is nil.
+func (r *replay) run(rng *rand.Rand) outcome {
+	stock := make(map[string]int, len(r.config.Stocks))
+	for item, qty := range r.config.Stocks {
+		stock[item] = qty
+	}
+	lots := engine.NewLots(r.config.ShelfLife, stock)
+	incoming := map[string]int{}
+	var active []running
+	o := outcome{stock: stock}
+
+	cycle, next := 0, 0
+	timeline := engine.NewTimeline(r.config.Deliveries, r.config.Orders)
+	receive := func(item string, qty int) {
+		stock[item] += qty
+		if qty > 0 {
+			lots.Add(item, qty, cycle)
+		} else {
+			lots.Take(item, -qty)
+		}
+	}
+	start := func(i int) bool {
+		p := r.byEntry[i]
+		if !p.CanRun(stock) || !p.Fits(stock, incoming, r.config.Capacities) {
+			return false
+		}
+		for item, qty := range p.Needs {
+			stock[item] -= qty
+			lots.Take(item, qty)
+		}
+		for item, qty := range p.MostResult() {
+			incoming[item] += qty
+		}
+		active = append(active, running{p: p, end: cycle + duration(p, rng)})
+		return true
+	}
+
+	// waiting holds the entries of the planned cycle being started that have not started yet
+	var waiting []int
+	timeline.Advance(cycle, stock, receive)
+	for {
+		for {
+			if len(waiting) == 0 {
+				if next == len(r.entries) || r.policy == Planned && r.entries[next].Cycle > cycle {
+					break
+				}
+				for planned := r.entries[next].Cycle; next < len(r.entries) && r.entries[next].Cycle == planned; next++ {
+					waiting = append(waiting, next)
+				}
+			}
+			// Go over the entries again as long as one starts, since a consumer can make
+			// room in storage for an entry logged before it
+			for progress := true; progress && len(waiting) > 0; {
+				progress = false
+				blocked := waiting[:0]
+				for _, i := range waiting {
+					if start(i) {
+						progress = true
+					} else {
+						blocked = append(blocked, i)
+					}
+				}
+				waiting = blocked
+			}
+			if len(waiting) > 0 {
+				if len(active) > 0 || timeline.Pending() {
+					break
+				}
+				o.dropped += len(waiting)
+				waiting = waiting[:0]
+			}
+		}
+
+		// Move on to the next completion or delivery, or to the next planned start if it comes first
+		upcoming := timeline.Next()
+		for _, a := range active {
+			upcoming = min(upcoming, a.end)
+		}
+		if r.policy == Planned && len(waiting) == 0 && next < len(r.entries) && r.entries[next].Cycle > cycle {
+			upcoming = min(upcoming, r.entries[next].Cycle)
+		}
+		if upcoming == math.MaxInt {
+			o.orders = timeline.Results()
+			return o
+		}
+		cycle = upcoming
+
+		remaining := active[:0]
+		for _, a := range active {
+			if a.end > cycle {
+				remaining = append(remaining, a)
+				continue
+			}
+			result := a.p.NominalOutcome()
+			if rng != nil && a.p.Stochastic() {
+				result = a.p.Draw(rng)
+			}
+			for item, qty := range a.p.OutputOf(result) {
+				stock[item] += qty
+				lots.Add(item, qty, cycle)
+			}
+			for item, qty := range a.p.MostResult() {
+				incoming[item] -= qty
+			}
+			if !result.Failed && r.obj.ProducesTarget(a.p) {
+				o.makespan = max(o.makespan, cycle)
+			}
+		}
+		active = remaining
+		for item, qty := range lots.Expire(cycle) {
+			stock[item] -= qty
+		}
+		timeline.Advance(cycle, stock, receive)
+	}
+}
+
+// duration returns the number of cycles a run of the process takes: a draw from its
+// distribution, at least one cycle, or its cycle count when it has none or rng is nil.
+func duration(p *process.Process, rng *rand.Rand) int {
+	if p.Duration == nil || rng == nil {
+		return p.Cycle
+	}
+	return max(p.Duration.Sample(rng), 1)
+}
+
+// newStats summarizes the values of a measure over the runs. higherIsBetter tells
+// whether the runs with the largest values are the best ones.
+func newStats(values []int, higherIsBetter bool) Stats {
+	sorted := append([]int(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if higherIsBetter {
+			return sorted[i] > sorted[j]
+		}
+		return sorted[i] < sorted[j]
+	})
+
+	sum := 0
+	for _, v := range sorted {
+		sum += v
+	}
+	return Stats{
+		Mean:  float64(sum) / float64(len(sorted)),
+		P50:   percentile(sorted, 0.50),
+		P95:   percentile(sorted, 0.95),
+		Worst: sorted[len(sorted)-1],
+	}
+}
+
+// percentile returns the value that the given fraction of the values, sorted from the
+// best to the worst, meet or beat, by the nearest-rank method.
+func percentile(sorted []int, fraction float64) int {
+	rank := int(math.Ceil(fraction * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
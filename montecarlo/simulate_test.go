@@ -0,0 +1,160 @@
+package montecarlo
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/jesee-kuya/stock_exchange/engine"
+	"github.com/jesee-kuya/stock_exchange/process"
+	"github.com/jesee-kuya/stock_exchange/util"
+)
+
+// cabinetConfig returns the cabinet example from the README.
+func cabinetConfig() *util.ConfigData {
+	return &util.ConfigData{
+		Stocks: map[string]int{"board": 7},
+		Processes: []*process.Process{
+			{Name: "do_doorknobs", Needs: map[string]int{"board": 1}, Result: map[string]int{"doorknobs": 1}, Cycle: 15},
+			{Name: "do_background", Needs: map[string]int{"board": 2}, Result: map[string]int{"background": 1}, Cycle: 20},
+			{Name: "do_shelf", Needs: map[string]int{"board": 1}, Result: map[string]int{"shelf": 1}, Cycle: 10},
+			{Name: "do_cabinet", Needs: map[string]int{"doorknobs": 2, "background": 1, "shelf": 3}, Result: map[string]int{"cabinet": 1}, Cycle: 30},
+		},
+		OptimizeTargets: []string{"time", "cabinet"},
+	}
+}
+
+// cabinetLog is a valid cabinet schedule that starts the background later than it could.
+var cabinetLog = []engine.ScheduleEntry{
+	{Cycle: 0, ProcessName: "do_shelf"},
+	{Cycle: 0, ProcessName: "do_shelf"},
+	{Cycle: 0, ProcessName: "do_shelf"},
+	{Cycle: 0, ProcessName: "do_doorknobs"},
+	{Cycle: 0, ProcessName: "do_doorknobs"},
+	{Cycle: 10, ProcessName: "do_background"},
+	{Cycle: 30, ProcessName: "do_cabinet"},
+}
+
+// TestSimulateFixedDurations tests that without distributions every run matches the plan,
+// and that the eager policy starts the background as soon as it can.
+func TestSimulateFixedDurations(t *testing.T) {
+	testCases := []struct {
+		policy   Policy
+		makespan int
+	}{
+		{Planned, 60},
+		{Eager, 50},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.policy), func(t *testing.T) {
+			result, err := Simulate(cabinetConfig(), cabinetLog, tc.policy, 10, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Planned.Makespan != tc.makespan || result.Planned.Quantity != 1 {
+				t.Errorf("Planned = %+v, want 1 cabinet at cycle %d", result.Planned, tc.makespan)
+			}
+			want := Stats{Mean: float64(tc.makespan), P50: tc.makespan, P95: tc.makespan, Worst: tc.makespan}
+			if result.Makespan != want {
+				t.Errorf("Makespan = %+v, want %+v", result.Makespan, want)
+			}
+			if result.Quantity != (Stats{Mean: 1, P50: 1, P95: 1, Worst: 1}) || result.Incomplete != 0 {
+				t.Errorf("Quantity = %+v with %d incomplete runs, want 1 cabinet every run", result.Quantity, result.Incomplete)
+			}
+		})
+	}
+}
+
+// TestSimulateDurations tests that drawn durations spread the makespan around the plan
+// and that a seed always gives the same result.
+func TestSimulateDurations(t *testing.T) {
+	config := cabinetConfig()
+	config.Processes[3].Duration = &process.Duration{Kind: process.Triangular, Min: 20, Mode: 25, Max: 45}
+
+	result, err := Simulate(config, cabinetLog, Planned, 500, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := result.Makespan
+	if result.Planned.Makespan != 60 || m.P50 > m.P95 || m.P95 > m.Worst || m.Worst > 75 || m.Mean < 50 {
+		t.Errorf("Makespan = %+v with a plan of %d, want values from 50 to 75", m, result.Planned.Makespan)
+	}
+	if m.Worst <= m.P50 {
+		t.Errorf("Makespan = %+v, want a spread of values", m)
+	}
+
+	again, err := Simulate(config, cabinetLog, Planned, 500, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result, again) {
+		t.Errorf("the same seed gave %+v and %+v", result, again)
+	}
+}
+
+// ovenConfig returns a bakery whose ovens fill the bread storage, so each cycle has to
+// sell before it bakes again. Yields and durations are fixed, so nothing is drawn.
+func ovenConfig() *util.ConfigData {
+	return &util.ConfigData{
+		Stocks:     map[string]int{"flour": 10, "bread": 0, "oven": 2},
+		Capacities: map[string]int{"bread": 6},
+		Renewables: map[string]int{"oven": 2},
+		Processes: []*process.Process{
+			{Name: "bake", Needs: map[string]int{"flour": 1, "oven": 1}, Result: map[string]int{"bread": 3}, Cycle: 5,
+				Holds: map[string]int{"oven": 1}, Yields: map[string]process.Yield{"bread": {Min: 3, Max: 3}}},
+			{Name: "sell", Needs: map[string]int{"bread": 3}, Result: map[string]int{"euro": 10}, Cycle: 1},
+		},
+		OptimizeTargets: []string{"euro"},
+	}
+}
+
+// TestSimulateDeterministic tests that a run with nothing to draw reproduces the plan of
+// the scheduler, and that entries of a cycle logged before the ones making room for them
+// still start.
+func TestSimulateDeterministic(t *testing.T) {
+	config := ovenConfig()
+	scheduler, err := engine.LookupScheduler("greedy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := scheduler.Schedule(config, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := engine.NewObjective(config.OptimizeTargets, config.Processes, config.Stocks).EvaluatePlan(plan)
+
+	// The same plan with the bakes of each cycle logged ahead of the sales
+	reordered := append([]engine.ScheduleEntry(nil), plan.Entries...)
+	sort.SliceStable(reordered, func(i, j int) bool {
+		if reordered[i].Cycle != reordered[j].Cycle {
+			return reordered[i].Cycle < reordered[j].Cycle
+		}
+		return reordered[i].ProcessName < reordered[j].ProcessName
+	})
+
+	for name, log := range map[string][]engine.ScheduleEntry{"scheduled": plan.Entries, "reordered": reordered} {
+		for _, policy := range []Policy{Planned, Eager} {
+			result, err := Simulate(config, log, policy, 10, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Incomplete != 0 || result.Planned != want {
+				t.Errorf("%s log, %s policy: Planned = %+v with %d incomplete runs, want %+v and none",
+					name, policy, result.Planned, result.Incomplete, want)
+			}
+		}
+	}
+}
+
+// TestSimulateErrors tests that unknown processes and non-positive run counts are rejected.
+func TestSimulateErrors(t *testing.T) {
+	if _, err := Simulate(cabinetConfig(), cabinetLog, Planned, 0, 1); err == nil {
+		t.Error("expected an error for zero runs")
+	}
+	log := []engine.ScheduleEntry{{Cycle: 0, ProcessName: "do_table"}}
+	if _, err := Simulate(cabinetConfig(), log, Planned, 1, 1); err == nil {
+		t.Error("expected an error for an unknown process")
+	}
+}
@@ -5,6 +5,9 @@ package process
 // and the value is the quantity available. The function iterates over the
 // resources required by the process (p.Needs) and returns false if any
 // required resource is missing or insufficient in the provided stocks map.
+// Renewable resources (p.Holds) are part of Needs, and their stock is the capacity
+// not held by running processes, so a process cannot start while the units it
+// holds are all in use.
 // Returns true only if all required resources are available in the needed quantities.
 func (p *Process) CanRun(stocks map[string]int) bool {
 	for resource, required := range p.Needs {
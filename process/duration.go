@@ -0,0 +1,121 @@
+package process
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+)
+
+// DurationKind names a duration distribution.
+type DurationKind string
+
+// Duration distributions.
+const (
+	Uniform    DurationKind = "uniform"
+	Triangular DurationKind = "triangular"
+	Histogram  DurationKind = "histogram"
+)
+
+// Duration is the distribution of the number of cycles a process takes, for processes
+// whose duration varies from one run to the next.
+//
+// Fields:
+//   - Kind: the distribution.
+//   - Min, Mode, Max: the bounds of a uniform distribution, and the bounds and most likely
+//     value of a triangular one; Mode is unused for uniform distributions.
+//   - Values, Weights: the durations of an empirical histogram and how often each one was
+//     observed, parallel slices.
+type Duration struct {
+	Kind    DurationKind
+	Min     int
+	Mode    int
+	Max     int
+	Values  []int
+	Weights []int
+}
+
+// Sample draws a duration from the distribution. Uniform durations are equally likely
+// integers between Min and Max included; triangular ones are drawn as real numbers and
+// rounded; histogram ones are drawn in proportion to their weights.
+func (d *Duration) Sample(rng *rand.Rand) int {
+	switch d.Kind {
+	case Uniform:
+		return d.Min + rng.Intn(d.Max-d.Min+1)
+	case Triangular:
+		if d.Max == d.Min {
+			return d.Min
+		}
+		a, b, c := float64(d.Min), float64(d.Max), float64(d.Mode)
+		u := rng.Float64()
+		if u < (c-a)/(b-a) {
+			return int(math.Round(a + math.Sqrt(u*(b-a)*(c-a))))
+		}
+		return int(math.Round(b - math.Sqrt((1-u)*(b-a)*(b-c))))
+	}
+	total := 0
+	for _, w := range d.Weights {
+		total += w
+	}
+	r := rng.Intn(total)
+	for i, w := range d.Weights {
+		if r < w {
+			return d.Values[i]
+		}
+		r -= w
+	}
+	return d.Values[len(d.Values)-1]
+}
+
+// Mean returns the expected duration.
+func (d *Duration) Mean() float64 {
+	switch d.Kind {
+	case Uniform:
+		return float64(d.Min+d.Max) / 2
+	case Triangular:
+		return float64(d.Min+d.Mode+d.Max) / 3
+	}
+	sum, total := 0, 0
+	for i, w := range d.Weights {
+		sum += d.Values[i] * w
+		total += w
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(sum) / float64(total)
+}
+
+// Range returns the shortest and the longest duration the distribution can draw.
+func (d *Duration) Range() (int, int) {
+	if d.Kind != Histogram {
+		return d.Min, d.Max
+	}
+	lo, hi := math.MaxInt, math.MinInt
+	for _, v := range d.Values {
+		lo, hi = min(lo, v), max(hi, v)
+	}
+	return lo, hi
+}
+
+// Nominal returns the mean duration rounded to a whole number of cycles, which the
+// schedulers and the checker use as the duration of the process.
+func (d *Duration) Nominal() int {
+	return int(math.Round(d.Mean()))
+}
+
+// String returns the distribution in the configuration syntax, such as "uniform(4;8)",
+// "triangular(4;5;9)" or "histogram(4:1;5:3;9:1)".
+func (d *Duration) String() string {
+	switch d.Kind {
+	case Uniform:
+		return fmt.Sprintf("uniform(%d;%d)", d.Min, d.Max)
+	case Triangular:
+		return fmt.Sprintf("triangular(%d;%d;%d)", d.Min, d.Mode, d.Max)
+	}
+	bins := make([]string, len(d.Values))
+	for i, v := range d.Values {
+		bins[i] = fmt.Sprintf("%d:%d", v, d.Weights[i])
+	}
+	return "histogram(" + strings.Join(bins, ";") + ")"
+}
@@ -0,0 +1,28 @@
+package process
+
+// Fits checks if the results of the process fit in storage when it starts with the given stocks.
+// For every produced item with a storage capacity, the stock left once the needs are taken,
+// plus the quantity already on its way from running processes, plus the largest quantity a
+// run can produce (see MostResult) must not exceed the capacity. Counting production in
+// flight means that a process is only started when there will be room for its results,
+// whatever else completes meanwhile; the incoming quantities must therefore count the
+// largest results of the running processes too. This is the one storage rule shared by the
+// schedulers, the execution of a plan and the checker, so a process with a varying yield
+// is never started on the hope of a small one.
+// Items without a capacity never limit the process.
+// Returns true if every result fits, and always when capacities is empty.
+func (p *Process) Fits(stocks, incoming, capacities map[string]int) bool {
+	if len(capacities) == 0 {
+		return true
+	}
+	for item, produced := range p.MostResult() {
+		capacity, ok := capacities[item]
+		if !ok {
+			continue
+		}
+		if stocks[item]-p.Needs[item]+incoming[item]+produced > capacity {
+			return false
+		}
+	}
+	return true
+}
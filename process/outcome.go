@@ -0,0 +1,228 @@
+package process
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Yield is the range of the quantity of a result that varies from one run to the next.
+type Yield struct {
+	Min int
+	Max int
+}
+
+// Expected returns the quantity the schedulers plan with for a run failing with the given
+// probability: the expected quantity (1-failure)·(Min+Max)/2, rounded to the nearest whole
+// number with halves rounded down. Without failures this is the middle of the range rounded
+// down; rounding to the nearest rather than down keeps the single unit of a process that
+// rarely fails in the plan.
+func (y Yield) Expected(failure float64) int {
+	// The margin keeps halves computed a rounding error above their exact value down
+	return int(math.Ceil((1-failure)*float64(y.Min+y.Max)/2 - 0.5 - 1e-9))
+}
+
+// String returns the range in the configuration syntax, such as "2-3", or the quantity
+// alone, such as "3", for a one-value range.
+func (y Yield) String() string {
+	if y.Min == y.Max {
+		return fmt.Sprintf("%d", y.Min)
+	}
+	return fmt.Sprintf("%d-%d", y.Min, y.Max)
+}
+
+// Outcome is what one run of a process turned out to produce.
+//
+// Fields:
+//   - Failed: the run failed; its needs are consumed but it produces nothing, except for
+//     the renewable resources it held, which are given back all the same.
+//   - Result: the quantity of each result produced by a successful run.
+type Outcome struct {
+	Failed bool
+	Result map[string]int
+}
+
+// String returns the outcome as written in outcome traces: "failed", or the results of
+// the run in lexical order, such as "(bread:3;crumbs:1)".
+func (o Outcome) String() string {
+	if o.Failed {
+		return "failed"
+	}
+	parts := make([]string, 0, len(o.Result))
+	for _, item := range sortedItems(o.Result) {
+		parts = append(parts, fmt.Sprintf("%s:%d", item, o.Result[item]))
+	}
+	return "(" + strings.Join(parts, ";") + ")"
+}
+
+// ParseOutcome reads an outcome in the format of Outcome.String: "failed", or results such
+// as "(bread:3;crumbs:1)", possibly empty.
+func ParseOutcome(s string) (Outcome, error) {
+	s = strings.TrimSpace(s)
+	if s == "failed" {
+		return Outcome{Failed: true}, nil
+	}
+	if !strings.HasPrefix(s, "(") || !strings.HasSuffix(s, ")") {
+		return Outcome{}, fmt.Errorf("malformed outcome %q, expected failed or (<item>:<quantity>;...)", s)
+	}
+	result := map[string]int{}
+	body := strings.TrimSpace(s[1 : len(s)-1])
+	if body == "" {
+		return Outcome{Result: result}, nil
+	}
+	for _, part := range strings.Split(body, ";") {
+		item, qtyStr, ok := strings.Cut(part, ":")
+		item = strings.TrimSpace(item)
+		qty, err := strconv.Atoi(strings.TrimSpace(qtyStr))
+		if !ok || item == "" || err != nil || qty < 0 {
+			return Outcome{}, fmt.Errorf("malformed result %q in outcome %q", part, s)
+		}
+		if _, dup := result[item]; dup {
+			return Outcome{}, fmt.Errorf("duplicate result '%s' in outcome %q", item, s)
+		}
+		result[item] = qty
+	}
+	return Outcome{Result: result}, nil
+}
+
+// Stochastic reports whether the runs of the process may fail or yield varying quantities.
+func (p *Process) Stochastic() bool {
+	return p.Failure > 0 || len(p.Yields) > 0
+}
+
+// PlanExpected sets Result to the expected quantity of each result (see Yield.Expected),
+// which the schedulers plan with, given Yields and Failure. A fixed result of a process
+// that can fail is first given a one-value range holding the quantity of a successful
+// run. Calling it again changes nothing.
+func (p *Process) PlanExpected() {
+	for _, item := range sortedItems(p.Result) {
+		y, ok := p.Yields[item]
+		if !ok {
+			if p.Failure <= 0 {
+				continue
+			}
+			if p.Yields == nil {
+				p.Yields = map[string]Yield{}
+			}
+			y = Yield{Min: p.Result[item], Max: p.Result[item]}
+			p.Yields[item] = y
+		}
+		p.Result[item] = y.Expected(p.Failure)
+	}
+}
+
+// NominalOutcome returns the outcome the schedulers plan with: the expected results (see
+// PlanExpected). For a process that may fail or yield varying quantities, no single run
+// need have this outcome.
+func (p *Process) NominalOutcome() Outcome {
+	return Outcome{Result: p.Result}
+}
+
+// Draw draws the outcome of one run: a failure with probability p.Failure, and otherwise
+// a success producing, for every result with a yield range, a quantity drawn uniformly in
+// the range. Results are drawn in lexical order, so the outcome only depends on rng.
+func (p *Process) Draw(rng *rand.Rand) Outcome {
+	if p.Failure > 0 && rng.Float64() < p.Failure {
+		return Outcome{Failed: true}
+	}
+	if len(p.Yields) == 0 {
+		return p.NominalOutcome()
+	}
+	result := make(map[string]int, len(p.Result))
+	for _, item := range sortedItems(p.Result) {
+		result[item] = p.Result[item]
+		if y, ok := p.Yields[item]; ok {
+			result[item] = y.Min + rng.Intn(y.Max-y.Min+1)
+		}
+	}
+	return Outcome{Result: result}
+}
+
+// Shortfall reports whether an outcome produced less of some result than planned.
+func (p *Process) Shortfall(o Outcome) bool {
+	if o.Failed {
+		return true
+	}
+	for item, qty := range p.Result {
+		if o.Result[item] < qty {
+			return true
+		}
+	}
+	return false
+}
+
+// MostResult returns the largest quantity of each result a run can produce: the upper
+// bound of its yield range, or its fixed quantity. The returned map must not be modified;
+// it is p.Result itself when no result varies.
+func (p *Process) MostResult() map[string]int {
+	if len(p.Yields) == 0 {
+		return p.Result
+	}
+	most := make(map[string]int, len(p.Result))
+	for item, qty := range p.Result {
+		most[item] = qty
+	}
+	for item, y := range p.Yields {
+		most[item] = y.Max
+	}
+	return most
+}
+
+// OutputOf returns the quantities added to the stock when a run with the given outcome
+// completes: the results it produced plus the renewable resources it held.
+func (p *Process) OutputOf(o Outcome) map[string]int {
+	output := make(map[string]int, len(o.Result)+len(p.Holds))
+	for item, qty := range o.Result {
+		output[item] = qty
+	}
+	for item, qty := range p.Holds {
+		output[item] += qty
+	}
+	return output
+}
+
+// CheckOutcome checks that a run of the process can have the given outcome.
+//
+// Returns:
+//   - An error if the run failed although the process cannot fail, or if it produced an
+//     item the process does not make, missed one of its results, or produced a quantity
+//     outside the yield range of a result, or other than the fixed quantity of the others.
+func (p *Process) CheckOutcome(o Outcome) error {
+	if o.Failed {
+		if p.Failure <= 0 {
+			return fmt.Errorf("'%s' cannot fail", p.Name)
+		}
+		return nil
+	}
+	for _, item := range sortedItems(o.Result) {
+		if _, ok := p.Result[item]; !ok {
+			return fmt.Errorf("'%s' does not produce '%s'", p.Name, item)
+		}
+	}
+	for _, item := range sortedItems(p.Result) {
+		qty, ok := o.Result[item]
+		y, varies := p.Yields[item]
+		switch {
+		case !ok:
+			return fmt.Errorf("'%s' must produce '%s'", p.Name, item)
+		case varies && (qty < y.Min || qty > y.Max):
+			return fmt.Errorf("'%s' yields %s '%s', got %d", p.Name, y, item, qty)
+		case !varies && qty != p.Result[item]:
+			return fmt.Errorf("'%s' produces %d '%s', got %d", p.Name, p.Result[item], item, qty)
+		}
+	}
+	return nil
+}
+
+// sortedItems returns the items of a quantity map in lexical order.
+func sortedItems(quantities map[string]int) []string {
+	items := make([]string, 0, len(quantities))
+	for item := range quantities {
+		items = append(items, item)
+	}
+	sort.Strings(items)
+	return items
+}
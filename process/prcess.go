@@ -3,9 +3,46 @@ package process
 // Process represents a processing unit in the stock exchange system.
 // It defines the name of the process, the required input resources (Needs),
 // the output resources produced (Result), and the number of cycles needed to complete the process.
+//
+// Holds lists the renewable resources among Needs, such as machines or workers: they
+// are taken from the stock at the start like any other need, but given back when the
+// process completes instead of being consumed. Holds is nil for processes that only
+// use consumable items.
+//
+// Duration is the distribution of the number of cycles of a process whose duration
+// varies, nil for a fixed one. Cycle is then its nominal duration (see Duration.Nominal),
+// which every scheduler plans with; only simulations draw from the distribution.
+//
+// Failure is the probability that a run fails and produces nothing, and Yields the range
+// of what a successful run produces of the results whose quantity varies from one run to
+// the next, nil when none does. Result then holds the expected quantity of each of them
+// (see PlanExpected), which the schedulers plan with; only runs drawing their outcomes
+// (see Draw) depart from it. For a process that can fail, every result has a range, a
+// fixed quantity being a one-value range, so that Result can hold its expected quantity.
 type Process struct {
-	Name   string
-	Needs  map[string]int
-	Result map[string]int
-	Cycle  int
+	Name     string
+	Needs    map[string]int
+	Result   map[string]int
+	Cycle    int
+	Holds    map[string]int
+	Duration *Duration
+	Failure  float64
+	Yields   map[string]Yield
+}
+
+// Output returns the quantities added to the stock when the process completes:
+// its results plus the renewable resources it held. The returned map must not be
+// modified; it is p.Result itself when the process holds nothing.
+func (p *Process) Output() map[string]int {
+	if len(p.Holds) == 0 {
+		return p.Result
+	}
+	output := make(map[string]int, len(p.Result)+len(p.Holds))
+	for item, qty := range p.Result {
+		output[item] = qty
+	}
+	for item, qty := range p.Holds {
+		output[item] += qty
+	}
+	return output
 }
@@ -0,0 +1,518 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jesee-kuya/stock_exchange/process"
+)
+
+// Format is a configuration file format.
+type Format string
+
+// Configuration formats. FormatAuto picks one from the file extension.
+const (
+	FormatAuto Format = ""
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+)
+
+// ParseFormat returns the format with the given name: "text", "json", "yaml", or "auto"
+// and the empty string for FormatAuto.
+func ParseFormat(name string) (Format, error) {
+	switch Format(name) {
+	case FormatText, FormatJSON, FormatYAML:
+		return Format(name), nil
+	case FormatAuto, "auto":
+		return FormatAuto, nil
+	}
+	return FormatAuto, fmt.Errorf("unknown config format %q, expected text, json or yaml", name)
+}
+
+// FormatFromPath returns the format of a configuration file from its extension:
+// ".json" for JSON, ".yaml" or ".yml" for YAML, and the text format otherwise.
+func FormatFromPath(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	}
+	return FormatText
+}
+
+// ParseConfigAs reads a configuration file in the given format, or in the format of its
+// extension for FormatAuto.
+//
+// The JSON and YAML formats describe the same data as the text format, and item and
+// process names may contain any character, including ':' and ';':
+//
+//	{
+//	  "stocks": {"board": 7},
+//	  "renewables": {"saw": 1},
+//	  "capacities": {"shelf": 5},
+//	  "perishables": {"glue": 40},
+//	  "processes": [
+//	    {"name": "do_shelf", "needs": {"board": 1}, "results": {"shelf": 1}, "cycles": 10},
+//	    {"name": "glue", "needs": {}, "results": {"glue": 1}, "duration": "uniform(4;8)"}
+//	  ],
+//	  "optimize": ["time", "shelf"]
+//	}
+//
+// Renewables are optional and give the capacity of each renewable resource; capacities are
+// optional too and give the storage capacity of items, which start at 0 when they are not
+// among the stocks; perishables are optional and give the shelf life of items in cycles.
+// Deliveries and orders are optional lists such as
+// "deliveries": [{"cycle": 100, "items": {"flour": 20}}] and
+// "orders": [{"due": 150, "items": {"bread": 10}, "penalty": 5}], the penalty defaulting
+// to DefaultPenalty.
+// A process may give a duration distribution in the text syntax instead of its cycles,
+// which are then its rounded mean, yield ranges such as {"bread": "2-3"} for the results
+// that vary, and a failure probability between 0 and 1, such as "failure": 0.05. The YAML form uses the same keys; see parseYAMLConfig
+// for the supported subset of YAML.
+// Unknown keys are rejected so that typos do not go unnoticed.
+//
+// Returns:
+//   - The parsed configuration.
+//   - A *ParseError locating the problem if the file is malformed, or an error if it cannot be read.
+func ParseConfigAs(path string, format Format) (*ConfigData, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config file: %w", err)
+	}
+	return parseConfigData(path, data, format, nil)
+}
+
+// parseConfigData parses the content of a configuration file in the given format.
+// includedBy lists the text files whose include directives led to this one, outermost
+// first; it is empty for the top-level file.
+func parseConfigData(path string, data []byte, format Format, includedBy []string) (*ConfigData, error) {
+	if format == FormatAuto {
+		format = FormatFromPath(path)
+	}
+
+	var config *ConfigData
+	var err error
+	switch format {
+	case FormatJSON:
+		config, err = parseJSONConfig(path, data)
+	case FormatYAML:
+		config, err = parseYAMLConfig(path, data)
+	default:
+		config, err = parseTextConfig(path, bytes.NewReader(data), includedBy)
+	}
+	if err != nil {
+		return nil, err
+	}
+	markHeld(config)
+	return config, nil
+}
+
+// WriteConfig writes a configuration in the given format. Converting between formats is
+// lossless for the configuration data; comments and the order of stock definitions are
+// not kept. Stocks and resources are written in lexical order, processes in their order.
+//
+// Returns:
+//   - An error if the format is unknown, if a name cannot be written in the text format
+//     (see textName), or if writing fails.
+func WriteConfig(w io.Writer, config *ConfigData, format Format) error {
+	switch format {
+	case FormatText:
+		return writeTextConfig(w, config)
+	case FormatJSON:
+		return writeJSONConfig(w, config)
+	case FormatYAML:
+		return writeYAMLConfig(w, config)
+	}
+	return fmt.Errorf("unknown config format %q, expected text, json or yaml", format)
+}
+
+// configDocument is the structure shared by the JSON and YAML formats.
+type configDocument struct {
+	Stocks     map[string]int     `json:"stocks"`
+	Renewables map[string]int     `json:"renewables,omitempty"`
+	Capacities map[string]int     `json:"capacities,omitempty"`
+	ShelfLife  map[string]int     `json:"perishables,omitempty"`
+	Deliveries []deliveryDocument `json:"deliveries,omitempty"`
+	Orders     []orderDocument    `json:"orders,omitempty"`
+	Processes  []processDocument  `json:"processes"`
+	Optimize   []string           `json:"optimize,omitempty"`
+}
+
+// processDocument is one process of a configDocument.
+type processDocument struct {
+	Name     string            `json:"name"`
+	Needs    map[string]int    `json:"needs"`
+	Results  map[string]int    `json:"results"`
+	Yields   map[string]string `json:"yields,omitempty"`
+	Cycles   int               `json:"cycles"`
+	Duration string            `json:"duration,omitempty"`
+	Failure  float64           `json:"failure,omitempty"`
+}
+
+// deliveryDocument is one delivery of a configDocument.
+type deliveryDocument struct {
+	Cycle int            `json:"cycle"`
+	Items map[string]int `json:"items"`
+}
+
+// orderDocument is one order of a configDocument. A missing penalty is DefaultPenalty.
+type orderDocument struct {
+	Due     int            `json:"due"`
+	Items   map[string]int `json:"items"`
+	Penalty *int           `json:"penalty,omitempty"`
+}
+
+// newConfigDocument returns the document form of a configuration. Maps are never nil,
+// so empty ones are written as {} rather than null. Renewable resources are only listed
+// under renewables, not under stocks.
+func newConfigDocument(config *ConfigData) configDocument {
+	doc := configDocument{
+		Stocks:     plainStocks(config),
+		Renewables: config.Renewables,
+		Capacities: config.Capacities,
+		ShelfLife:  config.ShelfLife,
+		Processes:  make([]processDocument, 0, len(config.Processes)),
+		Optimize:   config.OptimizeTargets,
+	}
+	for _, d := range config.Deliveries {
+		doc.Deliveries = append(doc.Deliveries, deliveryDocument{Cycle: d.Cycle, Items: nonNil(d.Items)})
+	}
+	for _, o := range config.Orders {
+		penalty := o.Penalty
+		doc.Orders = append(doc.Orders, orderDocument{Due: o.Due, Items: nonNil(o.Items), Penalty: &penalty})
+	}
+	for _, p := range config.Processes {
+		results, yields := statedResults(p)
+		doc.Processes = append(doc.Processes, processDocument{
+			Name:     p.Name,
+			Needs:    nonNil(p.Needs),
+			Results:  results,
+			Yields:   yieldSpecs(yields),
+			Cycles:   p.Cycle,
+			Duration: durationSpec(p.Duration),
+			Failure:  p.Failure,
+		})
+	}
+	return doc
+}
+
+// durationSpec returns the configuration syntax of a duration distribution, or the empty
+// string for a fixed duration.
+func durationSpec(d *process.Duration) string {
+	if d == nil {
+		return ""
+	}
+	return d.String()
+}
+
+// statedResults returns the results and yield ranges of a process as a configuration
+// states them: the one-value ranges that give the quantity of a successful run of a
+// process that can fail are folded back into the results, so that reading them back plans
+// with the same expected quantities.
+func statedResults(p *process.Process) (map[string]int, map[string]process.Yield) {
+	results := make(map[string]int, len(p.Result))
+	for item, qty := range p.Result {
+		results[item] = qty
+	}
+	var yields map[string]process.Yield
+	for item, y := range p.Yields {
+		if y.Min == y.Max {
+			results[item] = y.Min
+			continue
+		}
+		if yields == nil {
+			yields = map[string]process.Yield{}
+		}
+		yields[item] = y
+	}
+	return results, yields
+}
+
+// yieldSpecs returns the yield ranges of a process in the configuration syntax, or nil
+// when it has none.
+func yieldSpecs(yields map[string]process.Yield) map[string]string {
+	if len(yields) == 0 {
+		return nil
+	}
+	specs := make(map[string]string, len(yields))
+	for item, y := range yields {
+		specs[item] = y.String()
+	}
+	return specs
+}
+
+// configData returns the configuration described by a document. Duration distributions,
+// yield ranges and failure probabilities must have been checked with specs already.
+func (doc configDocument) configData() *ConfigData {
+	config := newConfigData()
+	for name, qty := range doc.Stocks {
+		config.Stocks[name] = qty
+	}
+	for name, capacity := range doc.Renewables {
+		config.Renewables[name] = capacity
+		config.Stocks[name] = capacity
+	}
+	for name, capacity := range doc.Capacities {
+		config.Capacities[name] = capacity
+		config.Stocks[name] += 0
+	}
+	for name, life := range doc.ShelfLife {
+		config.ShelfLife[name] = life
+	}
+	for _, p := range doc.Processes {
+		proc := &process.Process{
+			Name:   p.Name,
+			Needs:  nonNil(p.Needs),
+			Result: nonNil(p.Results),
+			Cycle:  p.Cycles,
+		}
+		if p.Duration != "" {
+			proc.Duration, _ = parseDuration(p.Duration, 0)
+			proc.Cycle = proc.Duration.Nominal()
+		}
+		for _, item := range sortedKeys(p.Yields) {
+			if proc.Yields == nil {
+				proc.Yields = map[string]process.Yield{}
+			}
+			proc.Yields[item], _ = parseYield(p.Yields[item], 0)
+		}
+		proc.Failure = p.Failure
+		proc.PlanExpected()
+		config.Processes = append(config.Processes, proc)
+	}
+	for _, d := range doc.Deliveries {
+		config.Deliveries = append(config.Deliveries, Delivery{Cycle: d.Cycle, Items: nonNil(d.Items)})
+	}
+	for _, o := range doc.Orders {
+		order := Order{Due: o.Due, Items: nonNil(o.Items), Penalty: DefaultPenalty}
+		if o.Penalty != nil {
+			order.Penalty = *o.Penalty
+		}
+		config.Orders = append(config.Orders, order)
+	}
+	config.OptimizeTargets = append(config.OptimizeTargets, doc.Optimize...)
+	config.HasOptimizer = len(doc.Optimize) > 0
+	return config
+}
+
+// capacityError describes the first negative storage capacity of the document, by item
+// name, or returns an empty message when there is none.
+func (doc configDocument) capacityError() string {
+	for _, name := range sortedKeys(doc.Capacities) {
+		if capacity := doc.Capacities[name]; capacity < 0 {
+			return fmt.Sprintf("invalid storage capacity %d for '%s', expected a non-negative integer", capacity, name)
+		}
+	}
+	return ""
+}
+
+// eventError describes the first delivery or order of the document with a negative
+// cycle or penalty, or returns an empty message when there is none.
+func (doc configDocument) eventError() (ErrorCode, string) {
+	for i, d := range doc.Deliveries {
+		if d.Cycle < 0 {
+			return ErrInvalidCycles, fmt.Sprintf("delivery %d has negative cycle %d", i+1, d.Cycle)
+		}
+	}
+	for i, o := range doc.Orders {
+		if o.Due < 0 {
+			return ErrInvalidCycles, fmt.Sprintf("order %d has negative due cycle %d", i+1, o.Due)
+		}
+		if o.Penalty != nil && *o.Penalty < 0 {
+			return ErrInvalidQuantity, fmt.Sprintf("order %d has negative penalty %d", i+1, *o.Penalty)
+		}
+	}
+	return "", ""
+}
+
+// specs checks the duration distributions, yield ranges and failure probabilities of the
+// processes, written in the text syntax.
+//
+// Returns:
+//   - The index of the first process with an invalid one, the offending text and its
+//     *ParseError, whose column is relative to that text; or -1, "" and nil.
+func (doc configDocument) specs() (int, string, error) {
+	for i, p := range doc.Processes {
+		if p.Duration != "" {
+			if _, err := parseDuration(p.Duration, 0); err != nil {
+				return i, p.Duration, err
+			}
+		}
+		for _, item := range sortedKeys(p.Yields) {
+			if _, err := parseYield(p.Yields[item], 0); err != nil {
+				return i, p.Yields[item], err
+			}
+		}
+		failure := strconv.FormatFloat(p.Failure, 'g', -1, 64)
+		if _, err := parseFailure(failure, 0); err != nil {
+			return i, failure, err
+		}
+	}
+	return -1, "", nil
+}
+
+// renewableConflict returns a name the document defines both as a stock and as a
+// renewable resource, in lexical order, or the empty string.
+func (doc configDocument) renewableConflict() string {
+	for _, name := range sortedKeys(doc.Renewables) {
+		if _, ok := doc.Stocks[name]; ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// plainStocks returns the stocks of a configuration that are not renewable resources.
+func plainStocks(config *ConfigData) map[string]int {
+	stocks := map[string]int{}
+	for name, qty := range config.Stocks {
+		if _, ok := config.Renewables[name]; !ok {
+			stocks[name] = qty
+		}
+	}
+	return stocks
+}
+
+// nonNil returns m, or an empty map if m is nil.
+func nonNil(m map[string]int) map[string]int {
+	if m == nil {
+		return map[string]int{}
+	}
+	return m
+}
+
+// writeTextConfig writes a configuration in the line-based text format: the stocks, the
+// renewable resources and the shelf lives, then the deliveries and orders, then the
+// processes, then the optimize line.
+func writeTextConfig(w io.Writer, config *ConfigData) error {
+	lines := []string{}
+	stocks := plainStocks(config)
+	for name := range config.Capacities {
+		stocks[name] += 0
+	}
+	for _, name := range sortedKeys(stocks) {
+		if err := textName("stock", name); err != nil {
+			return err
+		}
+		if capacity, ok := config.Capacities[name]; ok {
+			lines = append(lines, fmt.Sprintf("%s:%d/%d", name, stocks[name], capacity))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s:%d", name, stocks[name]))
+		}
+	}
+	if len(config.Renewables) > 0 {
+		renewables, err := textResources(config.Renewables)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, "renewable:("+renewables+")")
+	}
+	if len(config.ShelfLife) > 0 {
+		perishables, err := textResources(config.ShelfLife)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, "perishable:("+perishables+")")
+	}
+	events, err := eventLines(config)
+	if err != nil {
+		return err
+	}
+	lines = append(lines, events...)
+	if len(lines) > 0 {
+		lines = append(lines, "")
+	}
+
+	for _, p := range config.Processes {
+		if err := textName("process", p.Name); err != nil {
+			return err
+		}
+		needs, err := textResources(p.Needs)
+		if err != nil {
+			return err
+		}
+		results, err := textResults(p)
+		if err != nil {
+			return err
+		}
+		cycles := strconv.Itoa(p.Cycle)
+		if p.Duration != nil {
+			cycles = p.Duration.String()
+		}
+		if p.Failure > 0 {
+			cycles += ":" + failureSpec(p.Failure)
+		}
+		lines = append(lines, fmt.Sprintf("%s:(%s):(%s):%s", p.Name, needs, results, cycles))
+	}
+
+	if len(config.OptimizeTargets) > 0 {
+		for _, target := range config.OptimizeTargets {
+			if err := textName("optimize target", target); err != nil {
+				return err
+			}
+		}
+		if len(config.Processes) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "optimize:("+strings.Join(config.OptimizeTargets, ";")+")")
+	}
+
+	_, err = io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// textResources formats a resource map as "name:qty;name:qty" in lexical order.
+func textResources(resources map[string]int) (string, error) {
+	parts := make([]string, 0, len(resources))
+	for _, name := range sortedKeys(resources) {
+		if err := textName("resource", name); err != nil {
+			return "", err
+		}
+		parts = append(parts, fmt.Sprintf("%s:%d", name, resources[name]))
+	}
+	return strings.Join(parts, ";"), nil
+}
+
+// textResults formats the results of a process like textResources, with the yield range
+// of the results that have one instead of their nominal quantity.
+func textResults(p *process.Process) (string, error) {
+	parts := make([]string, 0, len(p.Result))
+	for _, name := range sortedKeys(p.Result) {
+		if err := textName("resource", name); err != nil {
+			return "", err
+		}
+		if y, ok := p.Yields[name]; ok {
+			parts = append(parts, name+":"+y.String())
+		} else {
+			parts = append(parts, fmt.Sprintf("%s:%d", name, p.Result[name]))
+		}
+	}
+	return strings.Join(parts, ";"), nil
+}
+
+// textName reports an error if a name cannot be written in the text format, where it
+// would be read back differently: names must be non-empty, must not contain ':', ';',
+// '(', ')' or line breaks, must not start with '#' or carry surrounding spaces, and
+// "optimize", "include", "renewable" and "perishable" are reserved. Stock and process
+// names must not start with '+' or '-' either, which start event lines.
+func textName(kind, name string) error {
+	trimmed, _ := trimToken(name, 0)
+	switch {
+	case name == "":
+		return fmt.Errorf("empty %s name cannot be written in the text format", kind)
+	case strings.ContainsAny(name, ":;()\r\n"), strings.HasPrefix(name, "#"), trimmed != name:
+		return fmt.Errorf("%s name %q cannot be written in the text format, use json or yaml", kind, name)
+	case (kind == "stock" || kind == "process") && strings.ContainsAny(name[:1], "+-"):
+		return fmt.Errorf("%s name %q cannot start an event line in the text format, use json or yaml", kind, name)
+	case (name == "optimize" || name == "include" || name == "renewable" || name == "perishable") && kind != "optimize target":
+		return fmt.Errorf("%s name %q is reserved in the text format, use json or yaml", kind, name)
+	}
+	return nil
+}
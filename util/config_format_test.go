@@ -0,0 +1,147 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// cabinetText is the cabinet example of the README in the text format.
+const cabinetText = `board:7
+
+do_doorknobs:(board:1):(doorknobs:1):15
+do_background:(board:2):(background:1):20
+do_shelf:(board:1):(shelf:1):10
+do_cabinet:(background:1;doorknobs:2;shelf:3):(cabinet:1):30
+
+optimize:(time;cabinet)
+`
+
+// writeConfig writes a configuration file in a temporary directory and returns its path.
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestConvertRoundTrip tests that converting text to JSON, JSON to YAML and YAML back to
+// text keeps the configuration and gives back the original file.
+func TestConvertRoundTrip(t *testing.T) {
+	original, err := ParseConfig(writeConfig(t, "cabinet", cabinetText))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := original
+	for _, format := range []Format{FormatJSON, FormatYAML, FormatText} {
+		var b bytes.Buffer
+		if err := WriteConfig(&b, config, format); err != nil {
+			t.Fatalf("writing %s: %v", format, err)
+		}
+		config, err = ParseConfigAs(writeConfig(t, "cabinet", b.String()), format)
+		if err != nil {
+			t.Fatalf("reading %s: %v\n%s", format, err, b.String())
+		}
+		if !reflect.DeepEqual(config.Stocks, original.Stocks) ||
+			!reflect.DeepEqual(config.Processes, original.Processes) ||
+			!reflect.DeepEqual(config.OptimizeTargets, original.OptimizeTargets) {
+			t.Fatalf("%s round trip changed the configuration:\n%s", format, b.String())
+		}
+		if format == FormatText && b.String() != cabinetText {
+			t.Errorf("text output =\n%s\nwant\n%s", b.String(), cabinetText)
+		}
+	}
+}
+
+// TestYAMLConfig tests the YAML subset: flow and block collections, quoted names holding
+// the separators of the text format, comments, and the recorded definition lines.
+func TestYAMLConfig(t *testing.T) {
+	content := `# bakery
+stocks:
+  "flour: type 55": 10   # sacks
+  'yeast;fresh': 2
+processes:
+- name: make_bread
+  needs: {"flour: type 55": 2, 'yeast;fresh': 1}
+  results:
+    bread: 3
+  cycles: 4
+optimize: [time, bread]
+`
+	config, err := ParseConfig(writeConfig(t, "bakery.yml", content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Stocks["flour: type 55"] != 10 || config.Stocks["yeast;fresh"] != 2 {
+		t.Errorf("Stocks = %v", config.Stocks)
+	}
+	p := config.Processes[0]
+	if p.Name != "make_bread" || p.Needs["flour: type 55"] != 2 || p.Result["bread"] != 3 || p.Cycle != 4 {
+		t.Errorf("process = %+v", p)
+	}
+	if !config.HasOptimizer || !reflect.DeepEqual(config.OptimizeTargets, []string{"time", "bread"}) {
+		t.Errorf("OptimizeTargets = %v", config.OptimizeTargets)
+	}
+	if config.Lines.Stocks["yeast;fresh"] != 4 || config.Lines.ProcessLine(0) != 6 {
+		t.Errorf("Lines = %+v", config.Lines)
+	}
+
+	if err := WriteConfig(&bytes.Buffer{}, config, FormatText); err == nil {
+		t.Error("expected names with ':' and ';' to be rejected by the text format")
+	}
+	var b bytes.Buffer
+	if err := WriteConfig(&b, config, FormatYAML); err != nil {
+		t.Fatal(err)
+	}
+	back, err := ParseConfigAs(writeConfig(t, "bakery", b.String()), FormatYAML)
+	if err != nil || !reflect.DeepEqual(back.Stocks, config.Stocks) {
+		t.Errorf("YAML round trip = %v, %v\n%s", back, err, b.String())
+	}
+}
+
+// TestConfigFormatErrors tests that malformed JSON and YAML configurations are reported
+// with the line, column and code of the problem.
+func TestConfigFormatErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		file    string
+		content string
+		line    int
+		column  int
+		code    ErrorCode
+	}{
+		{"json syntax", "c.json", "{\n  \"stocks\": {\"board\": 7,}\n}\n", 2, 25, ErrSyntax},
+		{"json type", "c.json", "{\n  \"stocks\": {\"board\": \"7\"}\n}\n", 2, 23, ErrInvalidValue},
+		{"json unknown field", "c.json", "{\n  \"stock\": {}\n}\n", 2, 3, ErrUnknownField},
+		{"yaml quantity", "c.yaml", "stocks:\n  board: seven\n", 2, 10, ErrInvalidQuantity},
+		{"yaml cycles", "c.yaml", "processes:\n  - name: p\n    cycles: 1.5\n", 3, 13, ErrInvalidCycles},
+		{"yaml unknown key", "c.yaml", "processes:\n  - name: p\n    result: {}\n", 3, 5, ErrUnknownField},
+		{"yaml unclosed flow", "c.yaml", "optimize: [time\n", 1, 16, ErrSyntax},
+		{"yaml tab", "c.yaml", "stocks:\n\tboard: 1\n", 2, 1, ErrSyntax},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseConfig(writeConfig(t, tc.file, tc.content))
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("expected a *ParseError, got %v", err)
+			}
+			if parseErr.Line != tc.line || parseErr.Column != tc.column || parseErr.Code != tc.code {
+				t.Errorf("got %d:%d [%s] (%v), want %d:%d [%s]",
+					parseErr.Line, parseErr.Column, parseErr.Code, parseErr, tc.line, tc.column, tc.code)
+			}
+			if !strings.Contains(parseErr.Render(), "^") {
+				t.Errorf("expected a caret in\n%s", parseErr.Render())
+			}
+		})
+	}
+}
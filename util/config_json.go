@@ -0,0 +1,132 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// parseJSONConfig parses a configuration in the JSON format described in ParseConfigAs.
+// Syntax and type errors are reported as a *ParseError at the line and column of the
+// offending input.
+func parseJSONConfig(path string, data []byte) (*ConfigData, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var doc configDocument
+	if err := dec.Decode(&doc); err != nil {
+		return nil, jsonError(path, data, dec, err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, offsetError(path, data, int(dec.InputOffset()), ErrSyntax, "", "unexpected data after the configuration")
+	}
+
+	for i, p := range doc.Processes {
+		if p.Name == "" {
+			return nil, &ParseError{File: path, Code: ErrMissingName, Message: fmt.Sprintf("process %d has no name", i+1)}
+		}
+	}
+	if name := doc.renewableConflict(); name != "" {
+		return nil, &ParseError{File: path, Code: ErrRenewableConflict, Token: name,
+			Message: fmt.Sprintf("'%s' is both a stock and a renewable resource", name)}
+	}
+	if message := doc.capacityError(); message != "" {
+		return nil, &ParseError{File: path, Code: ErrInvalidQuantity, Message: message}
+	}
+	if code, message := doc.eventError(); message != "" {
+		return nil, &ParseError{File: path, Code: code, Message: message}
+	}
+	if i, spec, err := doc.specs(); err != nil {
+		parseErr := err.(*ParseError)
+		offset := len(data)
+		if start := bytes.Index(data, []byte(`"`+spec+`"`)); start >= 0 {
+			offset = start + parseErr.Column
+		} else if start := bytes.Index(data, []byte(spec)); start >= 0 {
+			offset = start + parseErr.Column - 1
+		}
+		return nil, offsetError(path, data, offset, parseErr.Code, parseErr.Token,
+			"process '%s': %s", doc.Processes[i].Name, parseErr.Message)
+	}
+	return doc.configData(), nil
+}
+
+// jsonError converts an encoding/json decoding error into a *ParseError.
+func jsonError(path string, data []byte, dec *json.Decoder, err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return offsetError(path, data, int(syntaxErr.Offset)-1, ErrSyntax, "", "%s", syntaxErr.Error())
+	case errors.As(err, &typeErr):
+		start := valueStart(data, int(typeErr.Offset))
+		return offsetError(path, data, start, ErrInvalidValue, string(data[start:typeErr.Offset]),
+			"field %q: expected %s, got %s", typeErr.Field, typeErr.Type, typeErr.Value)
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return offsetError(path, data, len(data), ErrSyntax, "", "unexpected end of the configuration")
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		offset := int(dec.InputOffset())
+		if i := bytes.LastIndex(data[:offset], []byte(field)); i >= 0 {
+			offset = i
+		}
+		return offsetError(path, data, offset, ErrUnknownField, field,
+			"unknown field %s, expected stocks, renewables, capacities, perishables, deliveries, orders, processes and optimize, or name, needs, results, yields, cycles, duration and failure, or cycle, due, items and penalty", field)
+	}
+	return offsetError(path, data, int(dec.InputOffset()), ErrSyntax, "", "%s", err.Error())
+}
+
+// valueStart returns the offset at which the JSON value ending at the given offset starts.
+func valueStart(data []byte, end int) int {
+	end = min(end, len(data))
+	if end > 0 && data[end-1] == '"' {
+		for i := end - 2; i >= 0; i-- {
+			if data[i] == '"' && (i == 0 || data[i-1] != '\\') {
+				return i
+			}
+		}
+		return 0
+	}
+	start := end
+	for start > 0 && !strings.ContainsRune(" \t\r\n,:[{", rune(data[start-1])) {
+		start--
+	}
+	return start
+}
+
+// offsetError returns a *ParseError for the byte offset of a file, pointing at the
+// token at that offset when none is given.
+func offsetError(path string, data []byte, offset int, code ErrorCode, token, format string, args ...any) *ParseError {
+	offset = min(max(offset, 0), len(data))
+	lineStart := bytes.LastIndexByte(data[:offset], '\n') + 1
+	lineEnd := bytes.IndexByte(data[offset:], '\n')
+	if lineEnd == -1 {
+		lineEnd = len(data)
+	} else {
+		lineEnd += offset
+	}
+	text := strings.TrimRight(string(data[lineStart:lineEnd]), "\r")
+	if token == "" && offset < lineEnd {
+		token = string(data[offset:min(offset+1, lineEnd)])
+	}
+
+	return &ParseError{
+		File:    path,
+		Line:    bytes.Count(data[:offset], []byte("\n")) + 1,
+		Column:  offset - lineStart + 1,
+		Token:   token,
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+		Text:    text,
+	}
+}
+
+// writeJSONConfig writes a configuration in the JSON format, indented by two spaces.
+func writeJSONConfig(w io.Writer, config *ConfigData) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(newConfigDocument(config))
+}
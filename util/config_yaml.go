@@ -0,0 +1,916 @@
+package util
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/jesee-kuya/stock_exchange/process"
+)
+
+// yamlKind is the kind of a yamlNode.
+type yamlKind int
+
+const (
+	yamlScalar yamlKind = iota
+	yamlMap
+	yamlSeq
+)
+
+// yamlNode is a parsed YAML value with the position it started at.
+//
+// Fields:
+//   - Kind: whether the node is a scalar, a mapping or a sequence.
+//   - Value: the scalar text, unquoted; Null reports a missing value, "null" or "~".
+//   - Quoted: whether the scalar was quoted, so that "12" stays a string.
+//   - Keys, Items: the entries of a mapping, in order, and the items of a sequence.
+//   - Line, Column, Text: the 1-based position of the node and its source line.
+type yamlNode struct {
+	Kind   yamlKind
+	Value  string
+	Null   bool
+	Quoted bool
+	Keys   []yamlEntry
+	Items  []*yamlNode
+	Line   int
+	Column int
+	Text   string
+}
+
+// yamlEntry is one key and value of a mapping node.
+type yamlEntry struct {
+	Key   *yamlNode
+	Value *yamlNode
+}
+
+// yamlLine is a non-empty source line with its comment stripped.
+type yamlLine struct {
+	number  int
+	indent  int
+	content string
+	text    string
+}
+
+// yamlParser reads the block structure of a YAML document line by line.
+type yamlParser struct {
+	path  string
+	lines []yamlLine
+	pos   int
+}
+
+// parseYAMLConfig parses a configuration in the YAML format. It takes the same keys as
+// the JSON format:
+//
+//	stocks:
+//	  board: 7
+//	renewables:
+//	  saw: 1
+//	processes:
+//	  - name: do_shelf
+//	    needs: {board: 1}
+//	    results:
+//	      shelf: 1
+//	    cycles: 10
+//	optimize: [time, shelf]
+//
+// Deliveries and orders are sequences of mappings, such as
+// "- {cycle: 100, items: {flour: 20}}" and "- {due: 150, items: {bread: 10}, penalty: 5}".
+//
+// Only the subset of YAML needed for such documents is supported: block mappings and
+// sequences indented with spaces, single-line flow mappings and sequences, plain, single-
+// and double-quoted scalars, and '#' comments. Anchors, tags, multi-line scalars and
+// multiple documents are not.
+//
+// Returns:
+//   - The parsed configuration, with the line of each stock and process recorded in Lines.
+//   - A *ParseError locating the problem if the document is malformed.
+func parseYAMLConfig(path string, data []byte) (*ConfigData, error) {
+	p := &yamlParser{path: path}
+	if err := p.split(string(data)); err != nil {
+		return nil, err
+	}
+	if len(p.lines) == 0 {
+		return newConfigData(), nil
+	}
+
+	root, err := p.parseBlock(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		return nil, p.errorAt(line.number, line.indent, line.text, ErrSyntax, line.content, "unexpected indentation")
+	}
+	return p.decode(root)
+}
+
+// split fills p.lines with the lines of a document that hold content, dropping comments,
+// blank lines and the "---" document marker.
+func (p *yamlParser) split(data string) error {
+	for i, text := range strings.Split(data, "\n") {
+		text = strings.TrimRight(text, "\r")
+		content := strings.TrimRight(stripYAMLComment(text), " \t")
+		trimmed := strings.TrimLeft(content, " ")
+		if trimmed == "" || (i == 0 || len(p.lines) == 0) && trimmed == "---" {
+			continue
+		}
+		indent := len(content) - len(trimmed)
+		if strings.HasPrefix(trimmed, "\t") {
+			return p.errorAt(i+1, indent, text, ErrSyntax, "\t", "tabs are not allowed in indentation")
+		}
+		p.lines = append(p.lines, yamlLine{number: i + 1, indent: indent, content: trimmed, text: text})
+	}
+	return nil
+}
+
+// stripYAMLComment removes a '#' comment from a line. A '#' starts a comment at the
+// beginning of the line or after a space, unless it is inside quotes.
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// parseBlock parses the block node whose first line is the current one, at the given indent.
+func (p *yamlParser) parseBlock(indent int) (*yamlNode, error) {
+	line := p.lines[p.pos]
+	if isYAMLSeqItem(line.content) {
+		return p.parseSeq(indent)
+	}
+	if _, _, ok := splitYAMLKey(line.content); ok {
+		return p.parseMap(indent)
+	}
+	p.pos++
+	return p.parseInline(line, line.content, line.indent)
+}
+
+// parseMap parses the "key: value" lines at the given indent.
+func (p *yamlParser) parseMap(indent int) (*yamlNode, error) {
+	first := p.lines[p.pos]
+	node := &yamlNode{Kind: yamlMap, Line: first.number, Column: indent + 1, Text: first.text}
+	seen := map[string]bool{}
+
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && !isYAMLSeqItem(p.lines[p.pos].content) {
+		line := p.lines[p.pos]
+		rawKey, valueOffset, ok := splitYAMLKey(line.content)
+		if !ok {
+			return nil, p.errorAt(line.number, indent, line.text, ErrSyntax, line.content, "expected 'key: value'")
+		}
+		key, err := p.parseScalar(line, rawKey, indent)
+		if err != nil {
+			return nil, err
+		}
+		if seen[key.Value] {
+			return nil, p.errorAt(line.number, indent, line.text, ErrSyntax, rawKey, "duplicate key '%s'", key.Value)
+		}
+		seen[key.Value] = true
+		p.pos++
+
+		var value *yamlNode
+		rest := strings.TrimLeft(line.content[valueOffset:], " ")
+		switch {
+		case rest != "":
+			value, err = p.parseInline(line, rest, indent+len(line.content)-len(rest))
+		case p.pos < len(p.lines) && p.lines[p.pos].indent > indent:
+			value, err = p.parseBlock(p.lines[p.pos].indent)
+		case p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLSeqItem(p.lines[p.pos].content):
+			// A sequence may sit at the indent of its key.
+			value, err = p.parseSeq(indent)
+		default:
+			value = &yamlNode{Null: true, Line: line.number, Column: indent + valueOffset + 1, Text: line.text}
+		}
+		if err != nil {
+			return nil, err
+		}
+		node.Keys = append(node.Keys, yamlEntry{Key: key, Value: value})
+	}
+	return node, nil
+}
+
+// parseSeq parses the "- item" lines at the given indent. An item holding a mapping,
+// as in "- name: x", continues on the following lines indented like its first key.
+func (p *yamlParser) parseSeq(indent int) (*yamlNode, error) {
+	first := p.lines[p.pos]
+	node := &yamlNode{Kind: yamlSeq, Line: first.number, Column: indent + 1, Text: first.text}
+
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLSeqItem(p.lines[p.pos].content) {
+		line := p.lines[p.pos]
+		rest := strings.TrimLeft(line.content[1:], " ")
+
+		var item *yamlNode
+		var err error
+		switch {
+		case rest != "":
+			// Parse the rest of the line as if it started its own, further indented line.
+			p.lines[p.pos].indent = indent + len(line.content) - len(rest)
+			p.lines[p.pos].content = rest
+			item, err = p.parseBlock(p.lines[p.pos].indent)
+		case p.pos+1 < len(p.lines) && p.lines[p.pos+1].indent > indent:
+			p.pos++
+			item, err = p.parseBlock(p.lines[p.pos].indent)
+		default:
+			p.pos++
+			item = &yamlNode{Null: true, Line: line.number, Column: indent + 1, Text: line.text}
+		}
+		if err != nil {
+			return nil, err
+		}
+		node.Items = append(node.Items, item)
+	}
+	return node, nil
+}
+
+// isYAMLSeqItem reports whether a line content starts a sequence item.
+func isYAMLSeqItem(content string) bool {
+	return content == "-" || strings.HasPrefix(content, "- ")
+}
+
+// splitYAMLKey splits "key: value" at the first ':' followed by a space or the end of
+// the line, skipping quoted keys, so that plain keys may contain ':' themselves.
+//
+// Returns:
+//   - The raw key text and the offset of the text following the ':'.
+//   - false if the content is not a mapping entry.
+func splitYAMLKey(content string) (string, int, bool) {
+	start := 0
+	if content != "" && (content[0] == '"' || content[0] == '\'') {
+		end := closingQuote(content, 0)
+		if end < 0 {
+			return "", 0, false
+		}
+		start = end + 1
+	} else if strings.HasPrefix(content, "{") || strings.HasPrefix(content, "[") {
+		return "", 0, false
+	}
+	for i := start; i < len(content); i++ {
+		if content[i] == ':' && (i+1 == len(content) || content[i+1] == ' ') {
+			return strings.TrimRight(content[:i], " "), i + 1, true
+		}
+	}
+	return "", 0, false
+}
+
+// closingQuote returns the index of the quote closing the one at s[start], or -1.
+// In double quotes a backslash escapes the next character; in single quotes a
+// doubled quote stands for one.
+func closingQuote(s string, start int) int {
+	quote := s[start]
+	for i := start + 1; i < len(s); i++ {
+		switch {
+		case quote == '"' && s[i] == '\\':
+			i++
+		case s[i] == quote && quote == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// parseInline parses a value written on a single line, starting at the 0-based column.
+func (p *yamlParser) parseInline(line yamlLine, text string, column int) (*yamlNode, error) {
+	f := &yamlFlow{parser: p, line: line, text: text, column: column}
+	node, err := f.value(false)
+	if err != nil {
+		return nil, err
+	}
+	f.skipSpaces()
+	if f.pos < len(f.text) {
+		return nil, f.errorf(ErrSyntax, f.text[f.pos:], "unexpected text after the value")
+	}
+	return node, nil
+}
+
+// parseScalar parses a block mapping key.
+func (p *yamlParser) parseScalar(line yamlLine, text string, column int) (*yamlNode, error) {
+	f := &yamlFlow{parser: p, line: line, text: text, column: column}
+	return f.scalar(false)
+}
+
+// yamlFlow parses flow values such as "{board: 1}" or "[time, shelf]" and scalars.
+type yamlFlow struct {
+	parser *yamlParser
+	line   yamlLine
+	text   string
+	column int
+	pos    int
+}
+
+// value parses the node at the current position. Inside flow collections plain scalars
+// stop at ',', ']' and '}'.
+func (f *yamlFlow) value(inFlow bool) (*yamlNode, error) {
+	f.skipSpaces()
+	if f.pos < len(f.text) {
+		switch f.text[f.pos] {
+		case '{':
+			return f.mapping()
+		case '[':
+			return f.sequence()
+		}
+	}
+	return f.scalar(inFlow)
+}
+
+// mapping parses a flow mapping.
+func (f *yamlFlow) mapping() (*yamlNode, error) {
+	node := f.node(yamlMap)
+	seen := map[string]bool{}
+	f.pos++
+	for {
+		f.skipSpaces()
+		if f.consume('}') {
+			return node, nil
+		}
+		key, err := f.scalar(true)
+		if err != nil {
+			return nil, err
+		}
+		if seen[key.Value] {
+			return nil, f.errorAtNode(key, ErrSyntax, key.Value, "duplicate key '%s'", key.Value)
+		}
+		seen[key.Value] = true
+		f.skipSpaces()
+		if !f.consume(':') {
+			return nil, f.errorf(ErrSyntax, f.rest(), "expected ':' after key '%s'", key.Value)
+		}
+		value, err := f.value(true)
+		if err != nil {
+			return nil, err
+		}
+		node.Keys = append(node.Keys, yamlEntry{Key: key, Value: value})
+		if err := f.separator('}'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sequence parses a flow sequence.
+func (f *yamlFlow) sequence() (*yamlNode, error) {
+	node := f.node(yamlSeq)
+	f.pos++
+	for {
+		f.skipSpaces()
+		if f.consume(']') {
+			return node, nil
+		}
+		item, err := f.value(true)
+		if err != nil {
+			return nil, err
+		}
+		node.Items = append(node.Items, item)
+		if err := f.separator(']'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// separator consumes the ',' between flow entries, leaving a closing bracket in place.
+func (f *yamlFlow) separator(closing byte) error {
+	f.skipSpaces()
+	if f.consume(',') {
+		return nil
+	}
+	if f.pos < len(f.text) && f.text[f.pos] == closing {
+		return nil
+	}
+	if f.pos == len(f.text) {
+		return f.errorf(ErrSyntax, "", "missing '%c', flow collections must fit on one line", closing)
+	}
+	return f.errorf(ErrSyntax, f.rest(), "expected ',' or '%c'", closing)
+}
+
+// scalar parses a plain or quoted scalar.
+func (f *yamlFlow) scalar(inFlow bool) (*yamlNode, error) {
+	f.skipSpaces()
+	node := f.node(yamlScalar)
+	if f.pos == len(f.text) {
+		node.Null = true
+		return node, nil
+	}
+
+	switch quote := f.text[f.pos]; quote {
+	case '"', '\'':
+		end := closingQuote(f.text, f.pos)
+		if end < 0 {
+			return nil, f.errorf(ErrSyntax, f.rest(), "unterminated quoted string")
+		}
+		raw := f.text[f.pos : end+1]
+		if quote == '"' {
+			value, err := strconv.Unquote(raw)
+			if err != nil {
+				return nil, f.errorf(ErrSyntax, raw, "invalid quoted string %s", raw)
+			}
+			node.Value = value
+		} else {
+			node.Value = strings.ReplaceAll(raw[1:len(raw)-1], "''", "'")
+		}
+		node.Quoted = true
+		f.pos = end + 1
+		return node, nil
+	}
+
+	end := len(f.text)
+	if inFlow {
+		for i := f.pos; i < len(f.text); i++ {
+			c := f.text[i]
+			if c == ',' || c == ']' || c == '}' || c == ':' && (i+1 == len(f.text) || strings.ContainsRune(" ,]}", rune(f.text[i+1]))) {
+				end = i
+				break
+			}
+		}
+	}
+	node.Value = strings.TrimRight(f.text[f.pos:end], " ")
+	node.Null = node.Value == "null" || node.Value == "~"
+	f.pos = end
+	return node, nil
+}
+
+// node returns an empty node of the given kind at the current position.
+func (f *yamlFlow) node(kind yamlKind) *yamlNode {
+	return &yamlNode{Kind: kind, Line: f.line.number, Column: f.column + f.pos + 1, Text: f.line.text}
+}
+
+// consume skips c if it is the next character.
+func (f *yamlFlow) consume(c byte) bool {
+	if f.pos < len(f.text) && f.text[f.pos] == c {
+		f.pos++
+		return true
+	}
+	return false
+}
+
+// skipSpaces skips the spaces at the current position.
+func (f *yamlFlow) skipSpaces() {
+	for f.pos < len(f.text) && f.text[f.pos] == ' ' {
+		f.pos++
+	}
+}
+
+// rest returns the text from the current position to the end of the line.
+func (f *yamlFlow) rest() string {
+	return f.text[f.pos:]
+}
+
+// errorf returns a *ParseError at the current position.
+func (f *yamlFlow) errorf(code ErrorCode, token, format string, args ...any) *ParseError {
+	return f.parser.errorAt(f.line.number, f.column+f.pos, f.line.text, code, token, format, args...)
+}
+
+// errorAtNode returns a *ParseError at the position of a node.
+func (f *yamlFlow) errorAtNode(node *yamlNode, code ErrorCode, token, format string, args ...any) *ParseError {
+	return f.parser.errorAt(node.Line, node.Column-1, node.Text, code, token, format, args...)
+}
+
+// errorAt returns a *ParseError at the 0-based column of a line.
+func (p *yamlParser) errorAt(line, column int, text string, code ErrorCode, token, format string, args ...any) *ParseError {
+	err := newParseError(code, column, token, format, args...)
+	err.File, err.Line, err.Text = p.path, line, text
+	return err
+}
+
+// nodeError returns a *ParseError pointing at a node.
+func (p *yamlParser) nodeError(node *yamlNode, code ErrorCode, format string, args ...any) *ParseError {
+	return p.errorAt(node.Line, node.Column-1, node.Text, code, node.Value, format, args...)
+}
+
+// decode converts the document root into a configuration.
+func (p *yamlParser) decode(root *yamlNode) (*ConfigData, error) {
+	if root.Null {
+		return newConfigData(), nil
+	}
+	if root.Kind != yamlMap {
+		return nil, p.nodeError(root, ErrInvalidValue, "expected a mapping with stocks, processes and optimize")
+	}
+
+	var doc configDocument
+	var stockLines, renewableLines map[string]int
+	var processLines, deliveryLines, orderLines []int
+	for _, entry := range root.Keys {
+		var err error
+		switch entry.Key.Value {
+		case "stocks":
+			doc.Stocks, stockLines, err = p.quantities(entry.Value, "stock", ErrInvalidQuantity)
+		case "renewables":
+			doc.Renewables, renewableLines, err = p.quantities(entry.Value, "renewable", ErrInvalidQuantity)
+		case "capacities":
+			doc.Capacities, _, err = p.quantities(entry.Value, "storage capacity", ErrInvalidQuantity)
+			for _, capacity := range entry.Value.Keys {
+				if err == nil && doc.Capacities[capacity.Key.Value] < 0 {
+					err = p.nodeError(capacity.Value, ErrInvalidQuantity,
+						"invalid storage capacity '%s', expected a non-negative integer", capacity.Value.Value)
+				}
+			}
+		case "perishables":
+			doc.ShelfLife, _, err = p.quantities(entry.Value, "shelf life", ErrInvalidQuantity)
+		case "deliveries":
+			doc.Deliveries, deliveryLines, err = p.deliveries(entry.Value)
+		case "orders":
+			doc.Orders, orderLines, err = p.orders(entry.Value)
+		case "processes":
+			doc.Processes, processLines, err = p.processes(entry.Value)
+		case "optimize":
+			doc.Optimize, err = p.names(entry.Value)
+		default:
+			err = p.nodeError(entry.Key, ErrUnknownField, "unknown key '%s', expected stocks, renewables, capacities, perishables, deliveries, orders, processes or optimize", entry.Key.Value)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if name := doc.renewableConflict(); name != "" {
+		return nil, &ParseError{File: p.path, Line: renewableLines[name], Code: ErrRenewableConflict, Token: name,
+			Message: fmt.Sprintf("'%s' is both a stock (line %d) and a renewable resource", name, stockLines[name])}
+	}
+
+	config := doc.configData()
+	for name, line := range stockLines {
+		config.Lines.Stocks[name] = line
+	}
+	for name, line := range renewableLines {
+		config.Lines.Stocks[name] = line
+	}
+	config.Lines.Processes = processLines
+	config.Lines.Deliveries = deliveryLines
+	config.Lines.Orders = orderLines
+	return config, nil
+}
+
+// deliveries decodes the sequence of delivery mappings, with the line of each delivery.
+func (p *yamlParser) deliveries(node *yamlNode) ([]deliveryDocument, []int, error) {
+	docs := []deliveryDocument{}
+	lines := []int{}
+	err := p.events(node, "delivery", "cycle and items", func(item *yamlNode) error {
+		var doc deliveryDocument
+		for _, entry := range item.Keys {
+			var err error
+			switch entry.Key.Value {
+			case "cycle":
+				doc.Cycle, err = p.cycle(entry.Value, "delivery cycle")
+			case "items":
+				doc.Items, _, err = p.quantities(entry.Value, "item", ErrInvalidQuantity)
+			default:
+				err = p.nodeError(entry.Key, ErrUnknownField, "unknown key '%s', expected cycle or items", entry.Key.Value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		docs = append(docs, doc)
+		lines = append(lines, item.Line)
+		return nil
+	})
+	return docs, lines, err
+}
+
+// orders decodes the sequence of order mappings, with the line of each order.
+func (p *yamlParser) orders(node *yamlNode) ([]orderDocument, []int, error) {
+	docs := []orderDocument{}
+	lines := []int{}
+	err := p.events(node, "order", "due, items and penalty", func(item *yamlNode) error {
+		var doc orderDocument
+		for _, entry := range item.Keys {
+			var err error
+			switch entry.Key.Value {
+			case "due":
+				doc.Due, err = p.cycle(entry.Value, "due cycle")
+			case "items":
+				doc.Items, _, err = p.quantities(entry.Value, "item", ErrInvalidQuantity)
+			case "penalty":
+				var penalty int
+				if penalty, err = p.integer(entry.Value, "penalty", ErrInvalidQuantity); err == nil && penalty < 0 {
+					err = p.nodeError(entry.Value, ErrInvalidQuantity, "invalid penalty '%d', expected a non-negative integer", penalty)
+				}
+				doc.Penalty = &penalty
+			default:
+				err = p.nodeError(entry.Key, ErrUnknownField, "unknown key '%s', expected due, items or penalty", entry.Key.Value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		docs = append(docs, doc)
+		lines = append(lines, item.Line)
+		return nil
+	})
+	return docs, lines, err
+}
+
+// events calls decode with each mapping of a sequence of deliveries or orders.
+func (p *yamlParser) events(node *yamlNode, kind, keys string, decode func(item *yamlNode) error) error {
+	if node.Null {
+		return nil
+	}
+	if node.Kind != yamlSeq {
+		return p.nodeError(node, ErrInvalidValue, "%s list must be a sequence", kind)
+	}
+	for _, item := range node.Items {
+		if item.Kind != yamlMap {
+			return p.nodeError(item, ErrInvalidEvent, "a %s must be a mapping with %s", kind, keys)
+		}
+		if err := decode(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cycle decodes a non-negative cycle number.
+func (p *yamlParser) cycle(node *yamlNode, what string) (int, error) {
+	cycle, err := p.integer(node, what, ErrInvalidCycles)
+	if err == nil && cycle < 0 {
+		return 0, p.nodeError(node, ErrInvalidCycles, "invalid %s '%d', expected a non-negative integer", what, cycle)
+	}
+	return cycle, err
+}
+
+// processes decodes the sequence of process mappings.
+func (p *yamlParser) processes(node *yamlNode) ([]processDocument, []int, error) {
+	if node.Null {
+		return nil, nil, nil
+	}
+	if node.Kind != yamlSeq {
+		return nil, nil, p.nodeError(node, ErrInvalidValue, "processes must be a sequence")
+	}
+
+	docs := []processDocument{}
+	lines := []int{}
+	for _, item := range node.Items {
+		if item.Kind != yamlMap {
+			return nil, nil, p.nodeError(item, ErrInvalidProcess, "a process must be a mapping with name, needs, results and cycles")
+		}
+		var doc processDocument
+		hasName := false
+		for _, entry := range item.Keys {
+			var err error
+			switch entry.Key.Value {
+			case "name":
+				if entry.Value.Kind != yamlScalar || entry.Value.Null || entry.Value.Value == "" {
+					return nil, nil, p.nodeError(entry.Value, ErrMissingName, "process name must be a non-empty string")
+				}
+				doc.Name, hasName = entry.Value.Value, true
+			case "needs":
+				doc.Needs, _, err = p.quantities(entry.Value, "resource", ErrInvalidQuantity)
+			case "results":
+				doc.Results, _, err = p.quantities(entry.Value, "resource", ErrInvalidQuantity)
+			case "cycles":
+				doc.Cycles, err = p.integer(entry.Value, "cycle count", ErrInvalidCycles)
+			case "yields":
+				doc.Yields, err = p.yields(entry.Value)
+			case "duration":
+				doc.Duration, err = p.duration(entry.Value)
+			case "failure":
+				doc.Failure, err = p.failure(entry.Value)
+			default:
+				err = p.nodeError(entry.Key, ErrUnknownField, "unknown key '%s', expected name, needs, results, yields, cycles, duration or failure", entry.Key.Value)
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		if !hasName {
+			return nil, nil, p.nodeError(item, ErrMissingName, "process has no name")
+		}
+		docs = append(docs, doc)
+		lines = append(lines, item.Line)
+	}
+	return docs, lines, nil
+}
+
+// quantities decodes a mapping of names to integer quantities, with the line of each name.
+func (p *yamlParser) quantities(node *yamlNode, kind string, code ErrorCode) (map[string]int, map[string]int, error) {
+	quantities, lines := map[string]int{}, map[string]int{}
+	if node.Null {
+		return quantities, lines, nil
+	}
+	if node.Kind != yamlMap {
+		return nil, nil, p.nodeError(node, ErrInvalidValue, "%s quantities must be a mapping of names to integers", kind)
+	}
+	for _, entry := range node.Keys {
+		if entry.Key.Value == "" {
+			return nil, nil, p.nodeError(entry.Key, ErrMissingName, "empty %s name", kind)
+		}
+		qty, err := p.integer(entry.Value, kind+" quantity", code)
+		if err != nil {
+			return nil, nil, err
+		}
+		quantities[entry.Key.Value] = qty
+		lines[entry.Key.Value] = entry.Key.Line
+	}
+	return quantities, lines, nil
+}
+
+// duration decodes a duration distribution in the text syntax, such as uniform(4;8).
+func (p *yamlParser) duration(node *yamlNode) (string, error) {
+	if node.Kind != yamlScalar || node.Null {
+		return "", p.nodeError(node, ErrInvalidCycles, "invalid duration, expected a distribution such as uniform(4;8)")
+	}
+	if _, err := parseDuration(node.Value, 0); err != nil {
+		return "", p.specError(node, err)
+	}
+	return node.Value, nil
+}
+
+// yields decodes a mapping of results to yield ranges in the text syntax, such as 2-3.
+func (p *yamlParser) yields(node *yamlNode) (map[string]string, error) {
+	if node.Null {
+		return nil, nil
+	}
+	if node.Kind != yamlMap {
+		return nil, p.nodeError(node, ErrInvalidValue, "yields must be a mapping of results to ranges such as 2-3")
+	}
+	yields := map[string]string{}
+	for _, entry := range node.Keys {
+		value := entry.Value
+		if value.Kind != yamlScalar || value.Null || !isYieldRange(value.Value) {
+			return nil, p.nodeError(value, ErrInvalidQuantity, "invalid yield of '%s', expected a range such as 2-3", entry.Key.Value)
+		}
+		if _, err := parseYield(value.Value, 0); err != nil {
+			return nil, p.specError(value, err)
+		}
+		yields[entry.Key.Value] = value.Value
+	}
+	return yields, nil
+}
+
+// failure decodes a failure probability, a fraction such as 0.05 or a percentage such as 5%.
+func (p *yamlParser) failure(node *yamlNode) (float64, error) {
+	if node.Kind != yamlScalar || node.Null {
+		return 0, p.nodeError(node, ErrInvalidFailure, "invalid failure probability, expected a fraction such as 0.05")
+	}
+	failure, err := parseFailure(node.Value, 0)
+	if err != nil {
+		return 0, p.specError(node, err)
+	}
+	return failure, nil
+}
+
+// specError locates the *ParseError of a value in the text syntax within its scalar node.
+func (p *yamlParser) specError(node *yamlNode, err error) *ParseError {
+	parseErr := err.(*ParseError)
+	column := node.Column - 1 + parseErr.Column - 1
+	if node.Quoted {
+		column++
+	}
+	return p.errorAt(node.Line, column, node.Text, parseErr.Code, parseErr.Token, "%s", parseErr.Message)
+}
+
+// integer decodes a plain integer scalar.
+func (p *yamlParser) integer(node *yamlNode, what string, code ErrorCode) (int, error) {
+	if node.Kind != yamlScalar || node.Quoted || node.Null {
+		return 0, p.nodeError(node, code, "invalid %s, expected an integer", what)
+	}
+	n, err := strconv.Atoi(node.Value)
+	if err != nil {
+		return 0, p.nodeError(node, code, "invalid %s '%s'", what, node.Value)
+	}
+	return n, nil
+}
+
+// names decodes the optimize targets: a sequence of names, or a single name.
+func (p *yamlParser) names(node *yamlNode) ([]string, error) {
+	if node.Null {
+		return nil, nil
+	}
+	if node.Kind == yamlScalar {
+		return []string{node.Value}, nil
+	}
+	if node.Kind != yamlSeq {
+		return nil, p.nodeError(node, ErrInvalidValue, "optimize must be a sequence of names")
+	}
+	names := []string{}
+	seen := map[string]bool{}
+	for _, item := range node.Items {
+		if item.Kind != yamlScalar || item.Null || item.Value == "" {
+			return nil, p.nodeError(item, ErrInvalidValue, "optimize targets must be non-empty names")
+		}
+		if seen[item.Value] {
+			return nil, p.nodeError(item, ErrDuplicateOptimize, "duplicate optimize target '%s'", item.Value)
+		}
+		seen[item.Value] = true
+		names = append(names, item.Value)
+	}
+	return names, nil
+}
+
+// writeYAMLConfig writes a configuration in the YAML format, quoting the names that
+// would otherwise not read back as plain strings.
+func writeYAMLConfig(w io.Writer, config *ConfigData) error {
+	var b strings.Builder
+	b.WriteString("stocks:")
+	writeYAMLQuantities(&b, plainStocks(config), "  ")
+	if len(config.Renewables) > 0 {
+		b.WriteString("renewables:")
+		writeYAMLQuantities(&b, config.Renewables, "  ")
+	}
+	if len(config.Capacities) > 0 {
+		b.WriteString("capacities:")
+		writeYAMLQuantities(&b, config.Capacities, "  ")
+	}
+	if len(config.ShelfLife) > 0 {
+		b.WriteString("perishables:")
+		writeYAMLQuantities(&b, config.ShelfLife, "  ")
+	}
+	if len(config.Deliveries) > 0 {
+		b.WriteString("deliveries:\n")
+		for _, d := range config.Deliveries {
+			fmt.Fprintf(&b, "  - cycle: %d\n", d.Cycle)
+			b.WriteString("    items:")
+			writeYAMLQuantities(&b, d.Items, "      ")
+		}
+	}
+	if len(config.Orders) > 0 {
+		b.WriteString("orders:\n")
+		for _, o := range config.Orders {
+			fmt.Fprintf(&b, "  - due: %d\n", o.Due)
+			b.WriteString("    items:")
+			writeYAMLQuantities(&b, o.Items, "      ")
+			fmt.Fprintf(&b, "    penalty: %d\n", o.Penalty)
+		}
+	}
+
+	b.WriteString("processes:")
+	if len(config.Processes) == 0 {
+		b.WriteString(" []")
+	}
+	b.WriteString("\n")
+	for _, proc := range config.Processes {
+		writeYAMLProcess(&b, proc)
+	}
+
+	if len(config.OptimizeTargets) > 0 {
+		targets := make([]string, len(config.OptimizeTargets))
+		for i, target := range config.OptimizeTargets {
+			targets[i] = yamlString(target)
+		}
+		fmt.Fprintf(&b, "optimize: [%s]\n", strings.Join(targets, ", "))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeYAMLProcess writes one process as an item of the processes sequence.
+func writeYAMLProcess(b *strings.Builder, proc *process.Process) {
+	fmt.Fprintf(b, "  - name: %s\n", yamlString(proc.Name))
+	b.WriteString("    needs:")
+	writeYAMLQuantities(b, proc.Needs, "      ")
+	results, yields := statedResults(proc)
+	b.WriteString("    results:")
+	writeYAMLQuantities(b, results, "      ")
+	if len(yields) > 0 {
+		b.WriteString("    yields:\n")
+		for _, item := range sortedKeys(yields) {
+			fmt.Fprintf(b, "      %s: %s\n", yamlString(item), yields[item])
+		}
+	}
+	if proc.Duration != nil {
+		fmt.Fprintf(b, "    duration: %s\n", yamlString(proc.Duration.String()))
+	} else {
+		fmt.Fprintf(b, "    cycles: %d\n", proc.Cycle)
+	}
+	if proc.Failure > 0 {
+		fmt.Fprintf(b, "    failure: %s\n", yamlString(strconv.FormatFloat(proc.Failure, 'g', -1, 64)))
+	}
+}
+
+// writeYAMLQuantities writes a mapping of quantities after its key, in lexical order,
+// or {} when it is empty.
+func writeYAMLQuantities(b *strings.Builder, quantities map[string]int, indent string) {
+	if len(quantities) == 0 {
+		b.WriteString(" {}\n")
+		return
+	}
+	b.WriteString("\n")
+	for _, name := range sortedKeys(quantities) {
+		fmt.Fprintf(b, "%s%s: %d\n", indent, yamlString(name), quantities[name])
+	}
+}
+
+// yamlString returns a name as a YAML scalar, double-quoted unless it reads back
+// unchanged as a plain string in both block and flow context.
+func yamlString(s string) string {
+	plain := s != "" && s != "null" && s != "~" && s != "-" &&
+		!strings.ContainsAny(s, ":#,[]{}\"'\\\t\r\n") &&
+		!strings.ContainsAny(s[:1], " -?!&*|>%@`") && !strings.HasSuffix(s, " ")
+	if plain {
+		return s
+	}
+	return strconv.Quote(s)
+}
@@ -0,0 +1,100 @@
+package util
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/jesee-kuya/stock_exchange/process"
+)
+
+// parseDuration parses a duration distribution in the configuration syntax, found at the
+// given offset of its line:
+//   - "uniform(min;max)": every whole number of cycles from min to max is equally likely.
+//   - "triangular(min;mode;max)": durations near mode are the most likely.
+//   - "histogram(value:weight;...)": an empirical distribution, each value being drawn in
+//     proportion to its weight, such as the number of times it was observed.
+//
+// Returns:
+//   - The distribution.
+//   - A *ParseError if the syntax is wrong, the bounds are out of order or a weight is not
+//     positive.
+func parseDuration(spec string, offset int) (*process.Duration, error) {
+	open := strings.Index(spec, "(")
+	if open == -1 || !strings.HasSuffix(spec, ")") {
+		return nil, newParseError(ErrInvalidCycles, offset, spec,
+			"invalid cycle count '%s', expected a number or uniform(min;max), triangular(min;mode;max) or histogram(value:weight;...)", spec)
+	}
+	kind := process.DurationKind(strings.TrimSpace(spec[:open]))
+	argOffset := offset + open + 1
+	args := strings.Split(spec[open+1:len(spec)-1], ";")
+
+	d := &process.Duration{Kind: kind}
+	switch kind {
+	case process.Uniform, process.Triangular:
+		want := 2
+		if kind == process.Triangular {
+			want = 3
+		}
+		if len(args) != want {
+			return nil, newParseError(ErrInvalidCycles, offset, spec,
+				"%s takes %d cycle counts separated by ';', got %d", kind, want, len(args))
+		}
+		values := make([]int, want)
+		for i, arg := range args {
+			n, err := durationNumber(arg, argOffset)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = n
+			argOffset += len(arg) + 1
+		}
+		d.Min, d.Max = values[0], values[want-1]
+		if kind == process.Triangular {
+			d.Mode = values[1]
+		}
+		if d.Min > d.Max || (kind == process.Triangular && (d.Mode < d.Min || d.Mode > d.Max)) {
+			return nil, newParseError(ErrInvalidCycles, offset, spec,
+				"%s bounds are out of order in '%s'", kind, spec)
+		}
+	case process.Histogram:
+		for _, arg := range args {
+			value, weight, ok := strings.Cut(arg, ":")
+			if !ok {
+				token, column := trimToken(arg, argOffset)
+				return nil, newParseError(ErrInvalidCycles, column, token,
+					"invalid histogram bin '%s', expected \"cycles:weight\"", token)
+			}
+			v, err := durationNumber(value, argOffset)
+			if err != nil {
+				return nil, err
+			}
+			w, err := durationNumber(weight, argOffset+len(value)+1)
+			if err != nil {
+				return nil, err
+			}
+			if w <= 0 {
+				token, column := trimToken(weight, argOffset+len(value)+1)
+				return nil, newParseError(ErrInvalidCycles, column, token,
+					"histogram weight must be positive, got %d", w)
+			}
+			d.Values = append(d.Values, v)
+			d.Weights = append(d.Weights, w)
+			argOffset += len(arg) + 1
+		}
+	default:
+		token, column := trimToken(spec[:open], offset)
+		return nil, newParseError(ErrInvalidCycles, column, token,
+			"unknown duration distribution '%s', expected uniform, triangular or histogram", token)
+	}
+	return d, nil
+}
+
+// durationNumber parses one integer of a duration distribution.
+func durationNumber(arg string, offset int) (int, error) {
+	token, column := trimToken(arg, offset)
+	n, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, newParseError(ErrInvalidCycles, column, token, "invalid cycle count '%s'", token)
+	}
+	return n, nil
+}
@@ -0,0 +1,183 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DefaultPenalty is the penalty per cycle late of an order that does not give one.
+const DefaultPenalty = 1
+
+// Delivery is an inbound delivery: items added to the stock at a given cycle, such as a
+// weekly flour shipment from a supplier.
+type Delivery struct {
+	Cycle int
+	Items map[string]int
+}
+
+// Order is an outbound customer order: items taken from the stock and shipped to the
+// customer, due at a given cycle.
+//
+// Fields:
+//   - Due: the cycle the order should ship by.
+//   - Items: the quantity of each item the order takes.
+//   - Penalty: the cost of every cycle the order ships after its due cycle.
+type Order struct {
+	Due     int
+	Items   map[string]int
+	Penalty int
+}
+
+// String describes the order, such as "bread:10 due 150".
+func (o Order) String() string {
+	parts := make([]string, 0, len(o.Items))
+	for _, item := range sortedKeys(o.Items) {
+		parts = append(parts, fmt.Sprintf("%s:%d", item, o.Items[item]))
+	}
+	return fmt.Sprintf("%s due %d", strings.Join(parts, ";"), o.Due)
+}
+
+// Keywords starting the event lines of the text format.
+const (
+	deliveryKeyword = "+cycle"
+	orderKeyword    = "-cycle"
+	penaltyKeyword  = "penalty"
+)
+
+// parseDelivery parses an inbound delivery line and appends it to config.Deliveries.
+//
+// The expected format for the line is: "+cycle <cycle> <items>", where the items are
+// "name:quantity" pairs separated by ';', optionally in parentheses.
+// For example: "+cycle 100 flour:20" or "+cycle 100 (flour:20;yeast:5)"
+//
+// Parameters:
+//   - config: a pointer to the ConfigData struct to be updated.
+//   - line: a string representing the delivery line.
+//
+// Returns:
+//   - A *ParseError if the keyword is misspelled, the cycle is not a non-negative
+//     integer, or the items are missing or malformed.
+func parseDelivery(config *ConfigData, line string) error {
+	cycle, items, err := parseEvent(line, deliveryKeyword, "")
+	if err != nil {
+		return err
+	}
+	config.Deliveries = append(config.Deliveries, Delivery{Cycle: cycle, Items: items})
+	return nil
+}
+
+// parseOrder parses a customer order line and appends it to config.Orders.
+//
+// The expected format for the line is: "-cycle <due> <items>", optionally followed by
+// "penalty <cost>", the cost of every cycle the order ships late, DefaultPenalty if
+// it is left out. The items are written as in a delivery line.
+// For example: "-cycle 150 bread:10 penalty 5"
+//
+// Parameters:
+//   - config: a pointer to the ConfigData struct to be updated.
+//   - line: a string representing the order line.
+//
+// Returns:
+//   - A *ParseError if the keyword is misspelled, the due cycle or the penalty is not
+//     a non-negative integer, or the items are missing or malformed.
+func parseOrder(config *ConfigData, line string) error {
+	penalty := DefaultPenalty
+	itemsEnd := len(line)
+	if at := penaltyAt(line); at >= 0 {
+		spec, offset := trimToken(line[at+len(penaltyKeyword):], at+len(penaltyKeyword))
+		var err error
+		if penalty, err = strconv.Atoi(spec); err != nil || penalty < 0 {
+			return newParseError(ErrInvalidQuantity, offset, spec,
+				"invalid penalty '%s', expected a non-negative integer", spec)
+		}
+		itemsEnd = at
+	}
+	due, items, err := parseEvent(line[:itemsEnd], orderKeyword, " [penalty <cost>]")
+	if err != nil {
+		return err
+	}
+	config.Orders = append(config.Orders, Order{Due: due, Items: items, Penalty: penalty})
+	return nil
+}
+
+// penaltyAt returns the offset of the "penalty" keyword of an order line, a word of its
+// own after the items, or -1 when there is none.
+func penaltyAt(line string) int {
+	at := strings.LastIndex(line, penaltyKeyword)
+	if at <= 0 || !unicode.IsSpace(rune(line[at-1])) {
+		return -1
+	}
+	if end := at + len(penaltyKeyword); end < len(line) && !unicode.IsSpace(rune(line[end])) {
+		return -1
+	}
+	return at
+}
+
+// parseEvent parses the part of an event line shared by deliveries and orders: the
+// keyword, the cycle and the items. suffix completes the expected format in errors.
+//
+// Returns:
+//   - The cycle and the items.
+//   - A *ParseError locating the problem within the line.
+func parseEvent(line, keyword, suffix string) (int, map[string]int, error) {
+	expected := fmt.Sprintf("expected \"%s <cycle> <item>:<quantity>;...%s\"", keyword, suffix)
+	rest, found := strings.CutPrefix(line, keyword)
+	if !found || rest != "" && !unicode.IsSpace(rune(rest[0])) {
+		word := strings.FieldsFunc(line, unicode.IsSpace)[0]
+		return 0, nil, newParseError(ErrInvalidEvent, 0, word, "invalid event '%s', %s", word, expected)
+	}
+
+	spec, offset := trimToken(rest, len(keyword))
+	cycleStr := spec
+	if end := strings.IndexFunc(spec, unicode.IsSpace); end >= 0 {
+		cycleStr = spec[:end]
+	}
+	cycle, err := strconv.Atoi(cycleStr)
+	if err != nil || cycle < 0 {
+		if cycleStr == "" {
+			return 0, nil, newParseError(ErrInvalidEvent, offset, "", "missing cycle, %s", expected)
+		}
+		return 0, nil, newParseError(ErrInvalidCycles, offset, cycleStr,
+			"invalid cycle '%s', expected a non-negative integer", cycleStr)
+	}
+
+	itemsStr, itemsOffset := trimToken(spec[len(cycleStr):], offset+len(cycleStr))
+	if itemsStr == "" {
+		return 0, nil, newParseError(ErrInvalidEvent, itemsOffset, "", "missing items, %s", expected)
+	}
+	items, err := parseResourceMap(itemsStr, itemsOffset)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(items) == 0 {
+		return 0, nil, newParseError(ErrInvalidEvent, itemsOffset, itemsStr, "missing items, %s", expected)
+	}
+	return cycle, items, nil
+}
+
+// eventLines returns the delivery and order lines of a configuration in the text format,
+// deliveries first, each in its order.
+func eventLines(config *ConfigData) ([]string, error) {
+	lines := []string{}
+	for _, d := range config.Deliveries {
+		items, err := textResources(d.Items)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, fmt.Sprintf("%s %d %s", deliveryKeyword, d.Cycle, items))
+	}
+	for _, o := range config.Orders {
+		items, err := textResources(o.Items)
+		if err != nil {
+			return nil, err
+		}
+		line := fmt.Sprintf("%s %d %s", orderKeyword, o.Due, items)
+		if o.Penalty != DefaultPenalty {
+			line += fmt.Sprintf(" %s %d", penaltyKeyword, o.Penalty)
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
@@ -0,0 +1,151 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// includeConfig handles an "include:path" line of a text configuration by parsing the
+// included file and merging its definitions into config.
+//
+// Syntax:
+//   - "include:shared/oven.conf" includes the file with the namespace "oven", the file
+//     name without its extension.
+//   - "include:shared/oven.conf as bakery_oven" includes it with the namespace "bakery_oven".
+//
+// Behavior:
+//   - A relative path is resolved against the directory of the including file, and the
+//     included file may be in any format, chosen by its extension.
+//   - Process names are prefixed with the namespace and a dot, so "bake" becomes "oven.bake";
+//     includes nest, giving names such as "bakery.oven.bake". Items are not namespaced, so the
+//     included processes share their needs and results with the including file.
+//   - Included deliveries and orders are added after the ones defined so far.
+//   - Included stocks, renewable resources and shelf lives are set as if they were defined on
+//     the include line: later definitions of the same stock override them. The optimize targets of
+//     included files are ignored.
+//   - The definitions merged from an included file are recorded in config.Lines at the line
+//     of the include directive.
+//   - Including a file that is already being included, directly or not, is an error.
+//
+// Parameters:
+//   - config: the configuration being parsed.
+//   - path: the file holding the include directive.
+//   - includedBy: the files that included path, outermost first.
+//   - text: the full source line of the directive.
+//   - lineNumber: its line number.
+//
+// Returns:
+//   - A *ParseError for a malformed directive, an unreadable file or an include cycle.
+//     Errors in the included file keep their own position and gain the include chain.
+func includeConfig(config *ConfigData, path string, includedBy []string, text string, lineNumber int) error {
+	locate := func(err *ParseError) *ParseError {
+		err.File, err.Line, err.Text = path, lineNumber, text
+		return err
+	}
+
+	_, indent := trimToken(text, 0)
+	start := indent + len("include:")
+	target, offset := trimToken(text[start:], start)
+	namespace := ""
+	if i := strings.LastIndex(target, " as "); i != -1 {
+		namespace, _ = trimToken(target[i+len(" as "):], 0)
+		target = strings.TrimSpace(target[:i])
+		if !validNamespace(namespace) {
+			return locate(newParseError(ErrInclude, offset+strings.LastIndex(text[offset:], namespace), namespace,
+				"invalid namespace '%s', expected a name without spaces, '.', ':', ';', '(' or ')'", namespace))
+		}
+	}
+	if target == "" {
+		return locate(newParseError(ErrInclude, start, "", "missing path in include directive"))
+	}
+	if namespace == "" {
+		namespace = strings.TrimSuffix(filepath.Base(target), filepath.Ext(target))
+		if !validNamespace(namespace) {
+			return locate(newParseError(ErrInclude, offset, target,
+				"file name '%s' is not a valid namespace, add \"as <namespace>\"", filepath.Base(target)))
+		}
+	}
+
+	resolved := target
+	if !filepath.IsAbs(resolved) {
+		resolved = filepath.Join(filepath.Dir(path), resolved)
+	}
+	chain := append(append([]string{}, includedBy...), path)
+	for i, file := range chain {
+		if samePath(file, resolved) {
+			cycle := append(append([]string{}, chain[i:]...), resolved)
+			return locate(newParseError(ErrIncludeCycle, offset, target,
+				"include cycle: %s", strings.Join(cycle, " -> ")))
+		}
+	}
+
+	data, err := os.ReadFile(resolved)
+	if err != nil {
+		return locate(newParseError(ErrInclude, offset, target, "cannot read included file: %v", err))
+	}
+	included, err := parseConfigData(resolved, data, FormatAuto, chain)
+	if err != nil {
+		var parseErr *ParseError
+		if errors.As(err, &parseErr) {
+			parseErr.IncludedFrom = append(parseErr.IncludedFrom, IncludeSite{File: path, Line: lineNumber})
+			return parseErr
+		}
+		return fmt.Errorf("%s:%d: included file %s: %w", path, lineNumber, resolved, err)
+	}
+
+	for _, name := range sortedKeys(included.Stocks) {
+		_, wasRenewable := config.Renewables[name]
+		_, isRenewable := included.Renewables[name]
+		if _, wasStock := config.Stocks[name]; wasStock && wasRenewable != isRenewable {
+			return locate(newParseError(ErrRenewableConflict, offset, target,
+				"'%s' is a stock in one file and a renewable resource in the other", name))
+		}
+		config.Stocks[name] = included.Stocks[name]
+		config.Lines.Stocks[name] = lineNumber
+		if isRenewable {
+			config.Renewables[name] = included.Renewables[name]
+		}
+		if capacity, ok := included.Capacities[name]; ok {
+			config.Capacities[name] = capacity
+		} else {
+			delete(config.Capacities, name)
+		}
+	}
+	for _, name := range sortedKeys(included.ShelfLife) {
+		config.ShelfLife[name] = included.ShelfLife[name]
+		config.Lines.Perishables[name] = lineNumber
+	}
+	for _, p := range included.Processes {
+		namespaced := *p
+		namespaced.Name = namespace + "." + p.Name
+		config.Processes = append(config.Processes, &namespaced)
+		config.Lines.Processes = append(config.Lines.Processes, lineNumber)
+	}
+	for _, d := range included.Deliveries {
+		config.Deliveries = append(config.Deliveries, d)
+		config.Lines.Deliveries = append(config.Lines.Deliveries, lineNumber)
+	}
+	for _, o := range included.Orders {
+		config.Orders = append(config.Orders, o)
+		config.Lines.Orders = append(config.Lines.Orders, lineNumber)
+	}
+	return nil
+}
+
+// validNamespace reports whether a namespace can prefix process names in every format.
+func validNamespace(namespace string) bool {
+	return namespace != "" && !strings.ContainsAny(namespace, " \t.:;()#")
+}
+
+// samePath reports whether two paths name the same file, comparing their absolute forms.
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
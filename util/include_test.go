@@ -0,0 +1,111 @@
+package util
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeTree writes the given files under a temporary directory and returns the directory.
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// TestInclude tests that included processes are namespaced, that relative paths are
+// resolved against the including file, and that stocks merge in file order.
+func TestInclude(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"lines/cabinet.conf": "board:7\ninclude:../shared/parts.conf\ninclude:../shared/parts.conf as spare\nboard:9\n" +
+			"do_cabinet:(doorknobs:2;shelf:3):(cabinet:1):30\noptimize:(cabinet)\n",
+		"shared/parts.conf": "board:1\nscrew:4\ninclude:knobs.yaml\ndo_shelf:(board:1):(shelf:1):10\noptimize:(shelf)\n",
+		"shared/knobs.yaml": "processes:\n  - name: do_doorknobs\n    needs: {board: 1}\n    results: {doorknobs: 1}\n    cycles: 15\n",
+	})
+
+	config, err := ParseConfig(filepath.Join(dir, "lines/cabinet.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	for _, p := range config.Processes {
+		names = append(names, p.Name)
+	}
+	want := []string{"parts.knobs.do_doorknobs", "parts.do_shelf", "spare.knobs.do_doorknobs", "spare.do_shelf", "do_cabinet"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("processes = %v, want %v", names, want)
+	}
+	if config.Stocks["board"] != 9 || config.Stocks["screw"] != 4 {
+		t.Errorf("Stocks = %v, want board overridden to 9 and screw included", config.Stocks)
+	}
+	if !reflect.DeepEqual(config.OptimizeTargets, []string{"cabinet"}) {
+		t.Errorf("OptimizeTargets = %v, want only the top-level targets", config.OptimizeTargets)
+	}
+	if !reflect.DeepEqual(config.Lines.Processes, []int{2, 2, 3, 3, 5}) || config.Lines.Stocks["screw"] != 3 {
+		t.Errorf("Lines = %+v", config.Lines)
+	}
+}
+
+// TestIncludeErrors tests that include cycles, missing files and errors inside included
+// files are reported with the include chain.
+func TestIncludeErrors(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"main.conf":    "include:a.conf\n",
+		"a.conf":       "# a\ninclude:sub/b.conf\n",
+		"sub/b.conf":   "include:../main.conf\n",
+		"broken.conf":  "board:1\ninclude:bad.conf\n",
+		"bad.conf":     "board:1\ndo_shelf:(board:x):(shelf:1):10\n",
+		"missing.conf": "  include:nowhere.conf\n",
+	})
+
+	testCases := []struct {
+		name     string
+		file     string
+		code     ErrorCode
+		errFile  string
+		line     int
+		included []IncludeSite
+		message  string
+	}{
+		{"cycle", "main.conf", ErrIncludeCycle, "sub/b.conf", 1,
+			[]IncludeSite{{"a.conf", 2}, {"main.conf", 1}}, "main.conf -> " + filepath.Join(dir, "a.conf")},
+		{"error in included file", "broken.conf", ErrInvalidQuantity, "bad.conf", 2,
+			[]IncludeSite{{"broken.conf", 2}}, "'x'"},
+		{"missing file", "missing.conf", ErrInclude, "missing.conf", 1, nil, "cannot read included file"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseConfig(filepath.Join(dir, tc.file))
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("expected a *ParseError, got %v", err)
+			}
+			if parseErr.Code != tc.code || parseErr.File != filepath.Join(dir, tc.errFile) || parseErr.Line != tc.line {
+				t.Errorf("got %s:%d [%s], want %s:%d [%s]", parseErr.File, parseErr.Line, parseErr.Code, tc.errFile, tc.line, tc.code)
+			}
+			for i := range tc.included {
+				tc.included[i].File = filepath.Join(dir, tc.included[i].File)
+			}
+			if len(tc.included) > 0 && !reflect.DeepEqual(parseErr.IncludedFrom, tc.included) {
+				t.Errorf("IncludedFrom = %v, want %v", parseErr.IncludedFrom, tc.included)
+			}
+			if !strings.Contains(parseErr.Message, tc.message) {
+				t.Errorf("message %q does not mention %q", parseErr.Message, tc.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package util
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/jesee-kuya/stock_exchange/process"
+)
+
+// isYieldRange reports whether a result quantity is a yield range such as "2-3" rather
+// than a single number; a leading '-' is the sign of a negative quantity.
+func isYieldRange(quantity string) bool {
+	return len(quantity) > 1 && strings.Contains(quantity[1:], "-")
+}
+
+// parseYield parses a yield range "min-max", found at the given offset of its line.
+//
+// Returns:
+//   - The range.
+//   - A *ParseError if a bound is not a number or the bounds are out of order.
+func parseYield(spec string, offset int) (process.Yield, error) {
+	dash := strings.Index(spec[1:], "-") + 1
+	lo, loOffset := trimToken(spec[:dash], offset)
+	hi, hiOffset := trimToken(spec[dash+1:], offset+dash+1)
+	minimum, err := strconv.Atoi(lo)
+	if err != nil {
+		return process.Yield{}, newParseError(ErrInvalidQuantity, loOffset, lo, "invalid yield '%s', expected \"min-max\"", lo)
+	}
+	maximum, err := strconv.Atoi(hi)
+	if err != nil {
+		return process.Yield{}, newParseError(ErrInvalidQuantity, hiOffset, hi, "invalid yield '%s', expected \"min-max\"", hi)
+	}
+	if minimum > maximum {
+		return process.Yield{}, newParseError(ErrInvalidQuantity, offset, spec, "yield bounds are out of order in '%s'", spec)
+	}
+	return process.Yield{Min: minimum, Max: maximum}, nil
+}
+
+// parseFailure parses a failure probability, a percentage such as "5%" or a fraction
+// such as "0.05", found at the given offset of its line.
+//
+// Returns:
+//   - The probability.
+//   - A *ParseError if it is not a number, or not at least 0 and below 1.
+func parseFailure(spec string, offset int) (float64, error) {
+	number, percent := strings.CutSuffix(spec, "%")
+	p, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || math.IsNaN(p) {
+		return 0, newParseError(ErrInvalidFailure, offset, spec,
+			"invalid failure probability '%s', expected a percentage such as 5%% or a fraction such as 0.05", spec)
+	}
+	if percent {
+		p /= 100
+	}
+	if p < 0 || p >= 1 {
+		return 0, newParseError(ErrInvalidFailure, offset, spec,
+			"failure probability must be at least 0 and below 1, got '%s'", spec)
+	}
+	return p, nil
+}
+
+// failureSpec returns a failure probability in the configuration syntax: a percentage
+// when it reads back as the same probability, a fraction otherwise.
+func failureSpec(p float64) string {
+	percent := strconv.FormatFloat(p*100, 'g', 12, 64) + "%"
+	if back, err := parseFailure(percent, 0); err == nil && back == p {
+		return percent
+	}
+	return strconv.FormatFloat(p, 'g', -1, 64)
+}
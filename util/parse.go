@@ -2,10 +2,12 @@ package util
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
-	"os"
+	"io"
 	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/jesee-kuya/stock_exchange/process"
 )
@@ -18,53 +20,169 @@ import (
 //   - Processes: a slice of pointers to Process structs, each representing a process definition.
 //   - OptimizeTargets: a slice of strings specifying the optimization goals extracted from the config file.
 //   - HasOptimizer: a boolean flag to track if an optimizer has already been defined.
+//   - Lines: the line numbers of the definitions, filled in by ParseConfig for diagnostics.
+//   - Renewables: the capacity of each renewable resource, such as a machine or a worker.
+//     Renewables are also in Stocks with their capacity as initial quantity, and the
+//     processes needing them list them in Process.Holds, so they are given back at completion.
+//   - Capacities: the storage capacity of the items that have one; their stock may never
+//     exceed it. Every item with a capacity is also in Stocks.
+//   - ShelfLife: the number of cycles the units of each perishable item keep; a unit
+//     produced at cycle c is gone at cycle c+ShelfLife, and initial stocks count as
+//     produced at cycle 0.
+//   - Deliveries: the inbound deliveries, adding items to the stock at a given cycle, in
+//     their order in the file.
+//   - Orders: the customer orders, taking items from the stock by a due cycle, in their
+//     order in the file.
 type ConfigData struct {
 	Stocks          map[string]int
 	Processes       []*process.Process
 	OptimizeTargets []string
 	HasOptimizer    bool
+	Lines           SourceLines
+	Renewables      map[string]int
+	Capacities      map[string]int
+	ShelfLife       map[string]int
+	Deliveries      []Delivery
+	Orders          []Order
 }
 
-// ParseConfig reads a configuration file from the specified path and parses its contents
-// into a ConfigData struct. The configuration file is expected to define initial stock
-// quantities, process definitions, and optimization targets. Each line in the file is
-// interpreted based on its format:
-//   - Stock definitions: "name:quantity"
-//   - Process definitions: "name:(needs):(results):cycles"
-//   - Optimization targets: "optimize:(target1;target2;...)"
+// SourceLines records on which line of the configuration file each definition appeared.
+// Line numbers are 1-based; a zero line means the position is unknown, as for
+// configurations built in code.
 //
-// Lines that are empty or start with '#' are ignored as comments.
-// Returns a pointer to the populated ConfigData struct or an error if parsing fails.
-func ParseConfig(path string) (*ConfigData, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open config file: %w", err)
-	}
-	defer file.Close()
+// Fields:
+//   - Stocks: the line of the last definition of each stock or renewable resource.
+//   - Processes: the line of each process, parallel to ConfigData.Processes.
+//   - Optimize: the line of the optimize declaration.
+//   - Perishables: the line of the last shelf life declaration of each perishable item.
+//   - Deliveries, Orders: the line of each delivery and order, parallel to
+//     ConfigData.Deliveries and ConfigData.Orders.
+type SourceLines struct {
+	Stocks      map[string]int
+	Processes   []int
+	Optimize    int
+	Perishables map[string]int
+	Deliveries  []int
+	Orders      []int
+}
 
-	config := &ConfigData{
+// newConfigData returns an empty configuration ready to be filled by a parser.
+func newConfigData() *ConfigData {
+	return &ConfigData{
 		Stocks:          make(map[string]int),
 		Processes:       make([]*process.Process, 0),
 		OptimizeTargets: make([]string, 0),
 		HasOptimizer:    false,
+		Lines:           SourceLines{Stocks: make(map[string]int), Perishables: make(map[string]int)},
+		Renewables:      make(map[string]int),
+		Capacities:      make(map[string]int),
+		ShelfLife:       make(map[string]int),
 	}
+}
+
+// markHeld fills in the Holds of every process from the renewable resources among its needs.
+// It is called once a configuration is parsed, since renewables may be declared after the
+// processes using them, and can be called again after adding renewables or processes.
+func markHeld(config *ConfigData) {
+	for _, p := range config.Processes {
+		var holds map[string]int
+		for item, qty := range p.Needs {
+			if _, ok := config.Renewables[item]; ok {
+				if holds == nil {
+					holds = map[string]int{}
+				}
+				holds[item] = qty
+			}
+		}
+		p.Holds = holds
+	}
+}
+
+// ProcessLine returns the line of the i-th process, or 0 when it is unknown.
+func (l SourceLines) ProcessLine(i int) int {
+	if i < len(l.Processes) {
+		return l.Processes[i]
+	}
+	return 0
+}
+
+// DeliveryLine returns the line of the i-th delivery, or 0 when it is unknown.
+func (l SourceLines) DeliveryLine(i int) int {
+	if i < len(l.Deliveries) {
+		return l.Deliveries[i]
+	}
+	return 0
+}
+
+// OrderLine returns the line of the i-th order, or 0 when it is unknown.
+func (l SourceLines) OrderLine(i int) int {
+	if i < len(l.Orders) {
+		return l.Orders[i]
+	}
+	return 0
+}
+
+// ParseConfig reads a configuration file from the specified path and parses its contents
+// into a ConfigData struct. The format is chosen by the file extension: ".json" files hold
+// JSON, ".yaml" and ".yml" files YAML (see ParseConfigAs), and any other file the line-based
+// text format, which is expected to define initial stock quantities, process definitions,
+// and optimization targets. Each line in the file is interpreted based on its format:
+//   - Stock definitions: "name:quantity", or "name:quantity/capacity" for an item whose
+//     storage is limited
+//   - Process definitions: "name:(needs):(results):cycles", optionally followed by
+//     ":failure", with yield ranges such as "bread:2-3" among the results
+//   - Optimization targets: "optimize:(target1;target2;...)"
+//   - Renewable resources: "renewable:(name1:capacity1;name2:capacity2;...)"
+//   - Perishable items: "perishable:(name1:shelf_life1;name2:shelf_life2;...)"
+//   - Includes: "include:path" or "include:path as namespace" (see includeConfig)
+//   - Deliveries: "+cycle 100 flour:20", adding items to the stock at a cycle
+//   - Orders: "-cycle 150 bread:10 penalty 5", taking items from the stock by a due cycle
+//
+// Lines that are empty or start with '#' are ignored as comments.
+// Returns a pointer to the populated ConfigData struct or an error if parsing fails.
+func ParseConfig(path string) (*ConfigData, error) {
+	return ParseConfigAs(path, FormatAuto)
+}
 
-	scanner := bufio.NewScanner(file)
+// parseTextConfig parses a configuration in the line-based text format read from r.
+// The path locates errors and resolves include directives; includedBy lists the files
+// that included this one, outermost first.
+func parseTextConfig(path string, r io.Reader, includedBy []string) (*ConfigData, error) {
+	config := newConfigData()
+
+	scanner := bufio.NewScanner(r)
 	lineNumber := 0
 
 	for scanner.Scan() {
 		lineNumber++
-		line := strings.TrimSpace(scanner.Text())
+		text := scanner.Text()
+		line, indent := trimToken(text, 0)
 
 		// Skip empty lines and comments
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
 
+		if lineKindOf(line) == includeLine {
+			if err := includeConfig(config, path, includedBy, text, lineNumber); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
 		// Parse the line based on its format
 		if err := parseLine(config, line); err != nil {
-			return nil, fmt.Errorf(" Error while parsing `%s`\nExiting... ", line)
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, err
+			}
+			parseErr.File = path
+			parseErr.Line = lineNumber
+			parseErr.Column += indent
+			parseErr.Text = text
+			return nil, parseErr
 		}
+		recordLine(config, line, lineNumber)
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -79,54 +197,246 @@ func ParseConfig(path string) (*ConfigData, error) {
 //   - Stock definitions (e.g., "name:quantity") are handled by parseStock.
 //   - Process definitions (e.g., "name:(needs):(results):cycles") are handled by parseProcess.
 //   - Optimization targets (e.g., "optimize:(target1;target2;...)") are handled by parseOptimize.
+//   - Renewable resources (e.g., "renewable:(oven:2)") are handled by parseRenewable.
+//   - Perishable items (e.g., "perishable:(dough:10)") are handled by parsePerishable.
+//   - Deliveries (e.g., "+cycle 100 flour:20") are handled by parseDelivery.
+//   - Orders (e.g., "-cycle 150 bread:10 penalty 5") are handled by parseOrder.
 //
-// Returns an error if the line format is unrecognized or if parsing fails.
+// Returns a *ParseError if the line format is unrecognized or if parsing fails. Its column
+// is relative to the given line; ParseConfig fills in the file and line number.
 func parseLine(config *ConfigData, line string) error {
+	switch lineKindOf(line) {
+	case stockLine:
+		return parseStock(config, line)
+	case optimizeLine:
+		return parseOptimize(config, line)
+	case processLine:
+		return parseProcess(config, line)
+	case renewableLine:
+		return parseRenewable(config, line)
+	case perishableLine:
+		return parsePerishable(config, line)
+	case deliveryLine:
+		return parseDelivery(config, line)
+	case orderLine:
+		return parseOrder(config, line)
+	}
+
+	return newParseError(ErrUnrecognizedLine, 0, line,
+		"unrecognized line format, expected a stock, a process, an event or an optimize line")
+}
+
+// lineKind is the kind of definition held by a configuration line.
+type lineKind int
+
+const (
+	unknownLine lineKind = iota
+	stockLine
+	optimizeLine
+	processLine
+	includeLine
+	renewableLine
+	perishableLine
+	deliveryLine
+	orderLine
+)
+
+// lineKindOf classifies a trimmed, non-comment configuration line by its format.
+func lineKindOf(line string) lineKind {
+	if strings.HasPrefix(line, "include:") {
+		return includeLine
+	}
+	if strings.HasPrefix(line, "renewable:") {
+		return renewableLine
+	}
+	if strings.HasPrefix(line, "perishable:") {
+		return perishableLine
+	}
+	if strings.HasPrefix(line, "+") {
+		return deliveryLine
+	}
+	if strings.HasPrefix(line, "-") {
+		return orderLine
+	}
+
 	// Check if it's a stock definition (name:quantity)
 	if !strings.Contains(line, "(") && strings.Contains(line, ":") && !strings.HasPrefix(line, "optimize:") {
-		return parseStock(config, line)
+		return stockLine
 	}
 
 	// Check if it's an optimize line
 	if strings.HasPrefix(line, "optimize:") {
-		return parseOptimize(config, line)
+		return optimizeLine
 	}
 
 	// Check if it's a process definition (contains parentheses)
 	if strings.Contains(line, "(") && strings.Contains(line, ")") {
-		return parseProcess(config, line)
+		return processLine
 	}
+	return unknownLine
+}
 
-	return fmt.Errorf("unrecognized line format: %s", line)
+// recordLine stores the line number of a definition that parseLine just added to the config.
+func recordLine(config *ConfigData, line string, lineNumber int) {
+	switch lineKindOf(line) {
+	case stockLine:
+		name, _ := trimToken(line[:strings.Index(line, ":")], 0)
+		config.Lines.Stocks[name] = lineNumber
+	case optimizeLine:
+		config.Lines.Optimize = lineNumber
+	case renewableLine:
+		resources, _ := parseResourceMap(strings.TrimPrefix(line, "renewable:"), 0)
+		for name := range resources {
+			config.Lines.Stocks[name] = lineNumber
+		}
+	case perishableLine:
+		items, _ := parseResourceMap(strings.TrimPrefix(line, "perishable:"), 0)
+		for name := range items {
+			config.Lines.Perishables[name] = lineNumber
+		}
+	case processLine:
+		config.Lines.Processes = append(config.Lines.Processes, lineNumber)
+	case deliveryLine:
+		config.Lines.Deliveries = append(config.Lines.Deliveries, lineNumber)
+	case orderLine:
+		config.Lines.Orders = append(config.Lines.Orders, lineNumber)
+	}
 }
 
 // parseStock parses a single line of stock data and updates the provided ConfigData.
 //
-// The expected format for the line is: "item_name:quantity" (e.g., "euro:10").
+// The expected format for the line is: "item_name:quantity" (e.g., "euro:10"), or
+// "item_name:quantity/capacity" (e.g., "bread:0/50") to limit the storage of the item.
 // It splits the line at the colon, trims whitespace, and converts the quantity to an integer.
-// If parsing succeeds, the item and its quantity are added to the config's Stocks map.
+// If parsing succeeds, the item and its quantity are added to the config's Stocks map, and
+// its capacity, if any, to the Capacities map.
 //
 // Parameters:
 //   - config: a pointer to the ConfigData struct to be updated.
 //   - line: a string representing one line of stock information.
 //
 // Returns:
-//   - An error if the line format is invalid or the quantity is not a valid integer.
+//   - A *ParseError if the line format is invalid, the quantity is not a valid integer
+//     or the capacity is not a non-negative integer.
 func parseStock(config *ConfigData, line string) error {
-	parts := strings.Split(line, ":")
-	if len(parts) != 2 {
-		return fmt.Errorf("invalid stock format: %s", line)
+	colonIndex := strings.Index(line, ":")
+	if extra := strings.Index(line[colonIndex+1:], ":"); extra != -1 {
+		return newParseError(ErrInvalidStock, colonIndex+1+extra, ":",
+			"invalid stock format, expected \"name:quantity\"")
 	}
 
-	name := strings.TrimSpace(parts[0])
-	quantityStr := strings.TrimSpace(parts[1])
+	name, _ := trimToken(line[:colonIndex], 0)
+	if name == "" {
+		return newParseError(ErrMissingName, 0, "", "missing stock name before ':'")
+	}
 
+	quantityStr, offset := trimToken(line[colonIndex+1:], colonIndex+1)
+	capacity, hasCapacity := 0, false
+	if slash := strings.Index(quantityStr, "/"); slash != -1 {
+		capacityStr, capacityOffset := trimToken(quantityStr[slash+1:], offset+slash+1)
+		var err error
+		if capacity, err = strconv.Atoi(capacityStr); err != nil || capacity < 0 {
+			return newParseError(ErrInvalidQuantity, capacityOffset, capacityStr,
+				"invalid storage capacity '%s', expected a non-negative integer", capacityStr)
+		}
+		hasCapacity = true
+		quantityStr, _ = trimToken(quantityStr[:slash], offset)
+	}
 	quantity, err := strconv.Atoi(quantityStr)
 	if err != nil {
-		return fmt.Errorf("invalid stock quantity '%s': %w", quantityStr, err)
+		return newParseError(ErrInvalidQuantity, offset, quantityStr,
+			"invalid stock quantity '%s'", quantityStr)
+	}
+
+	if _, ok := config.Renewables[name]; ok {
+		return newParseError(ErrRenewableConflict, 0, name,
+			"'%s' is already declared as a renewable resource", name)
 	}
 
 	config.Stocks[name] = quantity
+	if hasCapacity {
+		config.Capacities[name] = capacity
+	} else {
+		delete(config.Capacities, name)
+	}
+	return nil
+}
+
+// parseRenewable parses a renewable resource declaration and updates the provided ConfigData.
+//
+// The expected format for the line is: "renewable:(name1:capacity1;name2:capacity2;...)".
+// For example: "renewable:(oven:2;baker:3)"
+//
+// Behavior:
+//   - Each resource is added to config.Renewables with its capacity, and to config.Stocks
+//     with the capacity as initial quantity.
+//   - A process listing a renewable among its needs holds it for its duration and gives it
+//     back when it completes, without listing it in its results.
+//   - Declaring a name that is already a plain stock is an error; declaring a renewable
+//     again replaces its capacity.
+//
+// Parameters:
+//   - config: a pointer to the ConfigData struct to be updated.
+//   - line: a string representing the declaration line.
+//
+// Returns:
+//   - A *ParseError if the resource list is malformed or a name is already a stock.
+func parseRenewable(config *ConfigData, line string) error {
+	block := strings.TrimPrefix(line, "renewable:")
+	offset := len("renewable:")
+	if trimmed, _ := trimToken(block, 0); !strings.HasPrefix(trimmed, "(") || !strings.HasSuffix(trimmed, ")") {
+		return newParseError(ErrInvalidResource, offset, block,
+			"invalid renewable format, expected \"renewable:(name:capacity;...)\"")
+	}
+	resources, err := parseResourceMap(block, offset)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range sortedKeys(resources) {
+		if _, isRenewable := config.Renewables[name]; !isRenewable {
+			if _, isStock := config.Stocks[name]; isStock {
+				return newParseError(ErrRenewableConflict, offset+strings.Index(block, name), name,
+					"'%s' is already defined as a stock", name)
+			}
+		}
+		config.Renewables[name] = resources[name]
+		config.Stocks[name] = resources[name]
+	}
+	return nil
+}
+
+// parsePerishable parses a shelf life declaration and updates the provided ConfigData.
+//
+// The expected format for the line is: "perishable:(name1:shelf_life1;name2:shelf_life2;...)".
+// For example: "perishable:(dough:10)"
+//
+// Behavior:
+//   - Each item is added to config.ShelfLife with its shelf life in cycles. The item need
+//     not be a stock; declaring it again replaces its shelf life.
+//   - The units of a perishable item are tracked as lots by their production cycle, consumed
+//     oldest first, and removed from the stock once their shelf life has elapsed.
+//
+// Parameters:
+//   - config: a pointer to the ConfigData struct to be updated.
+//   - line: a string representing the declaration line.
+//
+// Returns:
+//   - A *ParseError if the item list is malformed.
+func parsePerishable(config *ConfigData, line string) error {
+	block := strings.TrimPrefix(line, "perishable:")
+	offset := len("perishable:")
+	if trimmed, _ := trimToken(block, 0); !strings.HasPrefix(trimmed, "(") || !strings.HasSuffix(trimmed, ")") {
+		return newParseError(ErrInvalidResource, offset, block,
+			"invalid perishable format, expected \"perishable:(name:shelf_life;...)\"")
+	}
+	items, err := parseResourceMap(block, offset)
+	if err != nil {
+		return err
+	}
+	for name, life := range items {
+		config.ShelfLife[name] = life
+	}
 	return nil
 }
 
@@ -148,11 +458,11 @@ func parseStock(config *ConfigData, line string) error {
 //   - line: a string representing the line containing optimization targets.
 //
 // Returns:
-//   - An error if a duplicate optimizer is detected, nil otherwise.
+//   - A *ParseError if a duplicate optimizer is detected, nil otherwise.
 func parseOptimize(config *ConfigData, line string) error {
 	// Check if optimizer has already been defined
 	if config.HasOptimizer {
-		return fmt.Errorf("multiple optimize declarations found")
+		return newParseError(ErrDuplicateOptimize, 0, "optimize", "multiple optimize declarations found")
 	}
 
 	// Remove "optimize:" prefix and parse the targets
@@ -179,20 +489,28 @@ func parseOptimize(config *ConfigData, line string) error {
 
 // parseProcess parses a process definition line and updates the provided ConfigData.
 //
-// The expected format for the line is: "process_name:(needs):(results):cycles".
-// For example: "build_product:(material:1):(product:1):30"
+// The expected format for the line is: "process_name:(needs):(results):cycles", or
+// "process_name:(needs):(results):cycles:failure" for a process that may fail.
+// For example: "build_product:(material:1):(product:1):30" or "bake:(dough:1):(bread:2-3):5:10%"
 //
 // Components:
 //   - process_name: The unique identifier for the process.
 //   - (needs): A parentheses-enclosed list of required resources in format "resource:quantity;resource:quantity;...".
 //   - (results): A parentheses-enclosed list of produced resources in format "resource:quantity;resource:quantity;...".
-//   - cycles: An integer representing the number of cycles required to complete the process.
+//     A quantity may be a yield range "min-max" for a result that varies from run to run.
+//     Every result is planned with its expected quantity, failures included (see
+//     process.Process.PlanExpected).
+//   - cycles: An integer representing the number of cycles required to complete the process,
+//     or a duration distribution such as "uniform(4;8)" (see parseDuration), whose rounded
+//     mean becomes the cycle count.
+//   - failure: The optional probability that a run fails, as a percentage such as "10%" or a
+//     fraction such as "0.1".
 //
 // Behavior:
 //   - Extracts the process name from the beginning of the line up to the first colon.
 //   - Splits the remainder into three parts: needs, results, and cycles.
 //   - Parses needs and results using parseResourceMap to convert them into resource maps.
-//   - Converts the cycles string to an integer.
+//   - Converts the cycles string to an integer, or parses it as a duration distribution.
 //   - Creates a new Process struct and appends it to config.Processes.
 //
 // Parameters:
@@ -200,50 +518,91 @@ func parseOptimize(config *ConfigData, line string) error {
 //   - line: a string representing the process definition line.
 //
 // Returns:
-//   - An error if the line format is invalid, resource parsing fails, or cycles is not a valid integer.
+//   - A *ParseError if the line format is invalid, resource parsing fails, or cycles is not a valid integer.
 func parseProcess(config *ConfigData, line string) error {
 	// Find the first colon to separate name from the rest
 	colonIndex := strings.Index(line, ":")
 	if colonIndex == -1 {
-		return fmt.Errorf("%s", line)
+		return newParseError(ErrInvalidProcess, 0, line,
+			"invalid process format, expected \"name:(needs):(results):cycles\"")
 	}
 
-	name := strings.TrimSpace(line[:colonIndex])
+	name, _ := trimToken(line[:colonIndex], 0)
 	if name == "" {
-		return fmt.Errorf("%s", line)
+		return newParseError(ErrMissingName, 0, "", "missing process name before ':'")
 	}
 	rest := line[colonIndex+1:]
 
 	// Parse the remaining parts: (needs):(results):cycles
 	parts := strings.Split(rest, "):")
-	if len(parts) != 3 {
-		return fmt.Errorf("%s", line)
+	offsets := make([]int, len(parts))
+	offset := colonIndex + 1
+	for i, part := range parts {
+		offsets[i] = offset
+		offset += len(part) + len("):")
+	}
+	// A duration distribution followed by a failure probability ends with "):" too
+	if len(parts) == 4 && strings.Contains(parts[2], "(") {
+		parts = []string{parts[0], parts[1], parts[2] + "):" + parts[3]}
+	}
+	if len(parts) < 3 {
+		return newParseError(ErrInvalidProcess, len(line), "",
+			"invalid process format, expected \"name:(needs):(results):cycles\"")
+	}
+	if len(parts) > 3 {
+		return newParseError(ErrInvalidProcess, offsets[3]-len("):"), "):",
+			"invalid process format, unexpected \"):\" after the results")
 	}
 
 	// Parse needs
-	needs, err := parseResourceMap(parts[0])
+	needs, err := parseResourceMap(parts[0], offsets[0])
 	if err != nil {
-		return fmt.Errorf("error parsing needs: %w", err)
+		return err
 	}
 
-	// Parse results
-	results, err := parseResourceMap(parts[1])
+	// Parse results, whose quantities may be yield ranges
+	yields := map[string]process.Yield{}
+	results, err := parseQuantities(parts[1], offsets[1], yields)
 	if err != nil {
-		return fmt.Errorf("error parsing results: %w", err)
+		return err
+	}
+	if len(yields) == 0 {
+		yields = nil
 	}
 
-	// Parse Cycles
-	cycles, err := strconv.Atoi(strings.TrimSpace(parts[2]))
+	// Parse Cycles, a number or a duration distribution, then the failure probability if any
+	cyclesStr, cyclesOffset := trimToken(parts[2], offsets[2])
+	var failure float64
+	if colon := strings.LastIndex(cyclesStr, ":"); colon > strings.LastIndex(cyclesStr, ")") {
+		failureStr, failureOffset := trimToken(cyclesStr[colon+1:], cyclesOffset+colon+1)
+		if failure, err = parseFailure(failureStr, failureOffset); err != nil {
+			return err
+		}
+		cyclesStr, _ = trimToken(cyclesStr[:colon], cyclesOffset)
+	}
+	var duration *process.Duration
+	cycles, err := strconv.Atoi(cyclesStr)
 	if err != nil {
-		return fmt.Errorf("invalid cycle count '%s': %w", parts[2], err)
+		if !strings.Contains(cyclesStr, "(") {
+			return newParseError(ErrInvalidCycles, cyclesOffset, cyclesStr,
+				"invalid cycle count '%s'", cyclesStr)
+		}
+		if duration, err = parseDuration(cyclesStr, cyclesOffset); err != nil {
+			return err
+		}
+		cycles = duration.Nominal()
 	}
 
 	proc := &process.Process{
-		Name:   name,
-		Needs:  needs,
-		Result: results,
-		Cycle:  cycles,
+		Name:     name,
+		Needs:    needs,
+		Result:   results,
+		Cycle:    cycles,
+		Duration: duration,
+		Failure:  failure,
+		Yields:   yields,
 	}
+	proc.PlanExpected()
 
 	config.Processes = append(config.Processes, proc)
 	return nil
@@ -253,35 +612,76 @@ func parseProcess(config *ConfigData, line string) error {
 // It returns a map where the keys are resource names and the values are their corresponding quantities.
 // The input string should be enclosed in parentheses, with each resource separated by a semicolon and
 // each resource specified as "name:quantity". If the input string is empty or contains no resources,
-// an empty map is returned. Returns an error if any resource entry is malformed or if a quantity cannot
-// be parsed as an integer.
+// an empty map is returned. Returns a *ParseError if any resource entry is malformed or if a quantity
+// cannot be parsed as an integer.
+//
+// The offset is the position of blockStr within its line, so that errors point at the right column.
 //
 // Example input: "(iron:2;coal:3)"
 // Example output: map[string]int{"iron": 2, "coal": 3}
-func parseResourceMap(blockStr string) (map[string]int, error) {
+func parseResourceMap(blockStr string, offset int) (map[string]int, error) {
+	return parseQuantities(blockStr, offset, nil)
+}
+
+// parseQuantities is parseResourceMap for a list whose quantities may also be yield
+// ranges, such as "(bread:2-3)", when yields is not nil. The range of each such resource
+// is added to yields, and the middle of the range to the returned map, until the process
+// plans with its expected quantity (see process.Process.PlanExpected).
+func parseQuantities(blockStr string, offset int, yields map[string]process.Yield) (map[string]int, error) {
 	resources := make(map[string]int)
+	offset += len(blockStr) - len(strings.TrimLeft(blockStr, "()"))
 	blockStr = strings.Trim(blockStr, "()")
 	if blockStr == "" {
 		return resources, nil
 	}
 
 	items := strings.Split(blockStr, ";")
-	for _, item := range items {
-		item = strings.TrimSpace(item)
+	for _, raw := range items {
+		item, itemOffset := trimToken(raw, offset)
+		offset += len(raw) + len(";")
 		if item == "" {
 			continue
 		}
 
 		parts := strings.Split(item, ":")
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("invalid resource format: %s", item)
+		if len(parts) == 1 {
+			return nil, newParseError(ErrInvalidResource, itemOffset, item,
+				"missing quantity for resource '%s', expected \"name:quantity\"", item)
 		}
-		name := strings.TrimSpace(parts[0])
-		quantity, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if len(parts) > 2 {
+			extra := len(parts[0]) + len(":") + len(parts[1])
+			return nil, newParseError(ErrInvalidResource, itemOffset+extra, ":",
+				"invalid resource format '%s', expected ';' between resources", item)
+		}
+
+		name, _ := trimToken(parts[0], 0)
+		if name == "" {
+			return nil, newParseError(ErrMissingName, itemOffset, "", "missing resource name before ':'")
+		}
+		quantityStr, quantityOffset := trimToken(parts[1], itemOffset+len(parts[0])+len(":"))
+		if yields != nil && isYieldRange(quantityStr) {
+			yield, err := parseYield(quantityStr, quantityOffset)
+			if err != nil {
+				return nil, err
+			}
+			yields[name] = yield
+			resources[name] = yield.Expected(0)
+			continue
+		}
+		quantity, err := strconv.Atoi(quantityStr)
 		if err != nil {
-			return nil, fmt.Errorf("invalid resource quantity '%v': %w", quantity, err)
+			return nil, newParseError(ErrInvalidQuantity, quantityOffset, quantityStr,
+				"invalid resource quantity '%s'", quantityStr)
 		}
+		delete(yields, name)
 		resources[name] = quantity
 	}
 	return resources, nil
 }
+
+// trimToken trims the surrounding whitespace of s, which starts at the given offset of
+// its line, and returns the trimmed text with its own offset.
+func trimToken(s string, offset int) (string, int) {
+	left := strings.TrimLeftFunc(s, unicode.IsSpace)
+	return strings.TrimRightFunc(left, unicode.IsSpace), offset + len(s) - len(left)
+}
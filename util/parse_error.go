@@ -0,0 +1,153 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrorCode is a machine-readable identifier for the kind of a configuration error.
+type ErrorCode string
+
+// Error codes reported by ParseConfig.
+const (
+	ErrUnrecognizedLine  ErrorCode = "unrecognized-line"
+	ErrInvalidStock      ErrorCode = "invalid-stock"
+	ErrInvalidQuantity   ErrorCode = "invalid-quantity"
+	ErrDuplicateOptimize ErrorCode = "duplicate-optimize"
+	ErrInvalidProcess    ErrorCode = "invalid-process"
+	ErrMissingName       ErrorCode = "missing-name"
+	ErrInvalidResource   ErrorCode = "invalid-resource"
+	ErrInvalidCycles     ErrorCode = "invalid-cycles"
+	ErrSyntax            ErrorCode = "syntax"
+	ErrUnknownField      ErrorCode = "unknown-field"
+	ErrInvalidValue      ErrorCode = "invalid-value"
+	ErrInclude           ErrorCode = "include"
+	ErrIncludeCycle      ErrorCode = "include-cycle"
+	ErrRenewableConflict ErrorCode = "renewable-conflict"
+	ErrInvalidFailure    ErrorCode = "invalid-failure"
+	ErrInvalidEvent      ErrorCode = "invalid-event"
+)
+
+// ParseError describes a configuration error and where it occurred.
+//
+// Fields:
+//   - File: the path of the configuration file, empty when parsing a single line.
+//   - Line: the 1-based line number, 0 when parsing a single line.
+//   - Column: the 1-based byte column of the offending token within the line, 0 when unknown.
+//   - Token: the offending text; it may be empty when something is missing.
+//   - Code: the machine-readable kind of error.
+//   - Message: a human-readable description.
+//   - Text: the full source line, used to point at the token.
+//   - IncludedFrom: the include directives that led to File, innermost first; empty when
+//     the error is in the top-level file.
+type ParseError struct {
+	File         string
+	Line         int
+	Column       int
+	Token        string
+	Code         ErrorCode
+	Message      string
+	Text         string
+	IncludedFrom []IncludeSite
+}
+
+// IncludeSite is the position of an include directive.
+type IncludeSite struct {
+	File string
+	Line int
+}
+
+// String returns the site in the "file:line" form.
+func (s IncludeSite) String() string {
+	return fmt.Sprintf("%s:%d", s.File, s.Line)
+}
+
+// newParseError returns a ParseError for a token found at the 0-based offset of a line.
+func newParseError(code ErrorCode, offset int, token, format string, args ...any) *ParseError {
+	return &ParseError{
+		Column:  offset + 1,
+		Token:   token,
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// Error returns the error in the "file:line:column: message [code]" form, followed by
+// the include chain, as in "(included from main.conf:3)", for errors in included files.
+func (e *ParseError) Error() string {
+	msg := fmt.Sprintf("%s: %s [%s]", e.position(), e.Message, e.Code)
+	if len(e.IncludedFrom) > 0 {
+		sites := make([]string, len(e.IncludedFrom))
+		for i, site := range e.IncludedFrom {
+			sites[i] = site.String()
+		}
+		msg += " (included from " + strings.Join(sites, ", ") + ")"
+	}
+	return msg
+}
+
+// Render returns the error formatted like a compiler diagnostic: the position and
+// message, then the source line with a caret under the offending token.
+//
+// Example:
+//
+//	examples/run:9:12: error: invalid resource quantity 'x' [invalid-quantity]
+//	    9 | run:(water:x):(joy:5):1
+//	      |            ^
+//
+// Errors in included files start with the include chain, innermost first:
+//
+//	In file included from shared/oven.conf:2,
+//	                 from bakery.conf:5:
+func (e *ParseError) Render() string {
+	var b strings.Builder
+	for i, site := range e.IncludedFrom {
+		prefix, suffix := "                 from ", ","
+		if i == 0 {
+			prefix = "In file included from "
+		}
+		if i == len(e.IncludedFrom)-1 {
+			suffix = ":"
+		}
+		fmt.Fprintf(&b, "%s%s%s\n", prefix, site, suffix)
+	}
+	fmt.Fprintf(&b, "%s: error: %s [%s]\n", e.position(), e.Message, e.Code)
+	if e.Text == "" {
+		return b.String()
+	}
+
+	gutter := fmt.Sprint(e.Line)
+	fmt.Fprintf(&b, " %s | %s\n", gutter, e.Text)
+
+	// Keep tabs so the caret lines up with the source line
+	prefix := e.Text[:min(max(e.Column-1, 0), len(e.Text))]
+	padding := []rune{}
+	for _, r := range prefix {
+		if r == '\t' {
+			padding = append(padding, '\t')
+		} else {
+			padding = append(padding, ' ')
+		}
+	}
+	underline := "^"
+	if n := len([]rune(e.Token)); n > 1 {
+		underline += strings.Repeat("~", n-1)
+	}
+	fmt.Fprintf(&b, " %s | %s%s\n", strings.Repeat(" ", len(gutter)), string(padding), underline)
+	return b.String()
+}
+
+// position returns the "file:line:column" prefix, leaving out the parts that are unknown.
+func (e *ParseError) position() string {
+	parts := []string{}
+	if e.File != "" {
+		parts = append(parts, e.File)
+	}
+	if e.Line > 0 {
+		parts = append(parts, fmt.Sprint(e.Line))
+	}
+	if e.Column > 0 {
+		parts = append(parts, fmt.Sprint(e.Column))
+	}
+	return strings.Join(parts, ":")
+}
@@ -1,6 +1,11 @@
 package util
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/jesee-kuya/stock_exchange/process"
@@ -35,3 +40,294 @@ func TestParseLine(t *testing.T) {
 		})
 	}
 }
+
+// TestParseConfigErrors tests that ParseConfig reports the file, line, column, token
+// and code of the first malformed line.
+func TestParseConfigErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		line    int
+		column  int
+		token   string
+		code    ErrorCode
+	}{
+		{"bad stock quantity", "board:7\nshelf: x\n", 2, 8, "x", ErrInvalidQuantity},
+		{"extra colon in stock", "board:7:1\n", 1, 8, ":", ErrInvalidStock},
+		{"colon instead of semicolon", "board:7\n  do:(a:1:b:2):(c:1):10\n", 2, 10, ":", ErrInvalidResource},
+		{"bad resource quantity", "do:(a:1):(c:one):10\n", 1, 13, "one", ErrInvalidQuantity},
+		{"bad cycles", "do:(a:1):(c:1):ten\n", 1, 16, "ten", ErrInvalidCycles},
+		{"missing cycles", "do:(a:1):(c:1)\n", 1, 15, "", ErrInvalidProcess},
+		{"duplicate optimize", "optimize:(a)\noptimize:(b)\n", 2, 1, "optimize", ErrDuplicateOptimize},
+		{"unrecognized", "# comment\n\nboard\n", 3, 1, "board", ErrUnrecognizedLine},
+		{"stock redeclared renewable", "oven:1\nrenewable:(flour:2; oven:1)\n", 2, 21, "oven", ErrRenewableConflict},
+		{"renewable without parentheses", "renewable:oven:1\n", 1, 11, "oven:1", ErrInvalidResource},
+		{"bad storage capacity", "bread:0/lots\n", 1, 9, "lots", ErrInvalidQuantity},
+		{"negative storage capacity", "a:3/-7\n", 1, 5, "-7", ErrInvalidQuantity},
+		{"duration missing a bound", "do:(a:1):(c:1):uniform(4)\n", 1, 16, "uniform(4)", ErrInvalidCycles},
+		{"unknown distribution", "do:(a:1):(c:1):normal(4;1)\n", 1, 16, "normal", ErrInvalidCycles},
+		{"zero histogram weight", "do:(a:1):(c:1):histogram(4:1;5:0)\n", 1, 32, "0", ErrInvalidCycles},
+		{"yield bounds out of order", "do:(a:1):(c:3-1):10\n", 1, 13, "3-1", ErrInvalidQuantity},
+		{"yield range in needs", "do:(a:1-2):(c:1):10\n", 1, 7, "1-2", ErrInvalidQuantity},
+		{"failure out of range", "do:(a:1):(c:1):10:150%\n", 1, 19, "150%", ErrInvalidFailure},
+		{"misspelled delivery", "+cycles 100 flour:20\n", 1, 1, "+cycles", ErrInvalidEvent},
+		{"bad delivery cycle", "+cycle soon flour:20\n", 1, 8, "soon", ErrInvalidCycles},
+		{"order without items", "-cycle 150\n", 1, 11, "", ErrInvalidEvent},
+		{"bad order penalty", "-cycle 150 bread:10 penalty high\n", 1, 29, "high", ErrInvalidQuantity},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.conf")
+			if err := os.WriteFile(path, []byte(tc.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := ParseConfig(path)
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("expected a *ParseError, got %v", err)
+			}
+			if parseErr.File != path || parseErr.Line != tc.line || parseErr.Column != tc.column {
+				t.Errorf("position = %s:%d:%d, want %s:%d:%d",
+					parseErr.File, parseErr.Line, parseErr.Column, path, tc.line, tc.column)
+			}
+			if parseErr.Token != tc.token {
+				t.Errorf("Token = %q, want %q", parseErr.Token, tc.token)
+			}
+			if parseErr.Code != tc.code {
+				t.Errorf("Code = %q, want %q", parseErr.Code, tc.code)
+			}
+		})
+	}
+}
+
+// TestParseErrorRender tests that the rendered error points at the offending token.
+func TestParseErrorRender(t *testing.T) {
+	err := &ParseError{
+		File:    "run.conf",
+		Line:    9,
+		Column:  12,
+		Token:   "xy",
+		Code:    ErrInvalidQuantity,
+		Message: "invalid resource quantity 'xy'",
+		Text:    "run:(water:xy):(joy:5):1",
+	}
+
+	want := "run.conf:9:12: error: invalid resource quantity 'xy' [invalid-quantity]\n" +
+		" 9 | run:(water:xy):(joy:5):1\n" +
+		"   |            ^~\n"
+	if got := err.Render(); got != want {
+		t.Errorf("Render() =\n%s\nwant\n%s", got, want)
+	}
+	if !strings.HasPrefix(err.Error(), "run.conf:9:12: ") {
+		t.Errorf("Error() = %q, want the position prefix", err.Error())
+	}
+}
+
+// TestParseRenewable tests that renewable resources start with their capacity in stock
+// and that the processes needing them hold them.
+func TestParseRenewable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bakery.conf")
+	content := "flour:4\nbake:(flour:1;oven:1):(bread:1):5\nrenewable:(oven:2)\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ParseConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Renewables["oven"] != 2 || config.Stocks["oven"] != 2 {
+		t.Errorf("Renewables = %v, Stocks = %v, want an oven capacity of 2", config.Renewables, config.Stocks)
+	}
+	bake := config.Processes[0]
+	if bake.Holds["oven"] != 1 || bake.Holds["flour"] != 0 {
+		t.Errorf("Holds = %v, want only the oven", bake.Holds)
+	}
+	if output := bake.Output(); output["oven"] != 1 || output["bread"] != 1 {
+		t.Errorf("Output() = %v, want the bread and the oven back", output)
+	}
+}
+
+// TestParseCapacity tests that a stock may declare a storage capacity after its quantity.
+func TestParseCapacity(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bakery.conf")
+	if err := os.WriteFile(path, []byte("flour:10\nbread: 0 / 50\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ParseConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Stocks["bread"] != 0 || config.Capacities["bread"] != 50 {
+		t.Errorf("Stocks = %v, Capacities = %v, want 0 bread out of 50", config.Stocks, config.Capacities)
+	}
+	if _, ok := config.Capacities["flour"]; ok {
+		t.Errorf("flour should have no capacity, got %v", config.Capacities)
+	}
+}
+
+// TestParsePerishable tests that a perishable line sets the shelf life of its items and
+// survives a conversion to JSON and back.
+func TestParsePerishable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bakery.conf")
+	content := "flour:4\nperishable:(dough:6; yeast:20)\nknead:(flour:1):(dough:1):2\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ParseConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.ShelfLife["dough"] != 6 || config.ShelfLife["yeast"] != 20 || config.Lines.Perishables["dough"] != 2 {
+		t.Errorf("ShelfLife = %v, lines = %v", config.ShelfLife, config.Lines.Perishables)
+	}
+	if _, ok := config.Stocks["dough"]; ok {
+		t.Errorf("a shelf life should not make dough a stock, got %v", config.Stocks)
+	}
+
+	var b strings.Builder
+	if err := WriteConfig(&b, config, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `"perishables": {`) {
+		t.Errorf("JSON output lacks the perishables:\n%s", b.String())
+	}
+}
+
+// TestParseDuration tests that duration distributions are parsed with their nominal
+// duration as cycle count, and survive a conversion to YAML and back.
+func TestParseDuration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bakery.conf")
+	content := "flour:4\nknead:(flour:1):(dough:1):uniform(2;6)\nbake:(dough:1):(bread:1):histogram(4:1; 5:3; 9:1)\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ParseConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	knead, bake := config.Processes[0], config.Processes[1]
+	if knead.Cycle != 4 || knead.Duration.String() != "uniform(2;6)" {
+		t.Errorf("knead takes %d cycles with duration %v, want 4 with uniform(2;6)", knead.Cycle, knead.Duration)
+	}
+	if bake.Cycle != 6 || bake.Duration.String() != "histogram(4:1;5:3;9:1)" {
+		t.Errorf("bake takes %d cycles with duration %v, want 6 with histogram(4:1;5:3;9:1)", bake.Cycle, bake.Duration)
+	}
+
+	var b strings.Builder
+	if err := WriteConfig(&b, config, FormatYAML); err != nil {
+		t.Fatal(err)
+	}
+	yamlPath := filepath.Join(t.TempDir(), "bakery.yaml")
+	if err := os.WriteFile(yamlPath, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	converted, err := ParseConfig(yamlPath)
+	if err != nil {
+		t.Fatalf("%v in:\n%s", err, b.String())
+	}
+	if got := converted.Processes[1].Duration; got == nil || got.String() != bake.Duration.String() || converted.Processes[1].Cycle != 6 {
+		t.Errorf("converted bake duration = %v, want %v", got, bake.Duration)
+	}
+}
+
+// TestParseOutcomes tests that failure probabilities and yield ranges are parsed with the
+// expected quantity as result, and survive a conversion to JSON, YAML and back to text.
+func TestParseOutcomes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bakery.conf")
+	content := "dough:4\nbake:(dough:1):(bread:2-3; crumbs:1):5:10%\ntoast:(bread:1):(toast:4-6; crumbs:3):1:50%\n" +
+		"slice:(toast:1):(slice:4-5):1\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ParseConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bake := config.Processes[0]
+	wantYields := map[string]process.Yield{"bread": {Min: 2, Max: 3}, "crumbs": {Min: 1, Max: 1}}
+	if bake.Failure != 0.1 || !reflect.DeepEqual(bake.Yields, wantYields) {
+		t.Errorf("bake fails with %v and yields %v, want 0.1 and %v", bake.Failure, bake.Yields, wantYields)
+	}
+
+	// 0.9·2.5 = 2.25 bread and 0.9 crumbs, 0.5·5 = 2.5 toast and 1.5 crumbs, 4.5 slices
+	want := []map[string]int{{"bread": 2, "crumbs": 1}, {"toast": 2, "crumbs": 1}, {"slice": 4}}
+	for i, p := range config.Processes {
+		if !reflect.DeepEqual(p.Result, want[i]) {
+			t.Errorf("%s results = %v, want the expected %v", p.Name, p.Result, want[i])
+		}
+	}
+
+	for _, format := range []Format{FormatJSON, FormatYAML, FormatText} {
+		var b strings.Builder
+		if err := WriteConfig(&b, config, format); err != nil {
+			t.Fatal(err)
+		}
+		path = filepath.Join(t.TempDir(), "bakery."+string(format))
+		if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if config, err = ParseConfigAs(path, format); err != nil {
+			t.Fatalf("%v in:\n%s", err, b.String())
+		}
+	}
+	for i, p := range config.Processes {
+		if !reflect.DeepEqual(p.Result, want[i]) {
+			t.Errorf("converted %s results = %v, want %v", p.Name, p.Result, want[i])
+		}
+	}
+	converted := config.Processes[0]
+	if converted.Failure != bake.Failure || !reflect.DeepEqual(converted.Yields, bake.Yields) {
+		t.Errorf("converted bake = %+v, want %+v", converted, bake)
+	}
+}
+
+// TestParseEvents tests that delivery and order lines are parsed with their line, the
+// penalty defaulting to DefaultPenalty, and survive a conversion to YAML and back to text.
+func TestParseEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bakery.conf")
+	content := "flour:4\n+cycle 100 flour:20\n-cycle 150 (bread:10; crumbs:2) penalty 5\n-cycle 40 bread:1\n" +
+		"bake:(flour:1):(bread:1;crumbs:1):5\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ParseConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantDeliveries := []Delivery{{Cycle: 100, Items: map[string]int{"flour": 20}}}
+	wantOrders := []Order{
+		{Due: 150, Items: map[string]int{"bread": 10, "crumbs": 2}, Penalty: 5},
+		{Due: 40, Items: map[string]int{"bread": 1}, Penalty: DefaultPenalty},
+	}
+	if !reflect.DeepEqual(config.Deliveries, wantDeliveries) || !reflect.DeepEqual(config.Orders, wantOrders) {
+		t.Errorf("Deliveries = %v, Orders = %v, want %v and %v", config.Deliveries, config.Orders, wantDeliveries, wantOrders)
+	}
+	if config.Lines.DeliveryLine(0) != 2 || config.Lines.OrderLine(1) != 4 {
+		t.Errorf("lines = %v and %v, want delivery at 2 and second order at 4", config.Lines.Deliveries, config.Lines.Orders)
+	}
+
+	for _, format := range []Format{FormatYAML, FormatText} {
+		var b strings.Builder
+		if err := WriteConfig(&b, config, format); err != nil {
+			t.Fatal(err)
+		}
+		path = filepath.Join(t.TempDir(), "bakery."+string(format))
+		if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if config, err = ParseConfigAs(path, format); err != nil {
+			t.Fatalf("%v in:\n%s", err, b.String())
+		}
+	}
+	if !reflect.DeepEqual(config.Deliveries, wantDeliveries) || !reflect.DeepEqual(config.Orders, wantOrders) {
+		t.Errorf("converted Deliveries = %v, Orders = %v", config.Deliveries, config.Orders)
+	}
+}
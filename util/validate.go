@@ -0,0 +1,303 @@
+package util
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severity tells whether an Issue makes a configuration wrong or only suspicious.
+type Severity string
+
+// Severities reported by Validate.
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Codes of the issues reported by Validate.
+const (
+	ErrUnavailableNeed     ErrorCode = "unavailable-need"
+	ErrDuplicateProcess    ErrorCode = "duplicate-process"
+	ErrNegativeQuantity    ErrorCode = "negative-quantity"
+	ErrNonPositiveCycles   ErrorCode = "non-positive-cycles"
+	ErrUnknownTarget       ErrorCode = "unknown-target"
+	ErrStockAfterProcess   ErrorCode = "stock-after-process"
+	ErrRenewableResult     ErrorCode = "renewable-result"
+	ErrExceedsCapacity     ErrorCode = "exceeds-capacity"
+	ErrOverCapacity        ErrorCode = "over-capacity"
+	ErrNonPositiveLife     ErrorCode = "non-positive-shelf-life"
+	ErrPerishableRenewable ErrorCode = "perishable-renewable"
+	ErrNonPositiveEvent    ErrorCode = "non-positive-event-quantity"
+	ErrUnknownOrderItem    ErrorCode = "unknown-order-item"
+)
+
+// Issue is a semantic problem found in a configuration by Validate.
+//
+// Fields:
+//   - Severity: whether the issue is an error or a warning.
+//   - Code: the machine-readable kind of issue.
+//   - Line: the 1-based line of the offending definition, 0 when unknown.
+//   - Message: a human-readable description.
+type Issue struct {
+	Severity Severity
+	Code     ErrorCode
+	Line     int
+	Message  string
+}
+
+// Format returns the issue in the "file:line: severity: message [code]" form.
+// The line is left out when it is unknown.
+func (i Issue) Format(file string) string {
+	position := file
+	if i.Line > 0 {
+		position = fmt.Sprintf("%s:%d", file, i.Line)
+	}
+	return fmt.Sprintf("%s: %s: %s [%s]", position, i.Severity, i.Message, i.Code)
+}
+
+// Validate checks a parsed configuration for definitions that can never run meaningfully.
+//
+// Behavior:
+//   - Errors: duplicate process names, negative stock, need or result quantities, zero or
+//     negative cycle counts, stock definitions appearing after the first process, processes
+//     producing a renewable resource, which would raise its capacity for good, and processes
+//     holding more of a renewable resource than its capacity, initial stocks above their
+//     storage capacity, processes producing more of an item in one run than it can store,
+//     zero or negative shelf lives, renewable resources declared perishable, and zero or
+//     negative quantities in deliveries and orders.
+//   - Warnings: processes that can never run because a need is neither in stock nor produced
+//     by a process that can itself run, optimize targets (other than "time") that are
+//     neither stocks nor produced, and ordered items that are neither stocks, produced nor
+//     delivered. Delivered items count as available to the processes needing them.
+//   - Issues are sorted by line, so they read in file order.
+//
+// Parameters:
+//   - config: the parsed configuration; line numbers come from config.Lines.
+//
+// Returns:
+//   - The issues found, empty when the configuration is clean.
+func Validate(config *ConfigData) []Issue {
+	issues := []Issue{}
+	report := func(severity Severity, code ErrorCode, line int, format string, args ...any) {
+		issues = append(issues, Issue{Severity: severity, Code: code, Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	firstProcess := 0
+	for i := range config.Processes {
+		if line := config.Lines.ProcessLine(i); line > 0 && (firstProcess == 0 || line < firstProcess) {
+			firstProcess = line
+		}
+	}
+	for _, name := range sortedKeys(config.Stocks) {
+		line := config.Lines.Stocks[name]
+		if config.Stocks[name] < 0 {
+			report(SeverityError, ErrNegativeQuantity, line, "stock '%s' has negative quantity %d", name, config.Stocks[name])
+		}
+		if capacity, ok := config.Capacities[name]; ok && config.Stocks[name] > capacity {
+			report(SeverityError, ErrOverCapacity, line,
+				"stock '%s' starts at %d but its storage capacity is %d", name, config.Stocks[name], capacity)
+		}
+		if firstProcess > 0 && line > firstProcess {
+			report(SeverityError, ErrStockAfterProcess, line,
+				"stock '%s' is defined after the first process (line %d), stocks must come first", name, firstProcess)
+		}
+	}
+
+	for _, name := range sortedKeys(config.ShelfLife) {
+		line := config.Lines.Perishables[name]
+		if config.ShelfLife[name] <= 0 {
+			report(SeverityError, ErrNonPositiveLife, line, "perishable '%s' has non-positive shelf life %d", name, config.ShelfLife[name])
+		}
+		if _, ok := config.Renewables[name]; ok {
+			report(SeverityError, ErrPerishableRenewable, line, "renewable resource '%s' cannot be perishable", name)
+		}
+	}
+
+	seen := map[string]int{}
+	for i, p := range config.Processes {
+		line := config.Lines.ProcessLine(i)
+		if first, ok := seen[p.Name]; ok {
+			report(SeverityError, ErrDuplicateProcess, line, "process '%s' is already defined%s", p.Name, atLine(first))
+		} else {
+			seen[p.Name] = line
+		}
+		if p.Cycle <= 0 {
+			report(SeverityError, ErrNonPositiveCycles, line, "process '%s' has non-positive cycle count %d", p.Name, p.Cycle)
+		} else if p.Duration != nil {
+			if shortest, _ := p.Duration.Range(); shortest <= 0 {
+				report(SeverityError, ErrNonPositiveCycles, line, "process '%s' can take %d cycles with duration %s", p.Name, shortest, p.Duration)
+			}
+		}
+		for _, item := range sortedKeys(p.Needs) {
+			if p.Needs[item] < 0 {
+				report(SeverityError, ErrNegativeQuantity, line, "process '%s' needs negative quantity %d of '%s'", p.Name, p.Needs[item], item)
+			}
+		}
+		for _, item := range sortedKeys(p.Result) {
+			if p.Result[item] < 0 {
+				report(SeverityError, ErrNegativeQuantity, line, "process '%s' produces negative quantity %d of '%s'", p.Name, p.Result[item], item)
+			} else if y, ok := p.Yields[item]; ok && y.Min < 0 {
+				report(SeverityError, ErrNegativeQuantity, line, "process '%s' can yield negative quantity %d of '%s'", p.Name, y.Min, item)
+			}
+			if _, ok := config.Renewables[item]; ok {
+				report(SeverityError, ErrRenewableResult, line,
+					"process '%s' produces renewable resource '%s', renewables are given back automatically", p.Name, item)
+			}
+			if capacity, ok := config.Capacities[item]; ok && p.MostResult()[item]-p.Needs[item] > capacity {
+				report(SeverityError, ErrExceedsCapacity, line,
+					"process '%s' produces up to %d '%s' but its storage capacity is %d", p.Name, p.MostResult()[item], item, capacity)
+			}
+		}
+		for _, item := range sortedKeys(p.Needs) {
+			if capacity, ok := config.Renewables[item]; ok && p.Needs[item] > capacity {
+				report(SeverityError, ErrExceedsCapacity, line,
+					"process '%s' holds %d '%s' but its capacity is %d", p.Name, p.Needs[item], item, capacity)
+			}
+		}
+	}
+
+	for i, d := range config.Deliveries {
+		for _, item := range sortedKeys(d.Items) {
+			if d.Items[item] <= 0 {
+				report(SeverityError, ErrNonPositiveEvent, config.Lines.DeliveryLine(i),
+					"delivery at cycle %d has non-positive quantity %d of '%s'", d.Cycle, d.Items[item], item)
+			}
+		}
+	}
+	for i, o := range config.Orders {
+		for _, item := range sortedKeys(o.Items) {
+			if o.Items[item] <= 0 {
+				report(SeverityError, ErrNonPositiveEvent, config.Lines.OrderLine(i),
+					"order due at cycle %d has non-positive quantity %d of '%s'", o.Due, o.Items[item], item)
+			}
+		}
+	}
+
+	produced := map[string]bool{}
+	for _, p := range config.Processes {
+		for item := range p.Result {
+			produced[item] = true
+		}
+	}
+	delivered := map[string]bool{}
+	for _, d := range config.Deliveries {
+		for item := range d.Items {
+			delivered[item] = true
+		}
+	}
+	available, runnable := availableItems(config)
+	for i, p := range config.Processes {
+		if runnable[i] {
+			continue
+		}
+		missing := []string{}
+		for _, item := range sortedKeys(p.Needs) {
+			if p.Needs[item] <= 0 || available[item] {
+				continue
+			}
+			if _, inStock := config.Stocks[item]; !inStock && !produced[item] && !delivered[item] {
+				missing = append(missing, fmt.Sprintf("'%s' (neither in stock nor produced)", item))
+			} else {
+				missing = append(missing, fmt.Sprintf("'%s' (never available)", item))
+			}
+		}
+		report(SeverityWarning, ErrUnavailableNeed, config.Lines.ProcessLine(i),
+			"process '%s' can never run, it needs %s", p.Name, strings.Join(missing, ", "))
+	}
+
+	for _, target := range config.OptimizeTargets {
+		if target == "time" {
+			continue
+		}
+		if _, inStock := config.Stocks[target]; !inStock && !produced[target] {
+			report(SeverityWarning, ErrUnknownTarget, config.Lines.Optimize,
+				"optimize target '%s' is neither a stock nor produced by any process", target)
+		}
+	}
+
+	for i, o := range config.Orders {
+		for _, item := range sortedKeys(o.Items) {
+			if _, inStock := config.Stocks[item]; !inStock && !produced[item] && !delivered[item] {
+				report(SeverityWarning, ErrUnknownOrderItem, config.Lines.OrderLine(i),
+					"order due at cycle %d needs '%s', which is neither a stock, produced nor delivered", o.Due, item)
+			}
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Line < issues[j].Line })
+	return issues
+}
+
+// HasErrors reports whether any of the issues is an error rather than a warning.
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// availableItems computes which items can ever be in stock and which processes can ever run.
+// It starts from the positive initial stocks and the delivered items and repeatedly adds the results of the processes
+// whose needs are all available, until nothing changes. Quantities are ignored, so a process
+// counted as runnable may still be starved in practice.
+func availableItems(config *ConfigData) (map[string]bool, map[int]bool) {
+	available := map[string]bool{}
+	for item, qty := range config.Stocks {
+		if qty > 0 {
+			available[item] = true
+		}
+	}
+	for _, d := range config.Deliveries {
+		for item, qty := range d.Items {
+			if qty > 0 {
+				available[item] = true
+			}
+		}
+	}
+	runnable := map[int]bool{}
+	for changed := true; changed; {
+		changed = false
+		for i, p := range config.Processes {
+			if runnable[i] {
+				continue
+			}
+			ready := true
+			for item, qty := range p.Needs {
+				if qty > 0 && !available[item] {
+					ready = false
+					break
+				}
+			}
+			if !ready {
+				continue
+			}
+			runnable[i] = true
+			changed = true
+			for item := range p.Result {
+				available[item] = true
+			}
+		}
+	}
+	return available, runnable
+}
+
+// atLine returns " at line N", or nothing when the line is unknown.
+func atLine(line int) string {
+	if line > 0 {
+		return fmt.Sprintf(" at line %d", line)
+	}
+	return ""
+}
+
+// sortedKeys returns the keys of a map in lexical order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
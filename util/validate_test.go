@@ -0,0 +1,139 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestValidate tests that each kind of semantic problem is reported with its line number.
+func TestValidate(t *testing.T) {
+	content := `board:7
+do_shelf:(board:1):(shelf:1):10
+do_shelf:(board:-1):(shelf:1):0
+do_glue:(glue:1):(x:1):5
+do_y:(x:1):(y:1):5
+nails:3
+optimize:(time;cabinet)
+`
+	path := filepath.Join(t.TempDir(), "lint.conf")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := ParseConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		code     ErrorCode
+		line     int
+		severity Severity
+	}{
+		{ErrDuplicateProcess, 3, SeverityError},
+		{ErrNonPositiveCycles, 3, SeverityError},
+		{ErrNegativeQuantity, 3, SeverityError},
+		{ErrUnavailableNeed, 4, SeverityWarning},
+		{ErrUnavailableNeed, 5, SeverityWarning},
+		{ErrStockAfterProcess, 6, SeverityError},
+		{ErrUnknownTarget, 7, SeverityWarning},
+	}
+	issues := Validate(config)
+	if len(issues) != len(want) {
+		t.Fatalf("got %d issues, want %d: %v", len(issues), len(want), issues)
+	}
+	for i, w := range want {
+		if issues[i].Code != w.code || issues[i].Line != w.line || issues[i].Severity != w.severity {
+			t.Errorf("issue %d = %+v, want %s at line %d (%s)", i, issues[i], w.code, w.line, w.severity)
+		}
+	}
+	if !HasErrors(issues) {
+		t.Error("HasErrors() = false, want true")
+	}
+}
+
+// TestValidateClean tests that a sound configuration built in code has no issues.
+func TestValidateClean(t *testing.T) {
+	config := &ConfigData{Stocks: map[string]int{"board": 7}, OptimizeTargets: []string{"time", "shelf"}}
+	if err := parseLine(config, "do_shelf:(board:1):(shelf:1):10"); err != nil {
+		t.Fatal(err)
+	}
+	if issues := Validate(config); len(issues) != 0 {
+		t.Errorf("expected no issues, got %v", issues)
+	}
+}
+
+// TestValidateRenewables tests that producing a renewable resource and holding more of it
+// than its capacity are reported as errors.
+func TestValidateRenewables(t *testing.T) {
+	content := "flour:4\nrenewable:(oven:2)\nbake:(flour:1;oven:3):(bread:1):5\nbuild:(flour:1):(oven:1):5\n"
+	path := filepath.Join(t.TempDir(), "lint.conf")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := ParseConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issues := Validate(config)
+	if len(issues) != 2 || issues[0].Code != ErrExceedsCapacity || issues[0].Line != 3 ||
+		issues[1].Code != ErrRenewableResult || issues[1].Line != 4 {
+		t.Errorf("unexpected issues %v", issues)
+	}
+}
+
+// TestValidateCapacities tests that stocks and results above a storage capacity are errors.
+func TestValidateCapacities(t *testing.T) {
+	content := "flour:4/3\nbread:0/2\nbake:(flour:1):(bread:3):5\n"
+	path := filepath.Join(t.TempDir(), "lint.conf")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := ParseConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issues := Validate(config)
+	if len(issues) != 2 || issues[0].Code != ErrOverCapacity || issues[0].Line != 1 ||
+		issues[1].Code != ErrExceedsCapacity || issues[1].Line != 3 {
+		t.Errorf("unexpected issues %v", issues)
+	}
+}
+
+// TestValidatePerishables tests that shelf lives must be positive and renewables cannot perish.
+func TestValidatePerishables(t *testing.T) {
+	content := "renewable:(oven:1)\nperishable:(dough:0;oven:5)\n"
+	path := filepath.Join(t.TempDir(), "lint.conf")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := ParseConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issues := Validate(config)
+	if len(issues) != 2 || issues[0].Code != ErrNonPositiveLife || issues[1].Code != ErrPerishableRenewable || issues[1].Line != 2 {
+		t.Errorf("unexpected issues %v", issues)
+	}
+}
+
+// TestValidateDurations tests that a distribution able to draw no cycle at all is reported.
+func TestValidateDurations(t *testing.T) {
+	content := "a:1\ndo:(a:1):(b:1):uniform(0;4)\n"
+	path := filepath.Join(t.TempDir(), "lint.conf")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := ParseConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issues := Validate(config)
+	if len(issues) != 1 || issues[0].Code != ErrNonPositiveCycles || issues[0].Line != 2 {
+		t.Errorf("unexpected issues %v", issues)
+	}
+}